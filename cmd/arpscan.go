@@ -1,8 +1,15 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/mwiater/ghost/utils"
@@ -13,8 +20,32 @@ import (
 var ARPScannerCmd = &cobra.Command{
 	Use:   "arpscan",
 	Short: "Scans the local network using ARP to find devices",
+	Long: `Scans the local network using ARP to find devices. By default the IPv4 network of the
+selected interface is scanned; the interface defaults to the one carrying the default route.
+With --ipv6, IPv6 neighbors are discovered with NDP (Neighbor Discovery Protocol) instead.
+
+With --passive the kernel neighbor cache is listed instead of sending requests, which needs no
+special privileges; this is also used automatically when active requests are not permitted.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		results, err := RunARPScanner()
+		ipv6, _ := cmd.Flags().GetBool("ipv6")
+		opts := ARPScanOptions{}
+		opts.Interface, _ = cmd.Flags().GetString("interface")
+		opts.CIDR, _ = cmd.Flags().GetString("cidr")
+		opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+		opts.Retries, _ = cmd.Flags().GetInt("retries")
+		opts.Concurrency, _ = cmd.Flags().GetInt("concurrency")
+		opts.NeighborFile, _ = cmd.Flags().GetString("neighbor-file")
+		passive, _ := cmd.Flags().GetBool("passive")
+
+		var results []ARPResult
+		var err error
+		if passive || opts.NeighborFile != "" {
+			results, err = RunNeighborTable(opts, ipv6)
+		} else if ipv6 {
+			results, err = RunNDPScanner(opts)
+		} else {
+			results, err = RunARPScanner(opts)
+		}
 		if err != nil {
 			fmt.Println("Error:", err)
 			return
@@ -25,47 +56,274 @@ var ARPScannerCmd = &cobra.Command{
 
 // ARPResult holds the IP and MAC address for each discovered device.
 type ARPResult struct {
-	IPAddress  string
-	MACAddress string
+	IPAddress    string
+	MACAddress   string
+	Interface    string
+	State        string        // Neighbor cache state (REACHABLE, STALE, FAILED, ...); empty for active replies
+	Vendor       string        // Organization registered for the MAC prefix, or a note for randomized addresses
+	ResponseTime time.Duration // Zero when the entry did not come from an active request
+	Duplicate    bool          // More than one MAC address answered for IPAddress
+}
+
+// ARPScanOptions controls which network an ARP scan covers and how requests are sent.
+type ARPScanOptions struct {
+	Interface    string        // Interface name; empty selects the default-route interface
+	CIDR         string        // Network to scan; empty uses the interface's own IPv4 network
+	Timeout      time.Duration // How long to wait for a reply to each request
+	Retries      int           // Additional requests sent to addresses that did not answer
+	Concurrency  int           // Number of addresses with a request in flight at once
+	NeighborFile string        // Read neighbor entries from this file instead of the kernel (passive mode only)
 }
 
 // init registers the ARPScannerCmd with the root command when this package is imported.
 func init() {
 	RootCmd.AddCommand(ARPScannerCmd)
+	ARPScannerCmd.Flags().BoolP("ipv6", "6", false, "Discover IPv6 neighbors with NDP instead of ARP")
+	ARPScannerCmd.Flags().StringP("interface", "i", "", "Network interface to scan from (default: the default-route interface)")
+	ARPScannerCmd.Flags().StringP("cidr", "c", "", "IPv4 network to scan (default: the interface's own network)")
+	ARPScannerCmd.Flags().Duration("timeout", 1*time.Second, "Time to wait for a reply to each request")
+	ARPScannerCmd.Flags().Int("retries", 1, "Number of times to retry addresses that did not answer")
+	ARPScannerCmd.Flags().Int("concurrency", 64, "Number of addresses to query concurrently")
+	ARPScannerCmd.Flags().BoolP("passive", "p", false, "List the kernel neighbor cache instead of sending requests (no privileges needed)")
+	ARPScannerCmd.Flags().String("neighbor-file", "", "Read neighbor entries from a saved /proc/net/arp or 'ip neigh' output (implies --passive)")
 }
 
 // RunARPScanner determines the operating system and calls the appropriate ARP scanning function.
-func RunARPScanner() ([]ARPResult, error) {
-	return runARPScan()
+// When sending ARP requests is not permitted it falls back to the kernel neighbor cache.
+func RunARPScanner(opts ARPScanOptions) ([]ARPResult, error) {
+	results, err := runARPScan(opts.withDefaults())
+	if errors.Is(err, os.ErrPermission) {
+		warnNeighborFallback("ARP")
+		return RunNeighborTable(opts, false)
+	}
+	addARPVendors(results)
+	return results, err
+}
+
+// RunNDPScanner discovers IPv6 neighbors on the local link using Neighbor Discovery.
+// When raw ICMPv6 sockets are not permitted it falls back to the kernel neighbor cache.
+func RunNDPScanner(opts ARPScanOptions) ([]ARPResult, error) {
+	results, err := runNDPScan(opts.withDefaults())
+	if errors.Is(err, os.ErrPermission) {
+		warnNeighborFallback("NDP")
+		return RunNeighborTable(opts, true)
+	}
+	addARPVendors(results)
+	return results, err
+}
+
+// warnNeighborFallback explains why the neighbor cache is shown instead of scan results.
+func warnNeighborFallback(protocol string) {
+	utils.TerminalColor(fmt.Sprintf("Warning: sending %s requests needs raw-socket privileges (root or CAP_NET_RAW); showing the kernel neighbor cache instead.", protocol), utils.Warn)
+}
+
+// addARPVendors fills in the vendor of each result's MAC address.
+func addARPVendors(results []ARPResult) {
+	for i := range results {
+		results[i].Vendor = utils.DescribeVendor(results[i].MACAddress)
+	}
+}
+
+// withDefaults fills in zero-valued options with the command's defaults.
+func (opts ARPScanOptions) withDefaults() ARPScanOptions {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 1 * time.Second
+	}
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 64
+	}
+	return opts
 }
 
 // PrintArpScanResults displays the ARP scan results in a formatted table.
 func PrintArpScanResults(results []ARPResult) {
 	// Use utils.Table to create a table with "DarkSimple" style for alternate row shading
 	t := utils.Table("DarkSimple", "ARP Scan Results")
-	t.AppendHeader(table.Row{"IP Address", "MAC Address"})
+	t.AppendHeader(table.Row{"IP Address", "MAC Address", "Vendor", "Interface", "State", "Response Time", "Note"})
 
 	// Add each ARP result's details to the table
+	duplicates := 0
 	for _, result := range results {
-		t.AppendRow(table.Row{result.IPAddress, result.MACAddress})
+		responseTime := "-"
+		if result.ResponseTime > 0 {
+			responseTime = result.ResponseTime.Round(10 * time.Microsecond).String()
+		}
+		mac := result.MACAddress
+		if mac == "" {
+			mac = "-"
+		}
+		state := result.State
+		if state == "" {
+			state = "-"
+		}
+		note := ""
+		if result.Duplicate {
+			note = "DUPLICATE IP"
+			duplicates++
+		}
+		t.AppendRow(table.Row{result.IPAddress, mac, result.Vendor, result.Interface, state, responseTime, note})
 	}
 
 	// Render the table
 	fmt.Println()
 	t.Render()
 	fmt.Println()
+	if duplicates > 0 {
+		utils.TerminalColor(fmt.Sprintf("Warning: %d entries share their IP address with a different MAC address (possible IP conflict or ARP spoofing).", duplicates), utils.Warn)
+	}
 }
 
-// getInterface returns the first active network interface that is not a loopback interface.
-func getInterface() (*net.Interface, error) {
+// getInterface returns the named interface or, when name is empty, the interface that carries
+// the default route. If that cannot be determined it falls back to the first active interface
+// that is not a loopback and has an IPv4 address and hardware address.
+func getInterface(name string) (*net.Interface, error) {
+	if name != "" {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("interface %s: %w", name, err)
+		}
+		return iface, nil
+	}
+
+	if iface, err := defaultRouteInterface(); err == nil {
+		return iface, nil
+	}
+
 	interfaces, err := net.Interfaces()
 	if err != nil {
 		return nil, err
 	}
 	for _, iface := range interfaces {
-		if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagLoopback == 0 {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) == 0 {
+			continue
+		}
+		if _, err := interfaceIPv4Network(&iface); err == nil {
 			return &iface, nil
 		}
 	}
 	return nil, fmt.Errorf("no valid network interface found")
 }
+
+// defaultRouteInterface finds the interface the kernel would use to reach the internet by
+// "connecting" a UDP socket (no packets are sent) and matching its local address.
+func defaultRouteInterface() (*net.Interface, error) {
+	conn, err := net.Dial("udp4", "192.0.2.1:9") // TEST-NET-1; only used for route selection
+	if err != nil {
+		return nil, err
+	}
+	localIP := conn.LocalAddr().(*net.UDPAddr).IP
+	conn.Close()
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for i := range interfaces {
+		addrs, err := interfaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(localIP) {
+				return &interfaces[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no interface holds %s", localIP)
+}
+
+// interfaceIPv4Network returns the first IPv4 network configured on iface.
+func interfaceIPv4Network(iface *net.Interface) (netip.Prefix, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	for _, addr := range addrs {
+		prefix, err := netip.ParsePrefix(addr.String())
+		if err == nil && prefix.Addr().Is4() {
+			return prefix, nil
+		}
+	}
+	return netip.Prefix{}, fmt.Errorf("interface %s has no IPv4 address", iface.Name)
+}
+
+// arpScanAddresses returns the IPv4 addresses to query: those of cidr, or of the interface's own
+// network when cidr is empty. The interface's own addresses are left out.
+func arpScanAddresses(iface *net.Interface, cidr string) ([]netip.Addr, error) {
+	own := make(map[netip.Addr]bool)
+	if addrs, err := iface.Addrs(); err == nil {
+		for _, addr := range addrs {
+			if prefix, err := netip.ParsePrefix(addr.String()); err == nil {
+				own[prefix.Addr()] = true
+			}
+		}
+	}
+
+	if cidr == "" {
+		network, err := interfaceIPv4Network(iface)
+		if err != nil {
+			return nil, err
+		}
+		cidr = network.Masked().String()
+	}
+
+	targets, err := ParseScanTargets(cidr, FamilyIPv4)
+	if err != nil {
+		return nil, err
+	}
+	var addresses []netip.Addr
+	for _, target := range targets {
+		addr, err := netip.ParseAddr(target.Address)
+		if err != nil || own[addr] {
+			continue
+		}
+		addresses = append(addresses, addr)
+	}
+	return addresses, nil
+}
+
+// markDuplicateARPResults flags results whose IP address was claimed by more than one MAC address.
+func markDuplicateARPResults(results []ARPResult) {
+	macs := make(map[string]map[string]bool)
+	for _, result := range results {
+		if result.MACAddress == "" {
+			continue
+		}
+		if macs[result.IPAddress] == nil {
+			macs[result.IPAddress] = make(map[string]bool)
+		}
+		macs[result.IPAddress][strings.ToLower(result.MACAddress)] = true
+	}
+	for i := range results {
+		results[i].Duplicate = len(macs[results[i].IPAddress]) > 1
+	}
+}
+
+// sortARPResults orders results by address, numerically rather than as strings.
+func sortARPResults(results []ARPResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return addressLess(results[i].IPAddress, results[j].IPAddress)
+	})
+}
+
+// addressLess compares two IP addresses (optionally with a %zone) numerically, falling back to
+// a string comparison when either does not parse.
+func addressLess(a, b string) bool {
+	ipA := net.ParseIP(strings.SplitN(a, "%", 2)[0])
+	ipB := net.ParseIP(strings.SplitN(b, "%", 2)[0])
+	if ipA == nil || ipB == nil {
+		return a < b
+	}
+	return bytes.Compare(ipA.To16(), ipB.To16()) < 0
+}
+
+// ndpDisplayAddress formats a neighbor address, adding the zone to link-local addresses.
+func ndpDisplayAddress(ip net.IP, zone string) string {
+	if ip.IsLinkLocalUnicast() {
+		return ip.String() + "%" + zone
+	}
+	return ip.String()
+}
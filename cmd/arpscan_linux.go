@@ -4,39 +4,146 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"net/netip"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/mdlayher/arp"
 )
 
-// runARPScan performs ARP scanning on Unix-based systems (Linux/macOS).
-func runARPScan() ([]ARPResult, error) {
-	iface, err := getInterface()
+// arpReply is a single ARP reply observed for a requested address.
+type arpReply struct {
+	mac string
+	rtt time.Duration
+}
+
+// runARPScan performs ARP scanning on Linux. Requests are sent by opts.Concurrency workers;
+// a single reader collects every reply so that two MACs answering for one address are noticed.
+func runARPScan(opts ARPScanOptions) ([]ARPResult, error) {
+	iface, err := getInterface(opts.Interface)
 	if err != nil {
 		return nil, fmt.Errorf("error getting interface: %w", err)
 	}
 
+	addresses, err := arpScanAddresses(iface, opts.CIDR)
+	if err != nil {
+		return nil, err
+	}
+
 	conn, err := arp.Dial(iface)
 	if err != nil {
 		return nil, fmt.Errorf("error creating ARP connection: %w", err)
 	}
 	defer conn.Close()
 
-	var results []ARPResult
+	var mu sync.Mutex
+	pending := make(map[netip.Addr]chan struct{}) // Closed when the first reply for an address arrives
+	sentAt := make(map[netip.Addr]time.Time)      // Time of the most recent request per address
+	replies := make(map[netip.Addr][]arpReply)
+	var stopping atomic.Bool
 
-	// Scan the local network and collect each result
-	for ip := 1; ip < 255; ip++ {
-		address := fmt.Sprintf("192.168.1.%d", ip)
-		hwAddr, err := conn.Resolve(netip.MustParseAddr(address))
-		if err != nil {
-			continue
+	// Reader: collect every ARP reply for an address we asked about
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			packet, _, err := conn.Read()
+			if err != nil {
+				if stopping.Load() || errors.Is(err, net.ErrClosed) || errors.Is(err, os.ErrClosed) {
+					return
+				}
+				// An expired deadline or a malformed frame only loses this read
+				continue
+			}
+			if packet.Operation != arp.OperationReply {
+				continue
+			}
+
+			mu.Lock()
+			sent, asked := sentAt[packet.SenderIP]
+			if asked {
+				mac := packet.SenderHardwareAddr.String()
+				seen := false
+				for _, reply := range replies[packet.SenderIP] {
+					if reply.mac == mac {
+						seen = true
+						break
+					}
+				}
+				if !seen {
+					replies[packet.SenderIP] = append(replies[packet.SenderIP], arpReply{mac: mac, rtt: time.Since(sent)})
+				}
+				if ch, ok := pending[packet.SenderIP]; ok {
+					close(ch)
+					delete(pending, packet.SenderIP)
+				}
+			}
+			mu.Unlock()
 		}
-		results = append(results, ARPResult{
-			IPAddress:  address,
-			MACAddress: hwAddr.String(),
-		})
+	}()
+
+	// Workers: request each address, retrying when no reply arrives within the timeout
+	var wg sync.WaitGroup
+	addrCh := make(chan netip.Addr, opts.Concurrency)
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range addrCh {
+				answered := make(chan struct{})
+				for attempt := 0; attempt <= opts.Retries; attempt++ {
+					mu.Lock()
+					if _, ok := pending[addr]; !ok && attempt > 0 {
+						mu.Unlock()
+						break
+					}
+					pending[addr] = answered
+					sentAt[addr] = time.Now()
+					mu.Unlock()
+
+					if err := conn.Request(addr); err != nil {
+						break
+					}
+					select {
+					case <-answered:
+					case <-time.After(opts.Timeout):
+						continue
+					}
+					break
+				}
+			}
+		}()
+	}
+
+	for _, addr := range addresses {
+		addrCh <- addr
 	}
+	close(addrCh)
+	wg.Wait()
+
+	// Give late (duplicate) replies to the last requests a chance to arrive, then stop the reader
+	time.Sleep(opts.Timeout)
+	stopping.Store(true)
+	conn.SetReadDeadline(time.Now())
+	<-readerDone
 
+	var results []ARPResult
+	for addr, found := range replies {
+		for _, reply := range found {
+			results = append(results, ARPResult{
+				IPAddress:    addr.String(),
+				MACAddress:   reply.mac,
+				Interface:    iface.Name,
+				ResponseTime: reply.rtt,
+				Duplicate:    len(found) > 1,
+			})
+		}
+	}
+	sortARPResults(results)
 	return results, nil
 }
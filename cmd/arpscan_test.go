@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestARPScanOptionsWithDefaults(t *testing.T) {
+	got := ARPScanOptions{Retries: -1}.withDefaults()
+	if got.Timeout != time.Second || got.Retries != 0 || got.Concurrency != 64 {
+		t.Errorf("withDefaults() = %+v", got)
+	}
+	set := ARPScanOptions{Timeout: 200 * time.Millisecond, Retries: 3, Concurrency: 8}
+	if got := set.withDefaults(); got != set {
+		t.Errorf("withDefaults() changed explicit options: %+v", got)
+	}
+}
+
+func TestARPScanAddresses(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("no loopback interface named lo")
+	}
+	tests := []struct {
+		cidr    string
+		want    string
+		wantErr bool
+	}{
+		// 127.0.0.1 is the interface's own address and is skipped
+		{cidr: "127.0.0.0/29", want: "127.0.0.2 127.0.0.3 127.0.0.4 127.0.0.5 127.0.0.6"},
+		{cidr: "127.0.0.1/32", want: ""},
+		{cidr: "10.9.8.0/30", want: "10.9.8.1 10.9.8.2"},
+		{cidr: "fd00::/120", wantErr: true},
+		{cidr: "10.0.0.0/8", wantErr: true},
+	}
+	for _, tt := range tests {
+		addresses, err := arpScanAddresses(lo, tt.cidr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("arpScanAddresses(%s) err = %v, want error %v", tt.cidr, err, tt.wantErr)
+			continue
+		}
+		var got []string
+		for _, addr := range addresses {
+			got = append(got, addr.String())
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("arpScanAddresses(%s) = %v, want %s", tt.cidr, got, tt.want)
+		}
+	}
+}
+
+func TestMarkDuplicateARPResults(t *testing.T) {
+	results := []ARPResult{
+		{IPAddress: "192.168.1.10", MACAddress: "00:11:22:33:44:55"},
+		{IPAddress: "192.168.1.2", MACAddress: "00:11:22:33:44:66"},
+		{IPAddress: "192.168.1.10", MACAddress: "00:11:22:33:44:77"},
+		{IPAddress: "192.168.1.2", MACAddress: "00:11:22:33:44:66"},
+		{IPAddress: "192.168.1.3", MACAddress: "AA:BB:CC:DD:EE:FF"},
+		{IPAddress: "192.168.1.3", MACAddress: "aa:bb:cc:dd:ee:ff"},
+		{IPAddress: "192.168.1.4"},
+		{IPAddress: "192.168.1.4", MACAddress: "00:11:22:33:44:88"},
+	}
+	markDuplicateARPResults(results)
+	want := []bool{true, false, true, false, false, false, false, false}
+	for i, result := range results {
+		if result.Duplicate != want[i] {
+			t.Errorf("%s %s: Duplicate = %v, want %v", result.IPAddress, result.MACAddress, result.Duplicate, want[i])
+		}
+	}
+
+	sortARPResults(results)
+	var order []string
+	for _, result := range results {
+		order = append(order, result.IPAddress)
+	}
+	if got := strings.Join(order, " "); !strings.HasPrefix(got, "192.168.1.2 192.168.1.2 192.168.1.3") || !strings.HasSuffix(got, "192.168.1.10 192.168.1.10") {
+		t.Errorf("sortARPResults order = %s", got)
+	}
+}
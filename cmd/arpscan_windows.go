@@ -1,35 +1,125 @@
+//go:build windows
 // +build windows
 
 package cmd
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
-	"os/exec"
-	"strings"
+	"net/netip"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
 )
 
-// runARPScan performs ARP scanning on Windows using the 'arp -a' command.
-func runARPScan() ([]ARPResult, error) {
-	cmd := exec.Command("arp", "-a")
-	output, err := cmd.Output()
+var procSendARP = windows.NewLazySystemDLL("iphlpapi.dll").NewProc("SendARP")
+
+// arpReplyResult is the answer of one SendARP call.
+type arpReplyResult struct {
+	mac net.HardwareAddr
+	err error
+}
+
+// runARPScan performs ARP scanning on Windows. Windows offers no raw-socket access for ARP, so
+// each address is resolved with SendARP from the interface's address, which sends a request
+// unless the neighbor cache already has a fresh entry. opts.Concurrency addresses are resolved at
+// once. SendARP waits for its own fixed timeout, so only one call per address is made at a time:
+// a retry after opts.Timeout keeps waiting for the call still in flight, and a new one is made
+// only when the previous call has failed, up to opts.Retries times.
+func runARPScan(opts ARPScanOptions) ([]ARPResult, error) {
+	iface, err := getInterface(opts.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("error getting interface: %w", err)
+	}
+	network, err := interfaceIPv4Network(iface)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := arpScanAddresses(iface, opts.CIDR)
 	if err != nil {
-		return nil, fmt.Errorf("error running arp -a: %w", err)
+		return nil, err
+	}
+	if err := procSendARP.Find(); err != nil {
+		return nil, fmt.Errorf("error loading SendARP: %w", err)
 	}
 
+	var mu sync.Mutex
 	var results []ARPResult
-	lines := strings.Split(string(output), "\n")
+	var wg sync.WaitGroup
+	addrCh := make(chan netip.Addr, opts.Concurrency)
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range addrCh {
+				var start time.Time
+				var reply chan arpReplyResult
+			attempts:
+				for attempt := 0; attempt <= opts.Retries; attempt++ {
+					if reply == nil {
+						start = time.Now()
+						// Buffered, so a call still running when the address is given up on does not block
+						reply = make(chan arpReplyResult, 1)
+						go func(reply chan<- arpReplyResult) {
+							mac, err := sendARP(addr, network.Addr())
+							reply <- arpReplyResult{mac: mac, err: err}
+						}(reply)
+					}
+
+					select {
+					case answer := <-reply:
+						reply = nil
+						if answer.err != nil {
+							continue
+						}
+						mu.Lock()
+						results = append(results, ARPResult{
+							IPAddress:    addr.String(),
+							MACAddress:   answer.mac.String(),
+							Interface:    iface.Name,
+							ResponseTime: time.Since(start),
+						})
+						mu.Unlock()
+						break attempts
+					case <-time.After(opts.Timeout):
+						// SendARP is still waiting for its own timeout; calling it again now would
+						// only send a duplicate request, so the retry keeps waiting for this call
+					}
+				}
+			}
+		}()
+	}
 
-	// Parse the output to extract IP and MAC addresses
-	for _, line := range lines {
-		fields := strings.Fields(line)
-		if len(fields) >= 2 && net.ParseIP(fields[0]) != nil {
-			results = append(results, ARPResult{
-				IPAddress:  fields[0],
-				MACAddress: fields[1],
-			})
-		}
+	for _, addr := range addresses {
+		addrCh <- addr
 	}
+	close(addrCh)
+	wg.Wait()
 
+	sortARPResults(results)
 	return results, nil
 }
+
+// sendARP resolves the hardware address of dst with SendARP, sending from src.
+func sendARP(dst, src netip.Addr) (net.HardwareAddr, error) {
+	// IPAddr values hold the address in network byte order
+	dstIP, srcIP := dst.As4(), src.As4()
+	var mac [8]byte
+	size := uint32(len(mac))
+	ret, _, _ := procSendARP.Call(
+		uintptr(binary.LittleEndian.Uint32(dstIP[:])),
+		uintptr(binary.LittleEndian.Uint32(srcIP[:])),
+		uintptr(unsafe.Pointer(&mac[0])),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if ret != 0 {
+		return nil, windows.Errno(ret)
+	}
+	if size < 6 {
+		return nil, fmt.Errorf("no hardware address for %s", dst)
+	}
+	return net.HardwareAddr(append([]byte(nil), mac[:6]...)), nil
+}
@@ -0,0 +1,938 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Protocols supported by bench-net.
+const (
+	BenchNetTCP = "tcp"
+	BenchNetUDP = "udp"
+)
+
+const (
+	// benchNetPort is the default port of a bench-net server (TCP for control and TCP streams,
+	// UDP for UDP streams).
+	benchNetPort = 5201
+	// benchNetHeaderSize is the size of the header that starts every UDP datagram: test ID,
+	// stream, sequence number and send time.
+	benchNetHeaderSize = 24
+	// benchNetHandshakeTimeout limits how long either side waits for the other during setup.
+	benchNetHandshakeTimeout = 5 * time.Second
+	// benchNetDrainTimeout limits how long the server waits for the TCP streams to finish
+	// after the client says it is done.
+	benchNetDrainTimeout = 5 * time.Second
+	// benchNetUDPDrain is how long the server keeps counting datagrams still in flight after
+	// the client says it is done.
+	benchNetUDPDrain = 250 * time.Millisecond
+	// benchNetMaxStreams caps the parallelism a client may ask for.
+	benchNetMaxStreams = 128
+	// benchNetMaxDuration and benchNetMinInterval bound the test a client may ask for, and with
+	// it the number of intervals the server keeps.
+	benchNetMaxDuration = time.Hour
+	benchNetMinInterval = 100 * time.Millisecond
+)
+
+// BenchNetOptions configures a throughput test.
+type BenchNetOptions struct {
+	Port       int           // Server port
+	Protocol   string        // BenchNetTCP or BenchNetUDP
+	Streams    int           // Number of parallel streams
+	Duration   time.Duration // Length of the test
+	Interval   time.Duration // Reporting interval
+	BufferSize int           // Size of each write (TCP) or datagram (UDP) in bytes
+	Bitrate    int64         // Target bits per second for UDP across all streams; 0 is unlimited
+	Timeout    time.Duration // Limit for connecting to the server
+}
+
+// withDefaults returns the options with zero values replaced by defaults.
+func (o BenchNetOptions) withDefaults() BenchNetOptions {
+	if o.Port == 0 {
+		o.Port = benchNetPort
+	}
+	if o.Protocol == "" {
+		o.Protocol = BenchNetTCP
+	}
+	if o.Streams <= 0 {
+		o.Streams = 1
+	}
+	if o.Duration <= 0 {
+		o.Duration = 10 * time.Second
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = 128 * 1024
+		if o.Protocol == BenchNetUDP {
+			o.BufferSize = 1400
+		}
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = benchNetHandshakeTimeout
+	}
+	return o
+}
+
+// BenchNetInterval is the traffic of one reporting interval, as sent by the client and as
+// received by the server.
+type BenchNetInterval struct {
+	Start                 time.Duration `json:"start"`
+	End                   time.Duration `json:"end"`
+	SentBytes             int64         `json:"sentBytes"`
+	SentBitsPerSecond     float64       `json:"sentBitsPerSecond"`
+	ReceivedBytes         int64         `json:"receivedBytes"`
+	ReceivedBitsPerSecond float64       `json:"receivedBitsPerSecond"`
+	Retransmits           int64         `json:"retransmits"` // TCP segments retransmitted, where the platform reports them
+	Packets               int64         `json:"packets"`     // UDP datagrams received
+	Lost                  int64         `json:"lost"`        // UDP datagrams missing
+	LossPercent           float64       `json:"lossPercent"`
+	Jitter                time.Duration `json:"jitter"` // UDP jitter (RFC 3550) at the end of the interval
+}
+
+// BenchNetResult is the outcome of a throughput test run by the client.
+type BenchNetResult struct {
+	Server                string             `json:"server"`
+	Protocol              string             `json:"protocol"`
+	Streams               int                `json:"streams"`
+	BufferSize            int                `json:"bufferSize"`
+	Bitrate               int64              `json:"bitrate,omitempty"` // Target bits per second for UDP
+	Duration              time.Duration      `json:"duration"`
+	SentBytes             int64              `json:"sentBytes"`
+	SentBitsPerSecond     float64            `json:"sentBitsPerSecond"`
+	ReceivedBytes         int64              `json:"receivedBytes"`
+	ReceivedBitsPerSecond float64            `json:"receivedBitsPerSecond"`
+	RetransmitsAvailable  bool               `json:"retransmitsAvailable"`
+	Retransmits           int64              `json:"retransmits"`
+	PacketsSent           int64              `json:"packetsSent"`
+	Packets               int64              `json:"packets"`
+	Lost                  int64              `json:"lost"`
+	LossPercent           float64            `json:"lossPercent"`
+	OutOfOrder            int64              `json:"outOfOrder"`
+	Duplicates            int64              `json:"duplicates"` // UDP datagrams received more than once
+	Jitter                time.Duration      `json:"jitter"`
+	Intervals             []BenchNetInterval `json:"intervals"`
+}
+
+// BenchNetServerResult is what the server received during one test.
+type BenchNetServerResult struct {
+	Client        string        `json:"client"`
+	Protocol      string        `json:"protocol"`
+	Streams       int           `json:"streams"`
+	Duration      time.Duration `json:"duration"`
+	Bytes         int64         `json:"bytes"`
+	BitsPerSecond float64       `json:"bitsPerSecond"`
+	Packets       int64         `json:"packets"`
+	Lost          int64         `json:"lost"`
+	LossPercent   float64       `json:"lossPercent"`
+	Jitter        time.Duration `json:"jitter"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// benchNetHello is the first line of every connection to a server, as JSON. A control
+// connection describes the test; a data connection names the test its stream belongs to.
+type benchNetHello struct {
+	Role       string        `json:"role"` // "control" or "data"
+	ID         uint32        `json:"id,omitempty"`
+	Protocol   string        `json:"protocol,omitempty"`
+	Streams    int           `json:"streams,omitempty"`
+	Duration   time.Duration `json:"duration,omitempty"`
+	Interval   time.Duration `json:"interval,omitempty"`
+	BufferSize int           `json:"bufferSize,omitempty"`
+}
+
+// benchNetAccept is the server's answer to a control hello.
+type benchNetAccept struct {
+	ID    uint32 `json:"id"`
+	Error string `json:"error,omitempty"`
+}
+
+// benchNetDone tells the server that the client has stopped sending.
+type benchNetDone struct {
+	Sent []int64 `json:"sent"` // UDP datagrams sent on each stream
+}
+
+// benchNetReceived is what the server received during one interval.
+type benchNetReceived struct {
+	Bytes   int64         `json:"bytes"`
+	Packets int64         `json:"packets"`
+	Lost    int64         `json:"lost"`
+	Jitter  time.Duration `json:"jitter"`
+}
+
+// benchNetReport is the server's account of a test, sent when the client is done.
+type benchNetReport struct {
+	Intervals  []benchNetReceived `json:"intervals"`
+	OutOfOrder int64              `json:"outOfOrder"`
+	Duplicates int64              `json:"duplicates"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// writeBenchNetMessage sends a message as one line of JSON.
+func writeBenchNetMessage(conn net.Conn, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(append(data, '\n'))
+	return err
+}
+
+// readBenchNetMessage reads one line of JSON into v, waiting at most timeout.
+func readBenchNetMessage(conn net.Conn, reader *bufio.Reader, timeout time.Duration, v any) error {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, v)
+}
+
+// benchNetIntervals returns the number of reporting intervals in a test.
+func benchNetIntervals(duration, interval time.Duration) int {
+	return int((duration + interval - 1) / interval)
+}
+
+// BenchNetServer accepts throughput tests from bench-net clients, one test at a time.
+type BenchNetServer struct {
+	// OnResult, when set, is called after every test with what was received.
+	OnResult func(BenchNetServerResult)
+
+	listener net.Listener
+	packets  *net.UDPConn
+
+	mu     sync.Mutex
+	active *benchNetTest
+}
+
+// NewBenchNetServer listens on address for TCP connections and UDP datagrams on the same port.
+func NewBenchNetServer(address string) (*BenchNetServer, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	host, _, _ := net.SplitHostPort(address)
+	port := listener.Addr().(*net.TCPAddr).Port
+	udpAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	packets, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &BenchNetServer{listener: listener, packets: packets}, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *BenchNetServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve accepts tests until the server is closed.
+func (s *BenchNetServer) Serve() error {
+	go s.readDatagrams()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close stops the server.
+func (s *BenchNetServer) Close() error {
+	s.packets.Close()
+	return s.listener.Close()
+}
+
+// handle reads the hello of a new connection and serves it as a control or data connection.
+func (s *BenchNetServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	var hello benchNetHello
+	if err := readBenchNetMessage(conn, reader, benchNetHandshakeTimeout, &hello); err != nil {
+		return
+	}
+	switch hello.Role {
+	case "control":
+		s.control(conn, reader, hello)
+	case "data":
+		s.receive(conn, reader, hello.ID)
+	}
+}
+
+// control runs a test for a client: it accepts the test, waits until the client is done and
+// returns what was received.
+func (s *BenchNetServer) control(conn net.Conn, reader *bufio.Reader, hello benchNetHello) {
+	test, err := s.begin(hello)
+	if err != nil {
+		writeBenchNetMessage(conn, benchNetAccept{Error: err.Error()})
+		return
+	}
+	defer s.end(test)
+	if err := writeBenchNetMessage(conn, benchNetAccept{ID: test.id}); err != nil {
+		return
+	}
+
+	var done benchNetDone
+	if err := readBenchNetMessage(conn, reader, hello.Duration+2*benchNetHandshakeTimeout, &done); err != nil {
+		s.report(conn, test, benchNetReport{Error: "client did not finish the test"})
+		return
+	}
+	if hello.Protocol == BenchNetTCP {
+		select {
+		case <-test.finished:
+		case <-time.After(benchNetDrainTimeout):
+		}
+	} else {
+		time.Sleep(benchNetUDPDrain)
+	}
+	writeBenchNetMessage(conn, s.report(conn, test, test.report(done)))
+}
+
+// report passes the outcome of a test to OnResult and returns it.
+func (s *BenchNetServer) report(conn net.Conn, test *benchNetTest, report benchNetReport) benchNetReport {
+	if s.OnResult == nil {
+		return report
+	}
+	result := BenchNetServerResult{
+		Client:   conn.RemoteAddr().String(),
+		Protocol: test.hello.Protocol,
+		Streams:  test.hello.Streams,
+		Duration: test.hello.Duration,
+		Error:    report.Error,
+	}
+	var jitter time.Duration
+	for _, interval := range report.Intervals {
+		result.Bytes += interval.Bytes
+		result.Packets += interval.Packets
+		result.Lost += interval.Lost
+		if interval.Packets > 0 {
+			jitter = interval.Jitter
+		}
+	}
+	result.BitsPerSecond = benchNetBitrate(result.Bytes, result.Duration)
+	result.LossPercent = benchNetLoss(result.Packets, result.Lost)
+	result.Jitter = jitter
+	s.OnResult(result)
+	return report
+}
+
+// begin validates a test request and makes it the active test.
+func (s *BenchNetServer) begin(hello benchNetHello) (*benchNetTest, error) {
+	switch {
+	case hello.Protocol != BenchNetTCP && hello.Protocol != BenchNetUDP:
+		return nil, fmt.Errorf("unsupported protocol %q", hello.Protocol)
+	case hello.Streams < 1 || hello.Streams > benchNetMaxStreams:
+		return nil, fmt.Errorf("streams must be between 1 and %d", benchNetMaxStreams)
+	case hello.Duration <= 0 || hello.Interval <= 0:
+		return nil, fmt.Errorf("invalid duration or interval")
+	case hello.Duration > benchNetMaxDuration:
+		return nil, fmt.Errorf("duration must be at most %s", benchNetMaxDuration)
+	case hello.Interval < benchNetMinInterval:
+		return nil, fmt.Errorf("interval must be at least %s", benchNetMinInterval)
+	case hello.BufferSize < 1 || hello.BufferSize > 16<<20:
+		return nil, fmt.Errorf("invalid buffer size %d", hello.BufferSize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil {
+		return nil, fmt.Errorf("server is busy with another test")
+	}
+	s.active = &benchNetTest{
+		id:       rand.Uint32() | 1,
+		hello:    hello,
+		received: make([]benchNetReceived, benchNetIntervals(hello.Duration, hello.Interval)),
+		streams:  make(map[uint32]*benchNetStream),
+		finished: make(chan struct{}),
+	}
+	return s.active, nil
+}
+
+// end clears the active test.
+func (s *BenchNetServer) end(test *benchNetTest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == test {
+		s.active = nil
+	}
+}
+
+// lookup returns the active test when its ID is id.
+func (s *BenchNetServer) lookup(id uint32) *benchNetTest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil && s.active.id == id {
+		return s.active
+	}
+	return nil
+}
+
+// receive counts the bytes of a TCP stream until the client closes it.
+func (s *BenchNetServer) receive(conn net.Conn, reader *bufio.Reader, id uint32) {
+	test := s.lookup(id)
+	if test == nil || !test.join() {
+		return
+	}
+	defer test.leave()
+
+	conn.SetReadDeadline(time.Now().Add(test.hello.Duration + 2*benchNetHandshakeTimeout))
+	buf := make([]byte, test.hello.BufferSize)
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			test.mu.Lock()
+			test.bucket(time.Now()).Bytes += int64(n)
+			test.mu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readDatagrams counts the UDP datagrams of the active test until the server is closed.
+func (s *BenchNetServer) readDatagrams() {
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := s.packets.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		now := time.Now()
+		if n < benchNetHeaderSize {
+			continue
+		}
+		test := s.lookup(binary.BigEndian.Uint32(buf[0:4]))
+		if test == nil {
+			continue
+		}
+		test.datagram(now, n, binary.BigEndian.Uint32(buf[4:8]), binary.BigEndian.Uint64(buf[8:16]), int64(binary.BigEndian.Uint64(buf[16:24])))
+	}
+}
+
+// benchNetTest is the server's state of a running test.
+type benchNetTest struct {
+	id    uint32
+	hello benchNetHello
+
+	mu         sync.Mutex
+	start      time.Time
+	received   []benchNetReceived
+	streams    map[uint32]*benchNetStream
+	outOfOrder int64
+	duplicates int64
+	joined     int
+	left       int
+	finished   chan struct{} // Closed when every TCP stream has ended
+}
+
+// benchNetStream tracks sequence numbers and jitter of one UDP stream.
+type benchNetStream struct {
+	next    uint64        // Next expected sequence number
+	gaps    []benchNetGap // Sequence numbers still missing, in order
+	transit int64         // Transit time of the previous datagram in nanoseconds
+	jitter  float64       // RFC 3550 interarrival jitter in nanoseconds
+}
+
+// benchNetGap is a run of sequence numbers missing from a UDP stream, from up to but not
+// including to. They were counted as lost in the interval with index bucket.
+type benchNetGap struct {
+	from, to uint64
+	bucket   int
+}
+
+// fill removes seq from the stream's gaps and takes it off the loss of the interval the gap was
+// counted in. It reports whether seq was missing; otherwise it was already received.
+func (st *benchNetStream) fill(seq uint64, received []benchNetReceived) bool {
+	i := sort.Search(len(st.gaps), func(i int) bool { return st.gaps[i].to > seq })
+	if i == len(st.gaps) || seq < st.gaps[i].from {
+		return false
+	}
+	gap := st.gaps[i]
+	received[gap.bucket].Lost--
+	switch {
+	case gap.to-gap.from == 1:
+		st.gaps = append(st.gaps[:i], st.gaps[i+1:]...)
+	case seq == gap.from:
+		st.gaps[i].from++
+	case seq == gap.to-1:
+		st.gaps[i].to--
+	default:
+		// Split the gap around seq
+		st.gaps = append(st.gaps[:i+1], st.gaps[i:]...)
+		st.gaps[i].to = seq
+		st.gaps[i+1].from = seq + 1
+	}
+	return true
+}
+
+// join registers a TCP stream, refusing streams beyond the number requested.
+func (t *benchNetTest) join() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.joined >= t.hello.Streams {
+		return false
+	}
+	t.joined++
+	return true
+}
+
+// leave records the end of a TCP stream.
+func (t *benchNetTest) leave() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.left++
+	if t.left == t.hello.Streams {
+		close(t.finished)
+	}
+}
+
+// bucket returns the interval that now falls in, starting the test clock with the first data
+// received. Data arriving after the last interval counts towards the last one. t.mu must be held.
+func (t *benchNetTest) bucket(now time.Time) *benchNetReceived {
+	return &t.received[t.bucketIndex(now)]
+}
+
+// bucketIndex returns the index of the interval bucket returns. t.mu must be held.
+func (t *benchNetTest) bucketIndex(now time.Time) int {
+	if t.start.IsZero() {
+		t.start = now
+	}
+	return min(int(now.Sub(t.start)/t.hello.Interval), len(t.received)-1)
+}
+
+// datagram records a UDP datagram: gaps in the sequence count as lost in the interval they were
+// noticed in until the missing datagrams arrive late, duplicates are only counted, and jitter
+// follows RFC 3550 using the client's send times.
+func (t *benchNetTest) datagram(now time.Time, size int, stream uint32, seq uint64, sent int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if int(stream) >= t.hello.Streams {
+		return
+	}
+	i := t.bucketIndex(now)
+
+	st, ok := t.streams[stream]
+	if !ok {
+		st = &benchNetStream{}
+		t.streams[stream] = st
+	}
+	switch {
+	case seq == st.next:
+		st.next++
+	case seq > st.next:
+		t.received[i].Lost += int64(seq - st.next)
+		st.gaps = append(st.gaps, benchNetGap{from: st.next, to: seq, bucket: i})
+		st.next = seq + 1
+	case st.fill(seq, t.received):
+		t.outOfOrder++
+	default:
+		t.duplicates++
+		return
+	}
+	b := &t.received[i]
+	b.Bytes += int64(size)
+	b.Packets++
+
+	transit := now.UnixNano() - sent
+	if ok {
+		d := math.Abs(float64(transit - st.transit))
+		st.jitter += (d - st.jitter) / 16
+	}
+	st.transit = transit
+
+	var jitter float64
+	for _, s := range t.streams {
+		jitter += s.jitter
+	}
+	b.Jitter = time.Duration(jitter / float64(len(t.streams)))
+}
+
+// report returns what was received, counting datagrams the client sent after the last one
+// received on each stream as lost.
+func (t *benchNetTest) report(done benchNetDone) benchNetReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	last := &t.received[len(t.received)-1]
+	for i, sent := range done.Sent {
+		next := uint64(0)
+		if st, ok := t.streams[uint32(i)]; ok {
+			next = st.next
+		}
+		if sent > int64(next) {
+			last.Lost += sent - int64(next)
+		}
+	}
+	report := benchNetReport{Intervals: make([]benchNetReceived, len(t.received)), OutOfOrder: t.outOfOrder, Duplicates: t.duplicates}
+	copy(report.Intervals, t.received)
+	return report
+}
+
+// RunBenchNetClient runs a throughput test against a bench-net server on host: it sends for the
+// configured duration over every stream, sampling what was sent each interval, then asks the
+// server what it received.
+func RunBenchNetClient(host string, opts BenchNetOptions) (BenchNetResult, error) {
+	opts = opts.withDefaults()
+	result := BenchNetResult{Protocol: opts.Protocol, Streams: opts.Streams, BufferSize: opts.BufferSize}
+	switch {
+	case opts.Protocol != BenchNetTCP && opts.Protocol != BenchNetUDP:
+		return result, fmt.Errorf("unsupported protocol %q (use tcp or udp)", opts.Protocol)
+	case opts.Streams > benchNetMaxStreams:
+		return result, fmt.Errorf("at most %d streams are supported", benchNetMaxStreams)
+	case opts.Protocol == BenchNetUDP && (opts.BufferSize < benchNetHeaderSize || opts.BufferSize > 65507):
+		return result, fmt.Errorf("UDP datagram size must be between %d and 65507 bytes", benchNetHeaderSize)
+	}
+	if opts.Protocol == BenchNetUDP {
+		result.Bitrate = opts.Bitrate
+	}
+
+	control, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(opts.Port)), opts.Timeout)
+	if err != nil {
+		return result, err
+	}
+	defer control.Close()
+	result.Server = control.RemoteAddr().String()
+	reader := bufio.NewReader(control)
+
+	hello := benchNetHello{
+		Role:       "control",
+		Protocol:   opts.Protocol,
+		Streams:    opts.Streams,
+		Duration:   opts.Duration,
+		Interval:   opts.Interval,
+		BufferSize: opts.BufferSize,
+	}
+	if err := writeBenchNetMessage(control, hello); err != nil {
+		return result, err
+	}
+	var accept benchNetAccept
+	if err := readBenchNetMessage(control, reader, opts.Timeout, &accept); err != nil {
+		return result, fmt.Errorf("no answer from the bench-net server: %w", err)
+	}
+	if accept.Error != "" {
+		return result, fmt.Errorf("server refused the test: %s", accept.Error)
+	}
+
+	streams, err := openBenchNetStreams(result.Server, accept.ID, opts)
+	if err != nil {
+		return result, err
+	}
+	defer func() {
+		for _, stream := range streams {
+			stream.Close()
+		}
+	}()
+
+	sender := &benchNetSender{
+		id:      accept.ID,
+		opts:    opts,
+		start:   time.Now(),
+		bytes:   make([]atomic.Int64, len(streams)),
+		packets: make([]atomic.Int64, len(streams)),
+	}
+	sender.end = sender.start.Add(opts.Duration)
+	var wg sync.WaitGroup
+	for i, stream := range streams {
+		wg.Add(1)
+		go func(i int, stream net.Conn) {
+			defer wg.Done()
+			sender.run(i, stream)
+		}(i, stream)
+	}
+
+	// Sample what was sent at the end of every interval
+	var sentBefore, retransBefore int64
+	boundary := sender.start
+	for boundary.Before(sender.end) {
+		next := boundary.Add(opts.Interval)
+		if next.After(sender.end) {
+			next = sender.end
+		}
+		time.Sleep(time.Until(next))
+		if next.Equal(sender.end) {
+			wg.Wait()
+		}
+
+		sent := sender.sentBytes()
+		retrans, available := benchNetRetransmits(streams)
+		result.RetransmitsAvailable = available
+		result.Intervals = append(result.Intervals, BenchNetInterval{
+			Start:       boundary.Sub(sender.start),
+			End:         next.Sub(sender.start),
+			SentBytes:   sent - sentBefore,
+			Retransmits: retrans - retransBefore,
+		})
+		sentBefore, retransBefore = sent, retrans
+		boundary = next
+	}
+	if err := sender.failure(); err != nil {
+		return result, err
+	}
+
+	// Closing the TCP streams lets the server see the end of each one
+	for _, stream := range streams {
+		stream.Close()
+	}
+	done := benchNetDone{Sent: make([]int64, len(streams))}
+	for i := range sender.packets {
+		done.Sent[i] = sender.packets[i].Load()
+	}
+	if err := writeBenchNetMessage(control, done); err != nil {
+		return result, err
+	}
+	var report benchNetReport
+	if err := readBenchNetMessage(control, reader, benchNetDrainTimeout+benchNetHandshakeTimeout, &report); err != nil {
+		return result, fmt.Errorf("no report from the bench-net server: %w", err)
+	}
+	if report.Error != "" {
+		return result, fmt.Errorf("server: %s", report.Error)
+	}
+
+	result.merge(report, done)
+	return result, nil
+}
+
+// openBenchNetStreams opens the data streams of a test to the server's address.
+func openBenchNetStreams(address string, id uint32, opts BenchNetOptions) ([]net.Conn, error) {
+	var streams []net.Conn
+	for i := 0; i < opts.Streams; i++ {
+		stream, err := net.DialTimeout(opts.Protocol, address, opts.Timeout)
+		if err == nil && opts.Protocol == BenchNetTCP {
+			err = writeBenchNetMessage(stream, benchNetHello{Role: "data", ID: id})
+		}
+		if err != nil {
+			for _, open := range streams {
+				open.Close()
+			}
+			if stream != nil {
+				stream.Close()
+			}
+			return nil, fmt.Errorf("opening stream %d: %w", i+1, err)
+		}
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
+
+// benchNetSender sends the data of a test and counts what was sent on each stream.
+type benchNetSender struct {
+	id      uint32
+	opts    BenchNetOptions
+	start   time.Time
+	end     time.Time
+	bytes   []atomic.Int64
+	packets []atomic.Int64
+
+	mu  sync.Mutex
+	err error
+}
+
+// run sends on one stream until the end of the test.
+func (s *benchNetSender) run(i int, stream net.Conn) {
+	buf := make([]byte, s.opts.BufferSize)
+	if s.opts.Protocol == BenchNetTCP {
+		stream.SetWriteDeadline(s.end)
+		for {
+			n, err := stream.Write(buf)
+			s.bytes[i].Add(int64(n))
+			if err != nil {
+				if !errors.Is(err, os.ErrDeadlineExceeded) {
+					s.fail(err)
+				}
+				return
+			}
+		}
+	}
+
+	// UDP streams share the target bitrate and are paced against the time elapsed
+	binary.BigEndian.PutUint32(buf[0:4], s.id)
+	binary.BigEndian.PutUint32(buf[4:8], uint32(i))
+	bytesPerSecond := float64(s.opts.Bitrate) / 8 / float64(s.opts.Streams)
+	for seq := uint64(0); ; seq++ {
+		now := time.Now()
+		if !now.Before(s.end) {
+			return
+		}
+		if bytesPerSecond > 0 {
+			due := s.start.Add(time.Duration(float64(s.bytes[i].Load()) / bytesPerSecond * float64(time.Second)))
+			if due.After(now) {
+				if due.After(s.end) {
+					return
+				}
+				time.Sleep(due.Sub(now))
+			}
+		}
+		binary.BigEndian.PutUint64(buf[8:16], seq)
+		binary.BigEndian.PutUint64(buf[16:24], uint64(time.Now().UnixNano()))
+		n, err := stream.Write(buf)
+		if err != nil {
+			// A full socket buffer or an ICMP error is loss, not a reason to stop
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		s.bytes[i].Add(int64(n))
+		s.packets[i].Add(1)
+	}
+}
+
+// fail records the first error of any stream.
+func (s *benchNetSender) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// failure returns the first error of any stream.
+func (s *benchNetSender) failure() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// sentBytes returns the bytes sent so far on all streams.
+func (s *benchNetSender) sentBytes() int64 {
+	var total int64
+	for i := range s.bytes {
+		total += s.bytes[i].Load()
+	}
+	return total
+}
+
+// benchNetRetransmits returns the segments retransmitted so far on all TCP streams, and whether
+// the platform reports them.
+func benchNetRetransmits(streams []net.Conn) (int64, bool) {
+	var total int64
+	for _, stream := range streams {
+		conn, ok := stream.(*net.TCPConn)
+		if !ok {
+			return 0, false
+		}
+		retrans, ok := tcpRetransmits(conn)
+		if !ok {
+			return 0, false
+		}
+		total += retrans
+	}
+	return total, true
+}
+
+// merge combines the client's intervals with the server's report and computes the totals.
+func (r *BenchNetResult) merge(report benchNetReport, done benchNetDone) {
+	for i := range r.Intervals {
+		interval := &r.Intervals[i]
+		length := interval.End - interval.Start
+		interval.SentBitsPerSecond = benchNetBitrate(interval.SentBytes, length)
+		if i < len(report.Intervals) {
+			received := report.Intervals[i]
+			interval.ReceivedBytes = received.Bytes
+			interval.Packets = received.Packets
+			interval.Lost = max(received.Lost, 0)
+			interval.Jitter = received.Jitter
+		}
+		interval.ReceivedBitsPerSecond = benchNetBitrate(interval.ReceivedBytes, length)
+		interval.LossPercent = benchNetLoss(interval.Packets, interval.Lost)
+
+		r.Duration = interval.End
+		r.SentBytes += interval.SentBytes
+		r.ReceivedBytes += interval.ReceivedBytes
+		r.Retransmits += interval.Retransmits
+		r.Packets += interval.Packets
+		if interval.Packets > 0 {
+			r.Jitter = interval.Jitter
+		}
+	}
+	for _, sent := range done.Sent {
+		r.PacketsSent += sent
+	}
+	if r.Protocol == BenchNetUDP {
+		r.Lost = max(r.PacketsSent-r.Packets, 0)
+		r.LossPercent = benchNetLoss(r.PacketsSent-r.Lost, r.Lost)
+	}
+	r.OutOfOrder = report.OutOfOrder
+	r.Duplicates = report.Duplicates
+	r.SentBitsPerSecond = benchNetBitrate(r.SentBytes, r.Duration)
+	r.ReceivedBitsPerSecond = benchNetBitrate(r.ReceivedBytes, r.Duration)
+}
+
+// benchNetBitrate returns bytes transferred over a duration in bits per second.
+func benchNetBitrate(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / d.Seconds()
+}
+
+// benchNetLoss returns the percentage of datagrams lost.
+func benchNetLoss(received, lost int64) float64 {
+	if received+lost <= 0 {
+		return 0
+	}
+	return float64(lost) / float64(received+lost) * 100
+}
+
+// ParseBitrate parses a bitrate in bits per second with an optional K, M or G suffix
+// (powers of 1000), e.g. "100M" or "2.5G".
+func ParseBitrate(s string) (int64, error) {
+	value := strings.TrimSpace(s)
+	multiplier := 1.0
+	if value != "" {
+		switch strings.ToUpper(value[len(value)-1:]) {
+		case "K":
+			multiplier = 1e3
+		case "M":
+			multiplier = 1e6
+		case "G":
+			multiplier = 1e9
+		}
+		if multiplier > 1 {
+			value = value[:len(value)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid bitrate %q", s)
+	}
+	return int64(n * multiplier), nil
+}
+
+// formatBitrate formats bits per second with a decimal unit.
+func formatBitrate(bps float64) string {
+	units := []string{"bit/s", "Kbit/s", "Mbit/s", "Gbit/s", "Tbit/s"}
+	i := 0
+	for bps >= 1000 && i < len(units)-1 {
+		bps /= 1000
+		i++
+	}
+	return fmt.Sprintf("%.2f %s", bps, units[i])
+}
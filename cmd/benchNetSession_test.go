@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startTestBenchNetServer runs a bench-net server on a loopback port until the test ends and
+// returns the port. Every result the server reports is sent on the returned channel.
+func startTestBenchNetServer(t *testing.T) (int, chan BenchNetServerResult) {
+	t.Helper()
+	server, err := NewBenchNetServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan BenchNetServerResult, 1)
+	server.OnResult = func(result BenchNetServerResult) { results <- result }
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		server.Serve()
+	}()
+	t.Cleanup(func() {
+		server.Close()
+		wg.Wait()
+	})
+	return server.Addr().(*net.TCPAddr).Port, results
+}
+
+func TestBenchNetLoopback(t *testing.T) {
+	port, serverResults := startTestBenchNetServer(t)
+	tests := []BenchNetOptions{
+		{Protocol: BenchNetTCP, Streams: 2},
+		{Protocol: BenchNetUDP, Streams: 2, Bitrate: 8_000_000},
+	}
+	for _, opts := range tests {
+		t.Run(opts.Protocol, func(t *testing.T) {
+			opts.Port = port
+			opts.Duration = 600 * time.Millisecond
+			opts.Interval = 200 * time.Millisecond
+			result, err := RunBenchNetClient("127.0.0.1", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := <-serverResults
+
+			if len(result.Intervals) != 3 || result.Duration != opts.Duration {
+				t.Fatalf("%d intervals over %v, want 3 over %v", len(result.Intervals), result.Duration, opts.Duration)
+			}
+			var sent, received, packets int64
+			for _, interval := range result.Intervals {
+				sent += interval.SentBytes
+				received += interval.ReceivedBytes
+				packets += interval.Packets
+			}
+			if sent != result.SentBytes || received != result.ReceivedBytes || packets != result.Packets {
+				t.Errorf("totals %d/%d/%d are not the sums of the intervals %d/%d/%d", result.SentBytes, result.ReceivedBytes, result.Packets, sent, received, packets)
+			}
+			if result.SentBytes == 0 || server.Bytes != result.ReceivedBytes || server.Streams != 2 || server.Error != "" {
+				t.Errorf("client sent %d and received %d; server result %+v", result.SentBytes, result.ReceivedBytes, server)
+			}
+
+			if opts.Protocol == BenchNetTCP {
+				// Everything written to a TCP stream arrives
+				if result.ReceivedBytes != result.SentBytes || result.Packets != 0 {
+					t.Errorf("sent %d bytes, received %d and %d datagrams", result.SentBytes, result.ReceivedBytes, result.Packets)
+				}
+				return
+			}
+			// 8 Mbit/s in 1400-byte datagrams for 0.6s is about 430 datagrams
+			if result.PacketsSent < 100 || result.PacketsSent > 500 {
+				t.Errorf("sent %d datagrams, want no more than about 430 at the target bitrate", result.PacketsSent)
+			}
+			if result.Packets+result.Lost != result.PacketsSent || result.Duplicates != 0 {
+				t.Errorf("received %d and lost %d of %d datagrams, %d duplicated", result.Packets, result.Lost, result.PacketsSent, result.Duplicates)
+			}
+			if server.Packets != result.Packets {
+				t.Errorf("server counted %d datagrams, client reports %d", server.Packets, result.Packets)
+			}
+		})
+	}
+}
+
+func TestBenchNetServerRejectsHello(t *testing.T) {
+	port, _ := startTestBenchNetServer(t)
+	tests := []struct {
+		opts BenchNetOptions
+		err  string
+	}{
+		{BenchNetOptions{Duration: 2 * time.Hour}, "duration must be at most 1h0m0s"},
+		{BenchNetOptions{Interval: time.Millisecond}, "interval must be at least 100ms"},
+		{BenchNetOptions{BufferSize: 32 << 20}, "invalid buffer size"},
+	}
+	for _, tt := range tests {
+		tt.opts.Port = port
+		_, err := RunBenchNetClient("127.0.0.1", tt.opts)
+		if err == nil || !strings.Contains(err.Error(), "server refused the test: "+tt.err) {
+			t.Errorf("%+v: got %v, want the server to refuse with %q", tt.opts, err, tt.err)
+		}
+	}
+}
+
+func TestBenchNetDatagramLoss(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	test := &benchNetTest{
+		hello:    benchNetHello{Protocol: BenchNetUDP, Streams: 1, Duration: 3 * time.Second, Interval: time.Second},
+		received: make([]benchNetReceived, 3),
+		streams:  make(map[uint32]*benchNetStream),
+	}
+	at := func(offset time.Duration, seqs ...uint64) {
+		for _, seq := range seqs {
+			test.datagram(start.Add(offset), 100, 0, seq, start.UnixNano())
+		}
+	}
+	lost := func() []int64 {
+		var lost []int64
+		for _, interval := range test.received {
+			lost = append(lost, interval.Lost)
+		}
+		return lost
+	}
+
+	at(0, 0, 3)                  // 1 and 2 go missing in the first interval
+	at(1500*time.Millisecond, 1) // Late, in the second interval
+	at(1600*time.Millisecond, 1) // Duplicate
+	at(2100*time.Millisecond, 10)
+	at(2200*time.Millisecond, 6, 4, 9, 2)
+	if got := lost(); got[0] != 0 || got[1] != 0 || got[2] != 3 {
+		t.Errorf("lost per interval = %v, want [0 0 3]", got)
+	}
+	if test.outOfOrder != 5 || test.duplicates != 1 {
+		t.Errorf("out of order %d duplicates %d, want 5 and 1", test.outOfOrder, test.duplicates)
+	}
+	if packets := test.received[0].Packets + test.received[1].Packets + test.received[2].Packets; packets != 8 {
+		t.Errorf("counted %d datagrams, want 8 without the duplicate", packets)
+	}
+	if gaps := test.streams[0].gaps; len(gaps) != 2 || gaps[0] != (benchNetGap{5, 6, 2}) || gaps[1] != (benchNetGap{7, 9, 2}) {
+		t.Errorf("gaps = %+v, want 5 and 7-8 missing", gaps)
+	}
+
+	// Datagrams sent after the last one received are lost at the end
+	report := test.report(benchNetDone{Sent: []int64{13}})
+	if report.Intervals[2].Lost != 5 || report.OutOfOrder != 5 || report.Duplicates != 1 {
+		t.Errorf("report = %+v, want 5 lost at the end", report)
+	}
+}
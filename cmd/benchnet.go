@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+	"github.com/spf13/cobra"
+)
+
+// BenchNetCmd groups the server and client of the network throughput test.
+var BenchNetCmd = &cobra.Command{
+	Use:   "bench-net",
+	Short: "Measures network throughput between two ghost instances",
+	Long: `Measures the bandwidth between two hosts, like iperf: run "ghost bench-net server" on one host
+and "ghost bench-net client <host>" on the other. The client sends TCP (one or more parallel
+streams) or UDP (at a target bitrate) to the server for the test duration and reports what was
+sent and received in every interval, with TCP retransmits where the platform reports them and
+UDP loss and jitter.
+
+The server listens on TCP and UDP port 5201 by default and runs one test at a time.`,
+}
+
+// BenchNetServerCmd runs a throughput test server.
+var BenchNetServerCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Accepts throughput tests from bench-net clients",
+	Run: func(cmd *cobra.Command, args []string) {
+		bind, _ := cmd.Flags().GetString("bind")
+		port, _ := cmd.Flags().GetInt("port")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+
+		server, err := NewBenchNetServer(net.JoinHostPort(bind, strconv.Itoa(port)))
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		server.OnResult = func(result BenchNetServerResult) {
+			if jsonOutput {
+				// The server runs until interrupted, so each test gets a compact line of its own
+				data, _ := json.Marshal(result)
+				fmt.Println(string(data))
+				return
+			}
+			PrintBenchNetServerResult(result)
+		}
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		go func() {
+			<-interrupt
+			server.Close()
+		}()
+
+		if !jsonOutput {
+			fmt.Printf("Listening for bench-net clients on %s (tcp and udp). Press Ctrl-C to stop.\n", server.Addr())
+		}
+		if err := server.Serve(); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// BenchNetClientCmd runs a throughput test against a server.
+var BenchNetClientCmd = &cobra.Command{
+	Use:   "client <host>",
+	Short: "Runs a throughput test against a bench-net server",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := BenchNetOptions{}
+		opts.Port, _ = cmd.Flags().GetInt("port")
+		opts.Protocol, _ = cmd.Flags().GetString("proto")
+		opts.Streams, _ = cmd.Flags().GetInt("parallel")
+		opts.Duration, _ = cmd.Flags().GetDuration("duration")
+		opts.Interval, _ = cmd.Flags().GetDuration("interval")
+		opts.BufferSize, _ = cmd.Flags().GetInt("buffer-size")
+		opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+		bitrate, _ := cmd.Flags().GetString("bitrate")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+
+		var err error
+		opts.Bitrate, err = ParseBitrate(bitrate)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		result, err := RunBenchNetClient(args[0], opts)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if jsonOutput {
+			utils.PrintJSON(result)
+			return
+		}
+		PrintBenchNetResult(result)
+	},
+}
+
+// init registers the bench-net commands with the root command when this package is imported.
+func init() {
+	RootCmd.AddCommand(BenchNetCmd)
+	BenchNetCmd.AddCommand(BenchNetServerCmd)
+	BenchNetCmd.AddCommand(BenchNetClientCmd)
+
+	BenchNetCmd.PersistentFlags().IntP("port", "p", benchNetPort, "Server port, for both TCP and UDP")
+	BenchNetCmd.PersistentFlags().Bool("json", false, "Print the results as JSON")
+	BenchNetServerCmd.Flags().String("bind", "", "Address to listen on (default: all addresses)")
+	BenchNetClientCmd.Flags().StringP("proto", "P", BenchNetTCP, "Test protocol: tcp or udp")
+	BenchNetClientCmd.Flags().Int("parallel", 1, "Number of parallel streams")
+	BenchNetClientCmd.Flags().DurationP("duration", "t", 10*time.Second, "Length of the test")
+	BenchNetClientCmd.Flags().Duration("interval", time.Second, "Reporting interval")
+	BenchNetClientCmd.Flags().Int("buffer-size", 0, "Bytes per write for TCP or per datagram for UDP (default: 131072 for tcp, 1400 for udp)")
+	BenchNetClientCmd.Flags().String("bitrate", "1M", "Target UDP bitrate across all streams in bits per second, with K, M or G suffix; 0 sends as fast as possible")
+	BenchNetClientCmd.Flags().Duration("timeout", benchNetHandshakeTimeout, "Time to wait for the server to answer")
+}
+
+// PrintBenchNetResult displays the intervals and totals of a throughput test.
+func PrintBenchNetResult(result BenchNetResult) {
+	udp := result.Protocol == BenchNetUDP
+	t := utils.Table("DarkSimple", "Intervals")
+	header := table.Row{"Interval", "Sent", "Sent Rate", "Received", "Received Rate"}
+	switch {
+	case udp:
+		header = append(header, "Datagrams", "Lost", "Jitter")
+	case result.RetransmitsAvailable:
+		header = append(header, "Retransmits")
+	}
+	t.AppendHeader(header)
+	for _, interval := range result.Intervals {
+		row := table.Row{
+			fmt.Sprintf("%.1f-%.1fs", interval.Start.Seconds(), interval.End.Seconds()),
+			PrettyBytes(interval.SentBytes),
+			formatBitrate(interval.SentBitsPerSecond),
+			PrettyBytes(interval.ReceivedBytes),
+			formatBitrate(interval.ReceivedBitsPerSecond),
+		}
+		switch {
+		case udp:
+			row = append(row, interval.Packets, fmt.Sprintf("%d (%.1f%%)", interval.Lost, interval.LossPercent), formatPingRTT(interval.Jitter))
+		case result.RetransmitsAvailable:
+			row = append(row, interval.Retransmits)
+		}
+		t.AppendRow(row)
+	}
+	t.AppendSeparator()
+	total := table.Row{
+		fmt.Sprintf("%.1f-%.1fs", 0.0, result.Duration.Seconds()),
+		PrettyBytes(result.SentBytes),
+		formatBitrate(result.SentBitsPerSecond),
+		PrettyBytes(result.ReceivedBytes),
+		formatBitrate(result.ReceivedBitsPerSecond),
+	}
+	switch {
+	case udp:
+		total = append(total, result.Packets, fmt.Sprintf("%d/%d (%.1f%%)", result.Lost, result.PacketsSent, result.LossPercent), formatPingRTT(result.Jitter))
+	case result.RetransmitsAvailable:
+		total = append(total, result.Retransmits)
+	}
+	t.AppendRow(total)
+	fmt.Println()
+	t.Render()
+
+	description := fmt.Sprintf("%s test to %s: %d stream(s), %s buffers", result.Protocol, result.Server, result.Streams, PrettyBytes(int64(result.BufferSize)))
+	if udp {
+		target := "unlimited"
+		if result.Bitrate > 0 {
+			target = formatBitrate(float64(result.Bitrate))
+		}
+		description += ", target " + target
+		if result.OutOfOrder > 0 {
+			description += fmt.Sprintf(", %d datagrams out of order", result.OutOfOrder)
+		}
+		if result.Duplicates > 0 {
+			description += fmt.Sprintf(", %d duplicated", result.Duplicates)
+		}
+	} else if !result.RetransmitsAvailable {
+		description += ", retransmits not available on this platform"
+	}
+	fmt.Println(description + ".")
+	fmt.Println()
+}
+
+// PrintBenchNetServerResult displays a one-line summary of a test the server received.
+func PrintBenchNetServerResult(result BenchNetServerResult) {
+	line := fmt.Sprintf("[%s] %s test from %s, %d stream(s), %s: received %s, %s",
+		time.Now().Format("15:04:05"), result.Protocol, result.Client, result.Streams, result.Duration,
+		PrettyBytes(result.Bytes), formatBitrate(result.BitsPerSecond))
+	if result.Protocol == BenchNetUDP {
+		line += fmt.Sprintf(", %d datagrams, %d lost (%.1f%%), jitter %s", result.Packets, result.Lost, result.LossPercent, formatPingRTT(result.Jitter))
+	}
+	if result.Error != "" {
+		utils.TerminalColor(line+": "+result.Error, utils.Warn)
+		return
+	}
+	fmt.Println(line)
+}
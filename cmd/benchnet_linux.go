@@ -0,0 +1,28 @@
+//go:build linux
+// +build linux
+
+package cmd
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// tcpRetransmits returns the number of segments the kernel has retransmitted on a TCP
+// connection, from TCP_INFO.
+func tcpRetransmits(conn *net.TCPConn) (int64, bool) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+	var info *unix.TCPInfo
+	var infoErr error
+	err = raw.Control(func(fd uintptr) {
+		info, infoErr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	})
+	if err != nil || infoErr != nil {
+		return 0, false
+	}
+	return int64(info.Total_retrans), true
+}
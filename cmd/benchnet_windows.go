@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+import "net"
+
+// tcpRetransmits is not supported on Windows, so throughput tests report no retransmits.
+func tcpRetransmits(conn *net.TCPConn) (int64, bool) {
+	return 0, false
+}
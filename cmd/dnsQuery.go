@@ -0,0 +1,432 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Transports a DNS query can be sent over.
+const (
+	DNSTransportUDP = "udp"
+	DNSTransportTCP = "tcp"
+	DNSTransportTLS = "tls" // DNS over TLS (RFC 7858)
+)
+
+// Default DNS ports for plain DNS and DNS over TLS.
+const (
+	dnsPort    = 53
+	dnsTLSPort = 853
+)
+
+// dnsRecordTypes maps the record types dnslookup can query to their wire types.
+var dnsRecordTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"TXT":   dnsmessage.TypeTXT,
+	"SRV":   dnsmessage.TypeSRV,
+	"NS":    dnsmessage.TypeNS,
+	"SOA":   dnsmessage.TypeSOA,
+	"PTR":   dnsmessage.TypePTR,
+}
+
+// DNSRecord is a single resource record from a DNS response.
+type DNSRecord struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TTL  uint32 `json:"ttl"`  // Seconds
+	Data string `json:"data"` // The record data in zone-file presentation format
+}
+
+// DNSLookupResult is the response of one server to one query.
+type DNSLookupResult struct {
+	Server        string        `json:"server"`
+	Transport     string        `json:"transport"`
+	Name          string        `json:"name"`
+	Type          string        `json:"type"`
+	RCode         string        `json:"rcode"` // Response code, e.g. NOERROR or NXDOMAIN
+	Authoritative bool          `json:"authoritative"`
+	Truncated     bool          `json:"truncated"` // The UDP answer was truncated and the query retried over TCP
+	Answers       []DNSRecord   `json:"answers"`
+	Authority     []DNSRecord   `json:"authority"`
+	ResponseTime  time.Duration `json:"responseTime"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// DNSLookupOptions controls how dnslookup queries are sent.
+type DNSLookupOptions struct {
+	Servers   []string      // Server addresses (host, host:port or [v6]:port); empty uses the system's resolvers
+	Transport string        // DNSTransportUDP (default), DNSTransportTCP or DNSTransportTLS
+	Timeout   time.Duration // Limit for each query
+}
+
+// withDefaults fills in zero-valued options with the command's defaults.
+func (opts DNSLookupOptions) withDefaults() DNSLookupOptions {
+	opts.Transport = strings.ToLower(opts.Transport)
+	if opts.Transport == "" {
+		opts.Transport = DNSTransportUDP
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 3 * time.Second
+	}
+	return opts
+}
+
+// dnsServerAddress adds the transport's default port to a server given without one.
+func dnsServerAddress(server string, transport string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	port := dnsPort
+	if transport == DNSTransportTLS {
+		port = dnsTLSPort
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), strconv.Itoa(port))
+}
+
+// dnsQueryName returns the fully qualified name to query. For PTR queries an IP address is
+// turned into its in-addr.arpa or ip6.arpa name.
+func dnsQueryName(name string, recordType string) (string, error) {
+	if recordType == "PTR" {
+		if ip := net.ParseIP(name); ip != nil {
+			arpa, err := reverseDNSName(ip)
+			if err != nil {
+				return "", err
+			}
+			name = arpa
+		}
+	}
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name, nil
+}
+
+// reverseDNSName returns the name PTR records for ip are published under.
+func reverseDNSName(ip net.IP) (string, error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", ip4[3], ip4[2], ip4[1], ip4[0]), nil
+	}
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return "", fmt.Errorf("invalid IP address %s", ip)
+	}
+	digits := hex.EncodeToString(ip16)
+	var b strings.Builder
+	for i := len(digits) - 1; i >= 0; i-- {
+		b.WriteByte(digits[i])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String(), nil
+}
+
+// RunDNSLookup sends a query for name and each of the record types to every server concurrently.
+// Failures are reported in the Error field of the affected results rather than as an error.
+func RunDNSLookup(name string, recordTypes []string, opts DNSLookupOptions) ([]DNSLookupResult, error) {
+	opts = opts.withDefaults()
+	switch opts.Transport {
+	case DNSTransportUDP, DNSTransportTCP, DNSTransportTLS:
+	default:
+		return nil, fmt.Errorf("unknown transport %q (use udp, tcp or tls)", opts.Transport)
+	}
+	servers := opts.Servers
+	if len(servers) == 0 {
+		var err error
+		if servers, err = systemNameservers(); err != nil {
+			return nil, err
+		}
+	}
+
+	type query struct {
+		name, recordType string
+		qtype            dnsmessage.Type
+	}
+	var queries []query
+	for _, recordType := range recordTypes {
+		recordType = strings.ToUpper(recordType)
+		qtype, ok := dnsRecordTypes[recordType]
+		if !ok {
+			return nil, fmt.Errorf("unsupported record type %q", recordType)
+		}
+		qname, err := dnsQueryName(name, recordType)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, query{qname, recordType, qtype})
+	}
+
+	results := make([]DNSLookupResult, len(queries)*len(servers))
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		for j, server := range servers {
+			wg.Add(1)
+			go func(result *DNSLookupResult, q query, server string) {
+				defer wg.Done()
+				*result = queryDNS(dnsServerAddress(server, opts.Transport), opts.Transport, q.name, q.recordType, q.qtype, opts.Timeout)
+			}(&results[i*len(servers)+j], q, server)
+		}
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// queryDNS sends one query to server and parses the response. A truncated UDP response is
+// retried over TCP, like stub resolvers do.
+func queryDNS(server, transport, name, recordType string, qtype dnsmessage.Type, timeout time.Duration) DNSLookupResult {
+	result := DNSLookupResult{Server: server, Transport: transport, Name: name, Type: recordType}
+	query, id, err := buildDNSQuery(name, qtype)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	response, err := exchangeDNS(server, transport, query, timeout)
+	if err == nil && transport == DNSTransportUDP && len(response) > 2 && response[2]&0x02 != 0 {
+		result.Truncated = true
+		response, err = exchangeDNS(server, DNSTransportTCP, query, timeout)
+	}
+	result.ResponseTime = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if err := parseDNSResponse(response, id, &result); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// buildDNSQuery builds a recursive query for name with an EDNS(0) record allowing 1232-byte UDP
+// responses, the size recommended to avoid IP fragmentation.
+func buildDNSQuery(name string, qtype dnsmessage.Type) ([]byte, uint16, error) {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid name %q: %w", name, err)
+	}
+	id := uint16(rand.Intn(1 << 16))
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, 0, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, 0, err
+	}
+	if err := b.StartAdditionals(); err != nil {
+		return nil, 0, err
+	}
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(1232, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, 0, err
+	}
+	if err := b.OPTResource(opt, dnsmessage.OPTResource{}); err != nil {
+		return nil, 0, err
+	}
+	msg, err := b.Finish()
+	return msg, id, err
+}
+
+// exchangeDNS sends a query to server and returns the raw response. TCP and TLS messages are
+// prefixed with their two-byte length.
+func exchangeDNS(server, transport string, query []byte, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	var conn net.Conn
+	var err error
+	switch transport {
+	case DNSTransportUDP:
+		conn, err = (&net.Dialer{}).DialContext(ctx, "udp", server)
+	case DNSTransportTCP:
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", server)
+	case DNSTransportTLS:
+		host, _, _ := net.SplitHostPort(server)
+		conn, err = (&tls.Dialer{Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+
+	if transport == DNSTransportUDP {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(framed, query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// parseDNSResponse fills result from a response to the query with the given ID.
+func parseDNSResponse(response []byte, id uint16, result *DNSLookupResult) error {
+	var p dnsmessage.Parser
+	header, err := p.Start(response)
+	if err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	if header.ID != id {
+		return errors.New("response ID does not match the query")
+	}
+	result.RCode = dnsRCodeName(header.RCode)
+	result.Authoritative = header.Authoritative
+	if err := p.SkipAllQuestions(); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	if result.Answers, err = parseDNSSection(&p, p.AnswerHeader, p.SkipAnswer); err != nil {
+		return err
+	}
+	if result.Authority, err = parseDNSSection(&p, p.AuthorityHeader, p.SkipAuthority); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseDNSSection reads the records of the parser's current section.
+func parseDNSSection(p *dnsmessage.Parser, next func() (dnsmessage.ResourceHeader, error), skip func() error) ([]DNSRecord, error) {
+	records := []DNSRecord{}
+	for {
+		header, err := next()
+		if err == dnsmessage.ErrSectionDone {
+			return records, nil
+		}
+		if err != nil {
+			return records, fmt.Errorf("invalid response: %w", err)
+		}
+		if header.Type == dnsmessage.TypeOPT {
+			if err := skip(); err != nil {
+				return records, fmt.Errorf("invalid response: %w", err)
+			}
+			continue
+		}
+		data, err := dnsRecordData(p, header.Type)
+		if err != nil {
+			return records, fmt.Errorf("invalid response: %w", err)
+		}
+		records = append(records, DNSRecord{
+			Name: header.Name.String(),
+			Type: dnsTypeName(header.Type),
+			TTL:  header.TTL,
+			Data: data,
+		})
+	}
+}
+
+// dnsRecordData reads the body of the record the parser is at and formats it in zone-file
+// presentation format. Types without a dedicated parser are shown in the RFC 3597 generic form.
+func dnsRecordData(p *dnsmessage.Parser, rtype dnsmessage.Type) (string, error) {
+	switch rtype {
+	case dnsmessage.TypeA:
+		r, err := p.AResource()
+		return net.IP(r.A[:]).String(), err
+	case dnsmessage.TypeAAAA:
+		r, err := p.AAAAResource()
+		return net.IP(r.AAAA[:]).String(), err
+	case dnsmessage.TypeCNAME:
+		r, err := p.CNAMEResource()
+		return r.CNAME.String(), err
+	case dnsmessage.TypeNS:
+		r, err := p.NSResource()
+		return r.NS.String(), err
+	case dnsmessage.TypePTR:
+		r, err := p.PTRResource()
+		return r.PTR.String(), err
+	case dnsmessage.TypeMX:
+		r, err := p.MXResource()
+		return fmt.Sprintf("%d %s", r.Pref, r.MX), err
+	case dnsmessage.TypeSRV:
+		r, err := p.SRVResource()
+		return fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, r.Target), err
+	case dnsmessage.TypeSOA:
+		r, err := p.SOAResource()
+		return fmt.Sprintf("%s %s %d %d %d %d %d", r.NS, r.MBox, r.Serial, r.Refresh, r.Retry, r.Expire, r.MinTTL), err
+	case dnsmessage.TypeTXT:
+		r, err := p.TXTResource()
+		parts := make([]string, len(r.TXT))
+		for i, txt := range r.TXT {
+			parts[i] = strconv.Quote(txt)
+		}
+		return strings.Join(parts, " "), err
+	}
+	r, err := p.UnknownResource()
+	return fmt.Sprintf("\\# %d %x", len(r.Data), r.Data), err
+}
+
+// dnsTypeName returns the mnemonic of a record type, such as AAAA.
+func dnsTypeName(rtype dnsmessage.Type) string {
+	for name, t := range dnsRecordTypes {
+		if t == rtype {
+			return name
+		}
+	}
+	return "TYPE" + strconv.Itoa(int(rtype))
+}
+
+// dnsRCodeName returns the mnemonic of a response code, such as NXDOMAIN.
+func dnsRCodeName(rcode dnsmessage.RCode) string {
+	switch rcode {
+	case dnsmessage.RCodeSuccess:
+		return "NOERROR"
+	case dnsmessage.RCodeFormatError:
+		return "FORMERR"
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL"
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN"
+	case dnsmessage.RCodeNotImplemented:
+		return "NOTIMP"
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	}
+	return "RCODE" + strconv.Itoa(int(rcode))
+}
+
+// dnsAnswerKey summarizes the outcome of a query so results from different servers can be
+// compared: the response code and the sorted answer data, ignoring TTLs and record order.
+func dnsAnswerKey(result DNSLookupResult) string {
+	if result.Error != "" {
+		return "error"
+	}
+	var data []string
+	for _, answer := range result.Answers {
+		data = append(data, answer.Type+" "+answer.Data)
+	}
+	sort.Strings(data)
+	return result.RCode + "|" + strings.Join(data, ",")
+}
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsTestHandler builds the response to a query a test server received over UDP or TCP.
+type dnsTestHandler func(query dnsmessage.Message, tcp bool) dnsmessage.Message
+
+// startTestDNSServer serves handler over UDP and TCP on the same loopback port and returns its
+// address. The servers stop when the test ends.
+func startTestDNSServer(t *testing.T, handler dnsTestHandler) string {
+	t.Helper()
+	var udp net.PacketConn
+	var tcp net.Listener
+	for attempt := 0; tcp == nil; attempt++ {
+		var err error
+		if udp, err = net.ListenPacket("udp4", "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+		// The port is free for UDP but may be taken for TCP
+		if tcp, err = net.Listen("tcp4", udp.LocalAddr().String()); err != nil {
+			udp.Close()
+			if attempt == 10 {
+				t.Fatal(err)
+			}
+		}
+	}
+	t.Cleanup(func() {
+		udp.Close()
+		tcp.Close()
+	})
+
+	respond := func(request []byte, isTCP bool) []byte {
+		var query dnsmessage.Message
+		if err := query.Unpack(request); err != nil {
+			return nil
+		}
+		response := handler(query, isTCP)
+		packed, err := response.Pack()
+		if err != nil {
+			t.Errorf("packing the test response: %v", err)
+			return nil
+		}
+		return packed
+	}
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, from, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if response := respond(buf[:n], false); response != nil {
+				udp.WriteTo(response, from)
+			}
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				request := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, request); err != nil {
+					return
+				}
+				if response := respond(request, true); response != nil {
+					conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(response))), response...))
+				}
+			}()
+		}
+	}()
+	return udp.LocalAddr().String()
+}
+
+// answerA returns a handler answering every query with A records for addresses.
+func answerA(ttl uint32, addresses ...string) dnsTestHandler {
+	return func(query dnsmessage.Message, tcp bool) dnsmessage.Message {
+		response := dnsReply(query, dnsmessage.RCodeSuccess)
+		for _, address := range addresses {
+			var a [4]byte
+			copy(a[:], net.ParseIP(address).To4())
+			response.Answers = append(response.Answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+				Body:   &dnsmessage.AResource{A: a},
+			})
+		}
+		return response
+	}
+}
+
+// dnsReply starts the response to query with the given response code.
+func dnsReply(query dnsmessage.Message, rcode dnsmessage.RCode) dnsmessage.Message {
+	return dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, RecursionDesired: true, RecursionAvailable: true, RCode: rcode},
+		Questions: query.Questions,
+	}
+}
+
+func TestRunDNSLookupLoopback(t *testing.T) {
+	answer := answerA(300, "192.0.2.10", "192.0.2.11")
+	truncating := func(query dnsmessage.Message, tcp bool) dnsmessage.Message {
+		if !tcp {
+			response := dnsReply(query, dnsmessage.RCodeSuccess)
+			response.Truncated = true
+			return response
+		}
+		return answer(query, tcp)
+	}
+	wrongID := func(query dnsmessage.Message, tcp bool) dnsmessage.Message {
+		query.ID++
+		return answer(query, tcp)
+	}
+	nxdomain := func(query dnsmessage.Message, tcp bool) dnsmessage.Message {
+		response := dnsReply(query, dnsmessage.RCodeNameError)
+		response.Authoritative = true
+		response.Authorities = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("test."), Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body: &dnsmessage.SOAResource{NS: dnsmessage.MustNewName("ns.test."), MBox: dnsmessage.MustNewName("admin.test."),
+				Serial: 2024050101, Refresh: 7200, Retry: 900, Expire: 1209600, MinTTL: 60},
+		}}
+		return response
+	}
+
+	tests := []struct {
+		name      string
+		handler   dnsTestHandler
+		transport string
+		rcode     string
+		answers   []string
+		authority []string
+		truncated bool
+		err       string
+	}{
+		{name: "UDP", handler: answer, transport: DNSTransportUDP, rcode: "NOERROR", answers: []string{"192.0.2.10", "192.0.2.11"}},
+		{name: "TCP", handler: answer, transport: DNSTransportTCP, rcode: "NOERROR", answers: []string{"192.0.2.10", "192.0.2.11"}},
+		{name: "truncated UDP retried over TCP", handler: truncating, transport: DNSTransportUDP, rcode: "NOERROR",
+			answers: []string{"192.0.2.10", "192.0.2.11"}, truncated: true},
+		{name: "ID mismatch", handler: wrongID, transport: DNSTransportUDP, err: "response ID does not match the query"},
+		{name: "NXDOMAIN", handler: nxdomain, transport: DNSTransportUDP, rcode: "NXDOMAIN",
+			authority: []string{"ns.test. admin.test. 2024050101 7200 900 1209600 60"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := startTestDNSServer(t, tt.handler)
+			results, err := RunDNSLookup("host.test", []string{"a"}, DNSLookupOptions{Servers: []string{server}, Transport: tt.transport, Timeout: 2 * time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			result := results[0]
+			if result.Name != "host.test." || result.Type != "A" || result.Server != server || result.Transport != tt.transport {
+				t.Errorf("result describes %s %s @%s (%s)", result.Name, result.Type, result.Server, result.Transport)
+			}
+			if result.Error != tt.err {
+				t.Fatalf("Error = %q, want %q", result.Error, tt.err)
+			}
+			if result.RCode != tt.rcode || result.Truncated != tt.truncated {
+				t.Errorf("RCode %q Truncated %v, want %q %v", result.RCode, result.Truncated, tt.rcode, tt.truncated)
+			}
+			if got := recordData(result.Answers); !reflect.DeepEqual(got, tt.answers) {
+				t.Errorf("answers = %v, want %v", got, tt.answers)
+			}
+			if got := recordData(result.Authority); !reflect.DeepEqual(got, tt.authority) {
+				t.Errorf("authority = %v, want %v", got, tt.authority)
+			}
+			if tt.err == "" && (result.Answers == nil || result.Authority == nil) {
+				t.Error("empty sections must be empty slices so the JSON output has [] rather than null")
+			}
+		})
+	}
+}
+
+func TestCompareDNSResultsLoopback(t *testing.T) {
+	first := startTestDNSServer(t, answerA(300, "192.0.2.10", "192.0.2.11"))
+	// Same records in a different order and with a different TTL
+	second := startTestDNSServer(t, answerA(42, "192.0.2.11", "192.0.2.10"))
+	stale := startTestDNSServer(t, answerA(300, "192.0.2.99"))
+	broken := startTestDNSServer(t, func(query dnsmessage.Message, tcp bool) dnsmessage.Message {
+		query.ID++
+		return dnsReply(query, dnsmessage.RCodeSuccess)
+	})
+
+	servers := []string{first, stale, second, broken}
+	results, err := RunDNSLookup("host.test", []string{"A", "AAAA"}, DNSLookupOptions{Servers: servers, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparisons := CompareDNSResults(results)
+	if len(comparisons) != 2 {
+		t.Fatalf("got %d comparisons, want one per record type", len(comparisons))
+	}
+	for _, comparison := range comparisons {
+		if len(comparison.Results) != len(servers) {
+			t.Errorf("%s: %d results, want %d", comparison.Type, len(comparison.Results), len(servers))
+		}
+		// The failed server is not counted as differing
+		if comparison.Consistent || !reflect.DeepEqual(comparison.Differing, []string{stale}) {
+			t.Errorf("%s: Consistent %v Differing %v, want only %s to differ", comparison.Type, comparison.Consistent, comparison.Differing, stale)
+		}
+	}
+
+	agreeing := CompareDNSResults(results[:1])
+	if len(agreeing) != 1 || !agreeing[0].Consistent || len(agreeing[0].Differing) != 0 {
+		t.Errorf("a single answer compared as %+v, want consistent", agreeing)
+	}
+}
+
+func TestDNSQueryName(t *testing.T) {
+	tests := []struct {
+		name, recordType, want string
+	}{
+		{"example.com", "A", "example.com."},
+		{"example.com.", "MX", "example.com."},
+		{"192.0.2.10", "PTR", "10.2.0.192.in-addr.arpa."},
+		{"2001:db8::1", "PTR", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+		{"1.0.0.127.in-addr.arpa", "PTR", "1.0.0.127.in-addr.arpa."},
+	}
+	for _, tt := range tests {
+		got, err := dnsQueryName(tt.name, tt.recordType)
+		if err != nil || got != tt.want {
+			t.Errorf("dnsQueryName(%q, %s) = %q, %v; want %q", tt.name, tt.recordType, got, err, tt.want)
+		}
+	}
+
+	if got := dnsServerAddress("2001:db8::53", DNSTransportTLS); got != "[2001:db8::53]:853" {
+		t.Errorf("dnsServerAddress = %q, want [2001:db8::53]:853", got)
+	}
+	if got := dnsServerAddress("192.0.2.53:5353", DNSTransportUDP); got != "192.0.2.53:5353" {
+		t.Errorf("dnsServerAddress = %q, want the port kept", got)
+	}
+}
+
+// recordData returns the data of records, or nil if there are none.
+func recordData(records []DNSRecord) []string {
+	var data []string
+	for _, r := range records {
+		data = append(data, r.Data)
+	}
+	return data
+}
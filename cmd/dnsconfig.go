@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/mwiater/ghost/utils"
+	"github.com/spf13/cobra"
+)
+
+// DNSConfigCmd shows the resolver configuration and explains how names are resolved.
+var DNSConfigCmd = &cobra.Command{
+	Use:   "dnsconfig",
+	Short: "Shows the resolver configuration and explains how a name is resolved",
+	Long: `Shows the whole name resolution chain of a Linux system in one view: the name servers, search
+domains and options of /etc/resolv.conf, the order of sources on the hosts line of
+/etc/nsswitch.conf, the entries of /etc/hosts, and whether queries go through the systemd-resolved
+stub resolver (and the upstream servers it forwards to).
+
+With --explain <name> the steps the C library would take to resolve that name are listed: the hosts
+file and DNS in nsswitch order, the candidate names produced by the search list and ndots, and the
+servers they are sent to. Nothing is queried; use dnslookup for that.
+
+--root reads the files below another directory, e.g. a copy of a server's /etc or a container's root.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		root, _ := cmd.Flags().GetString("root")
+		explain, _ := cmd.Flags().GetString("explain")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+
+		if !cmd.Flags().Changed("root") {
+			var err error
+			if root, err = defaultDNSConfigRoot(); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		config, err := LoadDNSConfig(root)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if explain != "" {
+			steps := config.Explain(explain)
+			if jsonOutput {
+				utils.PrintJSON(steps)
+				return
+			}
+			PrintDNSExplain(explain, steps)
+			return
+		}
+		if jsonOutput {
+			utils.PrintJSON(config)
+			return
+		}
+		PrintDNSConfig(config)
+	},
+}
+
+// init registers the DNSConfigCmd with the root command when this package is imported.
+func init() {
+	RootCmd.AddCommand(DNSConfigCmd)
+	DNSConfigCmd.Flags().String("root", "/", "Directory the resolver files are read from (etc/resolv.conf, etc/nsswitch.conf, etc/hosts)")
+	DNSConfigCmd.Flags().String("explain", "", "Explain step by step how this name would be resolved")
+	DNSConfigCmd.Flags().Bool("json", false, "Print the results as JSON")
+}
+
+// PrintDNSConfig displays the resolver configuration.
+func PrintDNSConfig(config DNSConfig) {
+	resolv := config.Resolv
+	t := utils.Table("DarkSimple", "Resolver Configuration")
+	t.AppendHeader(table.Row{"Setting", "Value"})
+	t.AppendRow(table.Row{"resolv.conf", resolv.Path})
+	t.AppendRow(table.Row{"Name Servers", strings.Join(resolv.ActiveNameservers(), ", ")})
+	t.AppendRow(table.Row{"Search Domains", valueOrDash(strings.Join(resolv.Search, " "))})
+	t.AppendRow(table.Row{"ndots", resolv.Ndots})
+	t.AppendRow(table.Row{"Timeout", fmt.Sprintf("%ds", resolv.Timeout)})
+	t.AppendRow(table.Row{"Attempts", resolv.Attempts})
+	t.AppendRow(table.Row{"Rotate", resolv.Rotate})
+	t.AppendRow(table.Row{"Options", valueOrDash(strings.Join(resolv.Options, " "))})
+
+	var sources []string
+	for _, source := range config.Hosts {
+		sources = append(sources, source.String())
+	}
+	hosts := strings.Join(sources, " ")
+	if !config.HostsSet {
+		hosts += " (default; no hosts line in nsswitch.conf)"
+	}
+	t.AppendRow(table.Row{"nsswitch hosts", hosts})
+
+	resolved := "not used"
+	switch {
+	case config.Resolved.Active:
+		resolved = "stub resolver (" + config.Resolved.Mode + ")"
+		if len(config.Resolved.Upstreams) > 0 {
+			resolved += ", forwarding to " + strings.Join(config.Resolved.Upstreams, ", ")
+		}
+	case config.Resolved.Mode == "uplink":
+		resolved = "resolv.conf lists resolved's upstream servers; applications bypass the stub"
+	}
+	if config.Resolved.Target != "" {
+		resolved += "; resolv.conf -> " + config.Resolved.Target
+	}
+	t.AppendRow(table.Row{"systemd-resolved", resolved})
+
+	fmt.Println()
+	t.Render()
+
+	h := utils.Table("DarkSimple", "Hosts File")
+	h.AppendHeader(table.Row{"Line", "Address", "Names"})
+	for _, entry := range config.HostFile {
+		h.AppendRow(table.Row{entry.Line, entry.Address, strings.Join(entry.Names, " ")})
+	}
+	if len(config.HostFile) == 0 {
+		h.AppendRow(table.Row{"-", "-", "-"})
+	}
+	fmt.Println()
+	h.Render()
+	fmt.Println()
+
+	for _, warning := range resolv.Warnings {
+		utils.TerminalColor("Warning: "+warning, utils.Warn)
+	}
+}
+
+// PrintDNSExplain displays the steps taken to resolve name.
+func PrintDNSExplain(name string, steps []DNSExplainStep) {
+	t := utils.Table("DarkSimple", "Resolving "+name)
+	t.AppendHeader(table.Row{"Step", "Source", "Action", "Result"})
+	for i, step := range steps {
+		t.AppendRow(table.Row{i + 1, step.Source, step.Detail, step.Result})
+	}
+	t.SetColumnConfigs([]table.ColumnConfig{{Number: 3, WidthMax: 80, WidthMaxEnforcer: text.WrapSoft}})
+	fmt.Println()
+	t.Render()
+	fmt.Println()
+}
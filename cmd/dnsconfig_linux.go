@@ -0,0 +1,10 @@
+//go:build linux
+// +build linux
+
+package cmd
+
+// defaultDNSConfigRoot returns the directory dnsconfig reads when --root is not given: the
+// system's own files.
+func defaultDNSConfigRoot() (string, error) {
+	return "/", nil
+}
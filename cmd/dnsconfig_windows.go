@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+import "fmt"
+
+// defaultDNSConfigRoot fails on Windows, which has no resolver files of its own to read.
+func defaultDNSConfigRoot() (string, error) {
+	return "", fmt.Errorf("dnsconfig reads Linux resolver files; use --root to inspect a copy of them")
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+	"github.com/spf13/cobra"
+)
+
+// DNSLookupCmd queries DNS servers directly and shows their full answers.
+var DNSLookupCmd = &cobra.Command{
+	Use:   "dnslookup <name>",
+	Short: "Queries DNS servers and shows their answers with TTLs",
+	Long: `Sends DNS queries for a name directly to DNS servers, like dig, and shows the answer and
+authority sections with their TTLs, the response code and the response time. Supported record
+types are A, AAAA, CNAME, MX, TXT, SRV, NS, SOA and PTR; a PTR query for an IP address asks for
+its reverse name. Queries go to the system's configured name servers unless --server is given,
+over UDP (retried over TCP when truncated), TCP, or DNS over TLS.
+
+With --compare every server is queried at the same time and their answers are compared, which
+shows split-horizon or stale records: servers whose answers differ from the majority are flagged.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		opts := DNSLookupOptions{}
+		opts.Servers, _ = cmd.Flags().GetStringSlice("server")
+		opts.Transport, _ = cmd.Flags().GetString("transport")
+		opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+		recordTypes, _ := cmd.Flags().GetStringSlice("type")
+		compare, _ := cmd.Flags().GetBool("compare")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+
+		// An address on its own is looked up in reverse
+		if !cmd.Flags().Changed("type") && net.ParseIP(name) != nil {
+			recordTypes = []string{"PTR"}
+		}
+
+		results, err := RunDNSLookup(name, recordTypes, opts)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if compare {
+			comparisons := CompareDNSResults(results)
+			if len(comparisons) > 0 && len(comparisons[0].Results) < 2 {
+				fmt.Println("Error: --compare needs at least two servers")
+				os.Exit(1)
+			}
+			if jsonOutput {
+				utils.PrintJSON(comparisons)
+				return
+			}
+			PrintDNSComparisons(comparisons)
+			return
+		}
+
+		if jsonOutput {
+			utils.PrintJSON(results)
+			return
+		}
+		PrintDNSLookupResults(results)
+	},
+}
+
+// DNSComparison is the answers several servers gave to the same query.
+type DNSComparison struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Consistent bool              `json:"consistent"` // Every server that answered gave the same records
+	Differing  []string          `json:"differing"`  // Servers whose answer differs from the majority
+	Results    []DNSLookupResult `json:"results"`
+}
+
+// init registers the DNSLookupCmd with the root command when this package is imported.
+func init() {
+	RootCmd.AddCommand(DNSLookupCmd)
+	DNSLookupCmd.Flags().StringSliceP("type", "t", []string{"A"}, "Record types to query: A, AAAA, CNAME, MX, TXT, SRV, NS, SOA or PTR (default: PTR for an IP address)")
+	DNSLookupCmd.Flags().StringSliceP("server", "s", nil, "DNS servers to query, as host, host:port or [IPv6]:port (default: the system's name servers)")
+	DNSLookupCmd.Flags().String("transport", DNSTransportUDP, "Transport: udp, tcp or tls (DNS over TLS, port 853)")
+	DNSLookupCmd.Flags().Duration("timeout", 3*time.Second, "Time to wait for each response")
+	DNSLookupCmd.Flags().Bool("compare", false, "Query every server concurrently and highlight differing answers")
+	DNSLookupCmd.Flags().Bool("json", false, "Print the results as JSON")
+}
+
+// CompareDNSResults groups results by query and compares the answers of the servers. The most
+// common answer is taken as the reference; servers that failed are not counted as differing.
+func CompareDNSResults(results []DNSLookupResult) []DNSComparison {
+	var comparisons []DNSComparison
+	index := make(map[string]int)
+	for _, result := range results {
+		key := result.Name + " " + result.Type
+		i, ok := index[key]
+		if !ok {
+			i = len(comparisons)
+			index[key] = i
+			comparisons = append(comparisons, DNSComparison{Name: result.Name, Type: result.Type, Differing: []string{}})
+		}
+		comparisons[i].Results = append(comparisons[i].Results, result)
+	}
+
+	for i := range comparisons {
+		comparison := &comparisons[i]
+		counts := make(map[string]int)
+		majority := ""
+		for _, result := range comparison.Results {
+			if result.Error != "" {
+				continue
+			}
+			key := dnsAnswerKey(result)
+			counts[key]++
+			if majority == "" || counts[key] > counts[majority] {
+				majority = key
+			}
+		}
+		for _, result := range comparison.Results {
+			if result.Error == "" && dnsAnswerKey(result) != majority {
+				comparison.Differing = append(comparison.Differing, result.Server)
+			}
+		}
+		comparison.Consistent = len(comparison.Differing) == 0
+	}
+	return comparisons
+}
+
+// PrintDNSLookupResults displays the answer and authority sections of each response.
+func PrintDNSLookupResults(results []DNSLookupResult) {
+	for _, result := range results {
+		fmt.Println()
+		if result.Error != "" {
+			utils.TerminalColor(fmt.Sprintf("%s %s @%s (%s): %s", result.Name, result.Type, result.Server, result.Transport, result.Error), utils.Warn)
+			continue
+		}
+
+		t := utils.Table("DarkSimple", result.Name+" "+result.Type)
+		t.AppendHeader(table.Row{"Section", "Name", "Type", "TTL", "Data"})
+		for _, record := range result.Answers {
+			t.AppendRow(table.Row{"Answer", record.Name, record.Type, record.TTL, record.Data})
+		}
+		for _, record := range result.Authority {
+			t.AppendRow(table.Row{"Authority", record.Name, record.Type, record.TTL, record.Data})
+		}
+		if len(result.Answers)+len(result.Authority) == 0 {
+			t.AppendRow(table.Row{"-", "-", "-", "-", "-"})
+		}
+		t.Render()
+
+		flags := []string{result.RCode}
+		if result.Authoritative {
+			flags = append(flags, "authoritative")
+		}
+		if result.Truncated {
+			flags = append(flags, "truncated, retried over TCP")
+		}
+		fmt.Printf("Server %s (%s): %s; %d answers in %s\n", result.Server, result.Transport, strings.Join(flags, ", "), len(result.Answers), formatPingRTT(result.ResponseTime))
+	}
+	fmt.Println()
+}
+
+// PrintDNSComparisons displays the answers of every server side by side for each query and
+// warns about servers that disagree with the majority.
+func PrintDNSComparisons(comparisons []DNSComparison) {
+	for _, comparison := range comparisons {
+		differing := make(map[string]bool)
+		for _, server := range comparison.Differing {
+			differing[server] = true
+		}
+
+		t := utils.Table("DarkSimple", fmt.Sprintf("%s %s", comparison.Name, comparison.Type))
+		t.AppendHeader(table.Row{"Server", "Status", "Answers", "Min TTL", "Time", "Match"})
+		for _, result := range comparison.Results {
+			if result.Error != "" {
+				t.AppendRow(table.Row{result.Server, "error", result.Error, "-", "-", "-"})
+				continue
+			}
+			var answers []string
+			minTTL := "-"
+			if len(result.Answers) > 0 {
+				lowest := result.Answers[0].TTL
+				for _, record := range result.Answers {
+					answers = append(answers, record.Type+" "+record.Data)
+					lowest = min(lowest, record.TTL)
+				}
+				minTTL = fmt.Sprint(lowest)
+			}
+			match := "yes"
+			if differing[result.Server] {
+				match = "DIFFERS"
+			}
+			t.AppendRow(table.Row{result.Server, result.RCode, valueOrDash(strings.Join(answers, "\n")), minTTL, formatPingRTT(result.ResponseTime), match})
+		}
+
+		fmt.Println()
+		t.Render()
+		if !comparison.Consistent {
+			utils.TerminalColor(fmt.Sprintf("Servers disagree: %s answered differently from the majority.", strings.Join(comparison.Differing, ", ")), utils.Warn)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,14 @@
+//go:build linux
+// +build linux
+
+package cmd
+
+// systemNameservers returns the name servers the C library uses, from /etc/resolv.conf. Like the
+// library it uses the local host when none are configured.
+func systemNameservers() ([]string, error) {
+	conf, err := ParseResolvConf("/etc/resolv.conf")
+	if err != nil {
+		return nil, err
+	}
+	return conf.ActiveNameservers(), nil
+}
@@ -0,0 +1,49 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// systemNameservers returns the DNS servers configured on the network adapters that are up.
+func systemNameservers() ([]string, error) {
+	size := uint32(15000)
+	var buf []byte
+	for {
+		buf = make([]byte, size)
+		err := windows.GetAdaptersAddresses(syscall.AF_UNSPEC, windows.GAA_FLAG_SKIP_ANYCAST|windows.GAA_FLAG_SKIP_MULTICAST,
+			0, (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])), &size)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_BUFFER_OVERFLOW {
+			return nil, os.NewSyscallError("GetAdaptersAddresses", err)
+		}
+	}
+
+	var servers []string
+	for adapter := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])); adapter != nil; adapter = adapter.Next {
+		if adapter.OperStatus != windows.IfOperStatusUp {
+			continue
+		}
+		for dns := adapter.FirstDnsServerAddress; dns != nil; dns = dns.Next {
+			ip := dns.Address.IP()
+			// Skip the deprecated site-local defaults (fec0:0:0:ffff::1-3) Windows lists when no IPv6 DNS is configured
+			if ip == nil || (len(ip) == 16 && ip[0] == 0xfe && ip[1] == 0xc0) {
+				continue
+			}
+			servers = appendUnique(servers, ip.String())
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no DNS servers are configured")
+	}
+	return servers, nil
+}
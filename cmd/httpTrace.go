@@ -0,0 +1,398 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HTTPTiming breaks the time of an HTTP request down into its phases, like curl's -w timings.
+// Phases that did not happen, such as TLS for plain HTTP or DNS for an address, are zero.
+type HTTPTiming struct {
+	DNS       time.Duration `json:"dns"`       // Name lookup
+	Connect   time.Duration `json:"connect"`   // TCP connection setup
+	TLS       time.Duration `json:"tls"`       // TLS handshake
+	Server    time.Duration `json:"server"`    // From the request being sent to the first response byte
+	Transfer  time.Duration `json:"transfer"`  // From the first response byte to the end of the body
+	FirstByte time.Duration `json:"firstByte"` // From the start of the request to the first response byte
+	Total     time.Duration `json:"total"`
+}
+
+// add accumulates other into t, to total the timings of a redirect chain.
+func (t *HTTPTiming) add(other HTTPTiming) {
+	t.DNS += other.DNS
+	t.Connect += other.Connect
+	t.TLS += other.TLS
+	t.Server += other.Server
+	t.Transfer += other.Transfer
+	t.FirstByte += other.FirstByte
+	t.Total += other.Total
+}
+
+// HTTPHop is one request of a redirect chain.
+type HTTPHop struct {
+	URL        string     `json:"url"`
+	StatusCode int        `json:"statusCode"`
+	Location   string     `json:"location"` // Redirect target, for 3xx responses
+	RemoteAddr string     `json:"remoteAddr"`
+	Timing     HTTPTiming `json:"timing"`
+}
+
+// HTTPProbeResult is the outcome of one request, including any redirects it followed.
+type HTTPProbeResult struct {
+	URL        string      `json:"url"`
+	FinalURL   string      `json:"finalURL"`
+	Method     string      `json:"method"`
+	StatusCode int         `json:"statusCode"`
+	Status     string      `json:"status"`
+	Proto      string      `json:"proto"`
+	RemoteAddr string      `json:"remoteAddr"`
+	TLSVersion string      `json:"tlsVersion"`
+	Headers    http.Header `json:"headers"`
+	Size       int64       `json:"size"` // Bytes of response body received
+	Hops       []HTTPHop   `json:"hops"`
+	Timing     HTTPTiming  `json:"timing"` // Summed over every hop
+	Error      string      `json:"error,omitempty"`
+}
+
+// HTTPProbeOptions controls the requests httpprobe sends.
+type HTTPProbeOptions struct {
+	Method       string
+	Headers      []string // "Name: value" lines
+	Body         []byte
+	Resolve      []string // "host:ip" or "host:port:ip" overrides, like curl --resolve
+	Timeout      time.Duration
+	MaxRedirects int // Redirects to follow; 0 does not follow any
+	Insecure     bool
+	KeepAlive    bool // Reuse connections between repeated requests instead of measuring fresh ones
+}
+
+// withDefaults fills in zero-valued options with the command's defaults.
+func (opts HTTPProbeOptions) withDefaults() HTTPProbeOptions {
+	if opts.Method == "" {
+		opts.Method = http.MethodGet
+		if len(opts.Body) > 0 {
+			opts.Method = http.MethodPost
+		}
+	}
+	opts.Method = strings.ToUpper(opts.Method)
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return opts
+}
+
+// httpProber sends timed requests with one configuration.
+type httpProber struct {
+	opts      HTTPProbeOptions
+	headers   http.Header
+	host      string // Host header override
+	overrides map[string]string
+	transport *http.Transport
+}
+
+// newHTTPProber validates the options and prepares the transport requests are sent with.
+func newHTTPProber(opts HTTPProbeOptions) (*httpProber, error) {
+	p := &httpProber{opts: opts.withDefaults(), headers: http.Header{}, overrides: make(map[string]string)}
+	for _, header := range opts.Headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header %q (use \"Name: value\")", header)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if strings.EqualFold(name, "Host") {
+			p.host = value
+			continue
+		}
+		p.headers.Add(name, value)
+	}
+	for _, entry := range opts.Resolve {
+		key, ip, err := parseResolveOverride(entry)
+		if err != nil {
+			return nil, err
+		}
+		p.overrides[key] = ip
+	}
+
+	dialer := &net.Dialer{Timeout: p.opts.Timeout}
+	p.transport = &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		DisableKeepAlives: !p.opts.KeepAlive,
+		ForceAttemptHTTP2: true,
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: p.opts.Insecure},
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			if ip, ok := p.overrides[strings.ToLower(net.JoinHostPort(host, port))]; ok {
+				address = net.JoinHostPort(ip, port)
+			} else if ip, ok := p.overrides[strings.ToLower(host)]; ok {
+				address = net.JoinHostPort(ip, port)
+			}
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+	return p, nil
+}
+
+// parseResolveOverride parses a --resolve entry, host:ip or host:port:ip (the address may be an
+// IPv6 address, optionally in brackets), into the key the dialer looks it up by and the address.
+func parseResolveOverride(entry string) (string, string, error) {
+	host, rest, ok := strings.Cut(entry, ":")
+	if !ok || host == "" {
+		return "", "", fmt.Errorf("invalid --resolve %q (use host:ip or host:port:ip)", entry)
+	}
+	if ip := net.ParseIP(strings.Trim(rest, "[]")); ip != nil {
+		return strings.ToLower(host), ip.String(), nil
+	}
+	port, address, ok := strings.Cut(rest, ":")
+	if ip := net.ParseIP(strings.Trim(address, "[]")); ok && ip != nil && port != "" {
+		return strings.ToLower(net.JoinHostPort(host, port)), ip.String(), nil
+	}
+	return "", "", fmt.Errorf("invalid --resolve %q (use host:ip or host:port:ip)", entry)
+}
+
+// RunHTTPProbe sends count requests to rawURL one after the other, pausing interval between
+// them. Failed requests are returned with their Error set.
+func RunHTTPProbe(rawURL string, count int, interval time.Duration, opts HTTPProbeOptions) ([]HTTPProbeResult, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q (use http or https)", target.Scheme)
+	}
+	p, err := newHTTPProber(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer p.transport.CloseIdleConnections()
+
+	count = max(count, 1)
+	results := make([]HTTPProbeResult, 0, count)
+	for i := 0; i < count; i++ {
+		if i > 0 && interval > 0 {
+			time.Sleep(interval)
+		}
+		results = append(results, p.probe(target))
+	}
+	return results, nil
+}
+
+// probe sends one request and follows its redirects, timing every hop.
+func (p *httpProber) probe(target *url.URL) HTTPProbeResult {
+	result := HTTPProbeResult{URL: target.String(), Method: p.opts.Method, Hops: []HTTPHop{}}
+	method, body := p.opts.Method, p.opts.Body
+	header, host := p.headers.Clone(), p.host
+	current := target
+
+	for {
+		hop, response, err := p.send(method, current, body, header, host)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Hops = append(result.Hops, hop)
+		result.Timing.add(hop.Timing)
+
+		if hop.Location == "" || len(result.Hops) > p.opts.MaxRedirects {
+			result.FinalURL = current.String()
+			result.StatusCode = response.StatusCode
+			result.Status = response.Status
+			result.Proto = response.Proto
+			result.RemoteAddr = hop.RemoteAddr
+			result.Headers = response.Header
+			result.Size = response.size
+			if response.TLS != nil {
+				result.TLSVersion = tls.VersionName(response.TLS.Version)
+			}
+			if hop.Location != "" && p.opts.MaxRedirects > 0 {
+				result.Error = fmt.Sprintf("stopped after %d redirects", p.opts.MaxRedirects)
+			}
+			return result
+		}
+
+		next, err := current.Parse(hop.Location)
+		if err != nil {
+			result.Error = fmt.Sprintf("invalid redirect location %q: %v", hop.Location, err)
+			return result
+		}
+		// Like browsers, 301-303 redirects of anything but HEAD continue as GET without a body
+		if response.StatusCode != http.StatusTemporaryRedirect && response.StatusCode != http.StatusPermanentRedirect && method != http.MethodHead {
+			method, body = http.MethodGet, nil
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+		}
+		// Credentials were given for the host they were sent to, so another host does not get them
+		if !strings.EqualFold(next.Host, current.Host) {
+			header.Del("Authorization")
+			header.Del("Cookie")
+			header.Del("Proxy-Authorization")
+		}
+		// The Host override names the server of the requested URL, not the ones it redirects to
+		host = ""
+		if strings.EqualFold(next.Host, target.Host) {
+			host = p.host
+		}
+		current = next
+	}
+}
+
+// timedResponse is a response whose body has been read and discarded.
+type timedResponse struct {
+	*http.Response
+	size int64
+}
+
+// send performs a single request with header, and host as the Host header unless it is empty,
+// without following redirects, and times its phases.
+func (p *httpProber) send(method string, target *url.URL, body []byte, header http.Header, host string) (HTTPHop, *timedResponse, error) {
+	hop := HTTPHop{URL: target.String()}
+	var dnsStart, connectStart, tlsStart, wrote, firstByte time.Time
+	var timing HTTPTiming
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			if !dnsStart.IsZero() {
+				timing.DNS = time.Since(dnsStart)
+			}
+		},
+		ConnectStart: func(string, string) {
+			if connectStart.IsZero() {
+				connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				timing.Connect = time.Since(connectStart)
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			if !tlsStart.IsZero() {
+				timing.TLS = time.Since(tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			hop.RemoteAddr = info.Conn.RemoteAddr().String()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { wrote = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.Timeout)
+	defer cancel()
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), method, target.String(), reader)
+	if err != nil {
+		return hop, nil, err
+	}
+	request.Header = header.Clone()
+	if host != "" {
+		request.Host = host
+	}
+
+	start := time.Now()
+	response, err := p.transport.RoundTrip(request)
+	if err != nil {
+		return hop, nil, err
+	}
+	defer response.Body.Close()
+	size, err := io.Copy(io.Discard, response.Body)
+	end := time.Now()
+	if err != nil {
+		return hop, nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if !firstByte.IsZero() {
+		timing.FirstByte = firstByte.Sub(start)
+		timing.Transfer = end.Sub(firstByte)
+		if !wrote.IsZero() {
+			timing.Server = firstByte.Sub(wrote)
+		}
+	}
+	timing.Total = end.Sub(start)
+	hop.Timing = timing
+	hop.StatusCode = response.StatusCode
+	if response.StatusCode >= 300 && response.StatusCode < 400 {
+		hop.Location = response.Header.Get("Location")
+	}
+	return hop, &timedResponse{response, size}, nil
+}
+
+// httpPercentile returns the p-th percentile (0-100) of sorted durations, by the nearest-rank method.
+func httpPercentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// HTTPPhaseStats summarizes one timing phase over repeated requests.
+type HTTPPhaseStats struct {
+	Phase string        `json:"phase"`
+	Min   time.Duration `json:"min"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+// SummarizeHTTPTimings computes percentiles of every timing phase over the successful results.
+func SummarizeHTTPTimings(results []HTTPProbeResult) []HTTPPhaseStats {
+	phases := []struct {
+		name  string
+		value func(HTTPTiming) time.Duration
+	}{
+		{"DNS lookup", func(t HTTPTiming) time.Duration { return t.DNS }},
+		{"TCP connect", func(t HTTPTiming) time.Duration { return t.Connect }},
+		{"TLS handshake", func(t HTTPTiming) time.Duration { return t.TLS }},
+		{"Server processing", func(t HTTPTiming) time.Duration { return t.Server }},
+		{"Content transfer", func(t HTTPTiming) time.Duration { return t.Transfer }},
+		{"Time to first byte", func(t HTTPTiming) time.Duration { return t.FirstByte }},
+		{"Total", func(t HTTPTiming) time.Duration { return t.Total }},
+	}
+
+	var stats []HTTPPhaseStats
+	for _, phase := range phases {
+		var values []time.Duration
+		for _, result := range results {
+			if result.Error == "" {
+				values = append(values, phase.value(result.Timing))
+			}
+		}
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		if len(values) == 0 {
+			continue
+		}
+		stats = append(stats, HTTPPhaseStats{
+			Phase: phase.name,
+			Min:   values[0],
+			P50:   httpPercentile(values, 50),
+			P90:   httpPercentile(values, 90),
+			P95:   httpPercentile(values, 95),
+			P99:   httpPercentile(values, 99),
+			Max:   values[len(values)-1],
+		})
+	}
+	return stats
+}
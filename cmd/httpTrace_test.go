@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseResolveOverride(t *testing.T) {
+	tests := []struct {
+		entry, key, ip string
+		err            bool
+	}{
+		{entry: "example.com:192.0.2.10", key: "example.com", ip: "192.0.2.10"},
+		{entry: "Example.COM:443:192.0.2.10", key: "example.com:443", ip: "192.0.2.10"},
+		{entry: "example.com:2001:db8::10", key: "example.com", ip: "2001:db8::10"},
+		{entry: "example.com:[2001:db8::10]", key: "example.com", ip: "2001:db8::10"},
+		{entry: "example.com:8443:[2001:db8::10]", key: "example.com:8443", ip: "2001:db8::10"},
+		{entry: "example.com", err: true},
+		{entry: ":192.0.2.10", err: true},
+		{entry: "example.com:443:not-an-ip", err: true},
+		{entry: "example.com::192.0.2.10", err: true},
+	}
+	for _, tt := range tests {
+		key, ip, err := parseResolveOverride(tt.entry)
+		if (err != nil) != tt.err || key != tt.key || ip != tt.ip {
+			t.Errorf("parseResolveOverride(%q) = %q, %q, %v; want %q, %q, error %v", tt.entry, key, ip, err, tt.key, tt.ip, tt.err)
+		}
+	}
+}
+
+func TestRunHTTPProbeRedirects(t *testing.T) {
+	// /hop/n redirects n more times before landing on /done; /keep/n does the same with 307s
+	var mu sync.Mutex
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		kind, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		n, _ := strconv.Atoi(rest)
+		switch {
+		case kind == "done":
+			fmt.Fprint(w, "landed")
+		case n <= 1:
+			http.Redirect(w, r, "/done", redirectStatus(kind))
+		default:
+			// A relative location, resolved against the current URL
+			http.Redirect(w, r, strconv.Itoa(n-1), redirectStatus(kind))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		method       string
+		maxRedirects int
+		hops         int
+		status       int
+		final        string
+		err          string
+		methods      []string
+	}{
+		{name: "followed", path: "/hop/3", maxRedirects: 10, hops: 4, status: 200, final: "/done", methods: []string{"GET", "GET", "GET", "GET"}},
+		{name: "limit reached", path: "/hop/3", maxRedirects: 2, hops: 3, status: 302, final: "/hop/1", err: "stopped after 2 redirects"},
+		{name: "not followed", path: "/hop/3", hops: 1, status: 302, final: "/hop/3"},
+		{name: "302 turns POST into GET", path: "/hop/1", method: "POST", maxRedirects: 10, hops: 2, status: 200, final: "/done", methods: []string{"POST", "GET"}},
+		{name: "307 keeps POST", path: "/keep/1", method: "POST", maxRedirects: 10, hops: 2, status: 200, final: "/done", methods: []string{"POST", "POST"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			methods = nil
+			mu.Unlock()
+			opts := HTTPProbeOptions{Method: tt.method, MaxRedirects: tt.maxRedirects}
+			if tt.method == http.MethodPost {
+				opts.Body = []byte("payload")
+			}
+			results, err := RunHTTPProbe(server.URL+tt.path, 1, 0, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := results[0]
+			if len(result.Hops) != tt.hops || result.StatusCode != tt.status || result.FinalURL != server.URL+tt.final || result.Error != tt.err {
+				t.Errorf("hops %d status %d final %s error %q, want %d %d %s %q", len(result.Hops), result.StatusCode, result.FinalURL, result.Error,
+					tt.hops, tt.status, server.URL+tt.final, tt.err)
+			}
+			mu.Lock()
+			got := strings.Join(methods, " ")
+			mu.Unlock()
+			if tt.methods != nil && got != strings.Join(tt.methods, " ") {
+				t.Errorf("methods = %s, want %v", got, tt.methods)
+			}
+			if result.Hops[0].Location == "" && tt.hops > 1 {
+				t.Error("the first hop has no redirect location")
+			}
+			var total time.Duration
+			for _, hop := range result.Hops {
+				total += hop.Timing.Total
+			}
+			if result.Timing.Total != total {
+				t.Errorf("total %v is not the sum of the hops' %v", result.Timing.Total, total)
+			}
+		})
+	}
+}
+
+func TestRunHTTPProbeRedirectHeaders(t *testing.T) {
+	// origin /start -> origin /next -> other /hop -> origin /done, recording what each request carried
+	var mu sync.Mutex
+	seen := map[string]string{}
+	record := func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[r.URL.Path] = fmt.Sprintf("%s host=%s auth=%q cookie=%q type=%q length=%d trace=%q", r.Method, r.Host,
+			r.Header.Get("Authorization"), r.Header.Get("Cookie"), r.Header.Get("Content-Type"), r.ContentLength, r.Header.Get("X-Trace"))
+	}
+	var origin, other *httptest.Server
+	origin = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/next", http.StatusFound)
+		case "/next":
+			http.Redirect(w, r, other.URL+"/hop", http.StatusSeeOther)
+		default:
+			fmt.Fprint(w, "done")
+		}
+	}))
+	defer origin.Close()
+	other = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		http.Redirect(w, r, origin.URL+"/done", http.StatusTemporaryRedirect)
+	}))
+	defer other.Close()
+
+	opts := HTTPProbeOptions{
+		Method:       http.MethodPost,
+		Body:         []byte(`{"a":1}`),
+		MaxRedirects: 10,
+		Headers: []string{
+			"Host: site.example", "Authorization: Bearer secret", "Cookie: session=1",
+			"Content-Type: application/json", "X-Trace: abc",
+		},
+	}
+	results, err := RunHTTPProbe(origin.URL+"/start", 1, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := results[0]; result.Error != "" || len(result.Hops) != 4 || result.StatusCode != http.StatusOK {
+		t.Fatalf("result = %+v", result)
+	}
+
+	otherHost := strings.TrimPrefix(other.URL, "http://")
+	want := map[string]string{
+		"/start": `POST host=site.example auth="Bearer secret" cookie="session=1" type="application/json" length=7 trace="abc"`,
+		// The 302 turned the request into a GET, which has no body to describe
+		"/next": `GET host=site.example auth="Bearer secret" cookie="session=1" type="" length=0 trace="abc"`,
+		// Another host gets neither the credentials nor the Host override
+		"/hop": `GET host=` + otherHost + ` auth="" cookie="" type="" length=0 trace="abc"`,
+		// Back on the requested host the override applies again, but the credentials stay dropped
+		"/done": `GET host=site.example auth="" cookie="" type="" length=0 trace="abc"`,
+	}
+	for path, request := range want {
+		if seen[path] != request {
+			t.Errorf("%s got\n%s\nwant\n%s", path, seen[path], request)
+		}
+	}
+}
+
+// redirectStatus returns the status the redirect test server answers with for a kind of path.
+func redirectStatus(kind string) int {
+	if kind == "keep" {
+		return http.StatusTemporaryRedirect
+	}
+	return http.StatusFound
+}
+
+func TestRunHTTPProbeTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, strings.Repeat("x", 1000))
+	}))
+	// The request without --insecure fails the handshake, which the server would log
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	// A name only known through --resolve, so no DNS lookup happens
+	target := "https://probe.test:" + serverURL.Port() + "/"
+	results, err := RunHTTPProbe(target, 2, 0, HTTPProbeOptions{Insecure: true, Resolve: []string{"probe.test:127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Error != "" {
+			t.Fatal(result.Error)
+		}
+		timing := result.Timing
+		if result.StatusCode != 200 || result.Size != 1000 || result.TLSVersion == "" || result.RemoteAddr != serverURL.Host {
+			t.Errorf("status %d size %d TLS %q remote %s", result.StatusCode, result.Size, result.TLSVersion, result.RemoteAddr)
+		}
+		// Every request uses a new connection, so each one is set up and handshaken
+		if timing.DNS != 0 || timing.Connect <= 0 || timing.TLS <= 0 || timing.FirstByte <= 0 || timing.Total < timing.FirstByte {
+			t.Errorf("timing = %+v", timing)
+		}
+	}
+
+	results, err = RunHTTPProbe(server.URL, 1, 0, HTTPProbeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(results[0].Error, "certificate") {
+		t.Errorf("the test server's certificate was accepted without --insecure: %q", results[0].Error)
+	}
+}
+
+func TestSummarizeHTTPTimings(t *testing.T) {
+	var results []HTTPProbeResult
+	// Out of order, so the summary has to sort
+	for i := 100; i >= 1; i-- {
+		d := time.Duration(i) * time.Millisecond
+		results = append(results, HTTPProbeResult{Timing: HTTPTiming{Connect: d / 10, FirstByte: d, Total: d}})
+	}
+	results = append(results, HTTPProbeResult{Error: "connection refused", Timing: HTTPTiming{Total: time.Hour}})
+
+	stats := SummarizeHTTPTimings(results)
+	if len(stats) != 7 {
+		t.Fatalf("got %d phases, want 7", len(stats))
+	}
+	total := stats[6]
+	want := HTTPPhaseStats{Phase: "Total", Min: time.Millisecond, P50: 50 * time.Millisecond, P90: 90 * time.Millisecond,
+		P95: 95 * time.Millisecond, P99: 99 * time.Millisecond, Max: 100 * time.Millisecond}
+	if total != want {
+		t.Errorf("total = %+v, want %+v", total, want)
+	}
+	if stats[1].Phase != "TCP connect" || stats[1].P50 != 5*time.Millisecond || stats[0].Max != 0 {
+		t.Errorf("connect %+v dns %+v", stats[1], stats[0])
+	}
+
+	if got := httpPercentile([]time.Duration{3, 7}, 50); got != 3 {
+		t.Errorf("median of two = %v, want the lower by nearest rank", got)
+	}
+	if got := SummarizeHTTPTimings(results[100:]); len(got) != 0 {
+		t.Errorf("only failed requests summarized as %+v", got)
+	}
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+	"github.com/spf13/cobra"
+)
+
+// HTTPProbeCmd sends timed HTTP requests and breaks their latency down by phase.
+var HTTPProbeCmd = &cobra.Command{
+	Use:   "httpprobe <url>",
+	Short: "Times HTTP requests phase by phase (DNS, connect, TLS, first byte, transfer)",
+	Long: `Sends an HTTP request and reports how long each phase took: DNS lookup, TCP connect, TLS
+handshake, server processing (time to first byte after the request was sent) and content
+transfer, together with the status, the redirect chain, the response headers and the body size.
+
+With --count the request is repeated, each time on a new connection, and percentiles of every
+phase are reported. --resolve sends requests for a host to a given address, like curl --resolve,
+e.g. to test one backend behind a load balancer.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := HTTPProbeOptions{}
+		opts.Method, _ = cmd.Flags().GetString("method")
+		opts.Headers, _ = cmd.Flags().GetStringArray("header")
+		opts.Resolve, _ = cmd.Flags().GetStringArray("resolve")
+		opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+		opts.MaxRedirects, _ = cmd.Flags().GetInt("max-redirects")
+		opts.Insecure, _ = cmd.Flags().GetBool("insecure")
+		opts.KeepAlive, _ = cmd.Flags().GetBool("keepalive")
+		noFollow, _ := cmd.Flags().GetBool("no-follow")
+		data, _ := cmd.Flags().GetString("data")
+		count, _ := cmd.Flags().GetInt("count")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+
+		if noFollow {
+			opts.MaxRedirects = 0
+		}
+		body, err := readHTTPBody(data)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		opts.Body = body
+
+		results, err := RunHTTPProbe(args[0], count, interval, opts)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if count > 1 {
+			summary := HTTPProbeSummary{Results: results, Phases: SummarizeHTTPTimings(results)}
+			if jsonOutput {
+				utils.PrintJSON(summary)
+			} else {
+				PrintHTTPProbeSummary(summary)
+			}
+			// Phases are only summarized over successful requests
+			if len(summary.Phases) == 0 {
+				os.Exit(1)
+			}
+			return
+		}
+
+		if jsonOutput {
+			utils.PrintJSON(results[0])
+		} else {
+			PrintHTTPProbeResult(results[0])
+		}
+		if results[0].Error != "" {
+			os.Exit(1)
+		}
+	},
+}
+
+// HTTPProbeSummary is the outcome of repeated requests.
+type HTTPProbeSummary struct {
+	Results []HTTPProbeResult `json:"results"`
+	Phases  []HTTPPhaseStats  `json:"phases"`
+}
+
+// init registers the HTTPProbeCmd with the root command when this package is imported.
+func init() {
+	RootCmd.AddCommand(HTTPProbeCmd)
+	HTTPProbeCmd.Flags().StringP("method", "X", "", "Request method (default: GET, or POST with --data)")
+	HTTPProbeCmd.Flags().StringArrayP("header", "H", nil, "Request header as \"Name: value\" (repeatable)")
+	HTTPProbeCmd.Flags().StringP("data", "d", "", "Request body; @file reads it from a file and @- from standard input")
+	HTTPProbeCmd.Flags().StringArray("resolve", nil, "Connect to an address instead of resolving a host, as host:ip or host:port:ip (repeatable)")
+	HTTPProbeCmd.Flags().IntP("count", "c", 1, "Number of requests to send; more than one reports percentiles")
+	HTTPProbeCmd.Flags().Duration("interval", 0, "Pause between repeated requests")
+	HTTPProbeCmd.Flags().Duration("timeout", 10*time.Second, "Limit for each request, including reading the body")
+	HTTPProbeCmd.Flags().Int("max-redirects", 10, "Maximum number of redirects to follow")
+	HTTPProbeCmd.Flags().Bool("no-follow", false, "Do not follow redirects")
+	HTTPProbeCmd.Flags().BoolP("insecure", "k", false, "Do not verify TLS certificates")
+	HTTPProbeCmd.Flags().Bool("keepalive", false, "Reuse connections between repeated requests")
+	HTTPProbeCmd.Flags().Bool("json", false, "Print the results as JSON")
+}
+
+// readHTTPBody returns the request body given with --data, reading it from a file for @file
+// or from standard input for @-.
+func readHTTPBody(data string) ([]byte, error) {
+	switch {
+	case data == "":
+		return nil, nil
+	case data == "@-":
+		return io.ReadAll(os.Stdin)
+	case strings.HasPrefix(data, "@"):
+		return os.ReadFile(data[1:])
+	}
+	return []byte(data), nil
+}
+
+// PrintHTTPProbeResult displays the redirect chain, response and timing of a single request.
+func PrintHTTPProbeResult(result HTTPProbeResult) {
+	if len(result.Hops) > 1 {
+		r := utils.Table("DarkSimple", "Redirect Chain")
+		r.AppendHeader(table.Row{"#", "Status", "URL", "Location", "Time"})
+		for i, hop := range result.Hops {
+			r.AppendRow(table.Row{i + 1, hop.StatusCode, hop.URL, valueOrDash(hop.Location), formatPingRTT(hop.Timing.Total)})
+		}
+		fmt.Println()
+		r.Render()
+	}
+
+	if result.Error != "" {
+		fmt.Println()
+		utils.TerminalColor(fmt.Sprintf("%s %s: %s", result.Method, result.URL, result.Error), utils.Warn)
+		if result.StatusCode == 0 {
+			fmt.Println()
+			return
+		}
+	}
+
+	t := utils.Table("DarkSimple", result.Method+" "+result.FinalURL)
+	t.AppendHeader(table.Row{"Field", "Value"})
+	t.AppendRow(table.Row{"Status", result.Status})
+	t.AppendRow(table.Row{"Protocol", result.Proto})
+	t.AppendRow(table.Row{"Remote Address", valueOrDash(result.RemoteAddr)})
+	t.AppendRow(table.Row{"TLS", valueOrDash(result.TLSVersion)})
+	t.AppendRow(table.Row{"Body Size", fmt.Sprintf("%d bytes", result.Size)})
+	fmt.Println()
+	t.Render()
+
+	h := utils.Table("DarkSimple", "Response Headers")
+	h.AppendHeader(table.Row{"Header", "Value"})
+	names := make([]string, 0, len(result.Headers))
+	for name := range result.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range result.Headers[name] {
+			h.AppendRow(table.Row{name, value})
+		}
+	}
+	fmt.Println()
+	h.Render()
+
+	timing := result.Timing
+	p := utils.Table("DarkSimple", "Timing")
+	p.AppendHeader(table.Row{"Phase", "Time"})
+	p.AppendRow(table.Row{"DNS lookup", formatHTTPPhase(timing.DNS)})
+	p.AppendRow(table.Row{"TCP connect", formatHTTPPhase(timing.Connect)})
+	p.AppendRow(table.Row{"TLS handshake", formatHTTPPhase(timing.TLS)})
+	p.AppendRow(table.Row{"Server processing", formatHTTPPhase(timing.Server)})
+	p.AppendRow(table.Row{"Content transfer", formatHTTPPhase(timing.Transfer)})
+	p.AppendSeparator()
+	p.AppendRow(table.Row{"Time to first byte", formatHTTPPhase(timing.FirstByte)})
+	p.AppendRow(table.Row{"Total", formatHTTPPhase(timing.Total)})
+	fmt.Println()
+	p.Render()
+	fmt.Println()
+}
+
+// PrintHTTPProbeSummary displays the status codes and timing percentiles of repeated requests.
+func PrintHTTPProbeSummary(summary HTTPProbeSummary) {
+	statuses := make(map[string]int)
+	failed := 0
+	for _, result := range summary.Results {
+		if result.Error != "" && result.StatusCode == 0 {
+			failed++
+			continue
+		}
+		statuses[result.Status]++
+	}
+
+	s := utils.Table("DarkSimple", "Responses")
+	s.AppendHeader(table.Row{"Status", "Count"})
+	keys := make([]string, 0, len(statuses))
+	for status := range statuses {
+		keys = append(keys, status)
+	}
+	sort.Strings(keys)
+	for _, status := range keys {
+		s.AppendRow(table.Row{status, statuses[status]})
+	}
+	if failed > 0 {
+		s.AppendRow(table.Row{"failed", failed})
+	}
+	fmt.Println()
+	s.Render()
+
+	t := utils.Table("DarkSimple", "Timing Percentiles")
+	t.AppendHeader(table.Row{"Phase", "Min", "P50", "P90", "P95", "P99", "Max"})
+	for _, phase := range summary.Phases {
+		t.AppendRow(table.Row{
+			phase.Phase,
+			formatHTTPPhase(phase.Min),
+			formatHTTPPhase(phase.P50),
+			formatHTTPPhase(phase.P90),
+			formatHTTPPhase(phase.P95),
+			formatHTTPPhase(phase.P99),
+			formatHTTPPhase(phase.Max),
+		})
+	}
+	fmt.Println()
+	t.Render()
+	fmt.Println()
+	fmt.Printf("%d requests to %s; %d failed.\n", len(summary.Results), summary.Results[0].URL, failed)
+
+	for _, result := range summary.Results {
+		if result.Error != "" {
+			utils.TerminalColor(fmt.Sprintf("Request failed: %s", result.Error), utils.Warn)
+			break
+		}
+	}
+}
+
+// formatHTTPPhase formats the duration of a timing phase, or "-" when the phase did not happen.
+func formatHTTPPhase(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.3fms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+	"github.com/spf13/cobra"
+)
+
+// InventoryCmd scans the local network and tracks the devices found in a persistent inventory.
+var InventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Tracks devices on the local network and reports changes",
+	Long: `Scans the local network with ARP (see arpscan) and records every device by MAC address in an
+inventory file, with the time it was first and last seen, the addresses it used, its vendor and
+an optional label. Each run reports devices that are new, devices from the previous run that no
+longer answer, and devices whose IP address changed. Exits with status 2 when anything changed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		storePath, _ := cmd.Flags().GetString("store")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		passive, _ := cmd.Flags().GetBool("passive")
+		opts := ARPScanOptions{}
+		opts.Interface, _ = cmd.Flags().GetString("interface")
+		opts.CIDR, _ = cmd.Flags().GetString("cidr")
+		opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+		opts.Retries, _ = cmd.Flags().GetInt("retries")
+
+		store, err := LoadInventory(storePath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		// Only devices on the scanned interface can be reported missing
+		scope := opts.Interface
+		var results []ARPResult
+		if passive {
+			results, err = RunNeighborTable(opts, false)
+		} else {
+			if scope == "" {
+				if iface, err := getInterface(""); err == nil {
+					scope = iface.Name
+				}
+			}
+			results, err = RunARPScanner(opts)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		report := store.Update(results, scope, time.Now())
+		if err := SaveInventory(storePath, store); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			utils.PrintJSON(report)
+		} else {
+			PrintInventoryReport(report)
+		}
+		if report.ChangesFound {
+			os.Exit(utils.FindingsExitCode)
+		}
+	},
+}
+
+// InventoryListCmd lists every device in the inventory.
+var InventoryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the devices in the inventory",
+	Run: func(cmd *cobra.Command, args []string) {
+		storePath, _ := cmd.Flags().GetString("store")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+
+		store, err := LoadInventory(storePath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if jsonOutput {
+			utils.PrintJSON(store.SortedDevices())
+			return
+		}
+		PrintInventoryDevices(store.SortedDevices())
+	},
+}
+
+// InventoryLabelCmd assigns a label to a device in the inventory.
+var InventoryLabelCmd = &cobra.Command{
+	Use:   "label <mac> <label>",
+	Short: "Assigns a label to a device (an empty label clears it)",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		storePath, _ := cmd.Flags().GetString("store")
+
+		store, err := LoadInventory(storePath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := store.SetLabel(args[0], args[1]); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := SaveInventory(storePath, store); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Labeled %s as %q.\n", inventoryKey(args[0]), args[1])
+	},
+}
+
+// init registers the inventory commands with the root command when this package is imported.
+func init() {
+	RootCmd.AddCommand(InventoryCmd)
+	InventoryCmd.AddCommand(InventoryListCmd)
+	InventoryCmd.AddCommand(InventoryLabelCmd)
+
+	InventoryCmd.PersistentFlags().String("store", DefaultInventoryPath(), "Inventory file")
+	InventoryCmd.PersistentFlags().Bool("json", false, "Print the results as JSON")
+	InventoryCmd.Flags().StringP("interface", "i", "", "Network interface to scan from (default: the default-route interface)")
+	InventoryCmd.Flags().StringP("cidr", "c", "", "IPv4 network to scan (default: the interface's own network)")
+	InventoryCmd.Flags().Duration("timeout", 1*time.Second, "Time to wait for a reply to each request")
+	InventoryCmd.Flags().Int("retries", 1, "Number of times to retry addresses that did not answer")
+	InventoryCmd.Flags().BoolP("passive", "p", false, "Use the kernel neighbor cache instead of sending ARP requests")
+}
+
+// PrintInventoryReport displays the changes found by an inventory run.
+func PrintInventoryReport(report InventoryReport) {
+	t := utils.Table("DarkSimple", "Inventory Changes")
+	t.AppendHeader(table.Row{"Change", "MAC Address", "Vendor", "Label", "IP Addresses", "First Seen", "Last Seen"})
+
+	for _, device := range report.New {
+		t.AppendRow(inventoryRow("NEW", device, strings.Join(device.Addresses, ", ")))
+	}
+	for _, change := range report.IPChanged {
+		ips := strings.Join(change.PreviousIPs, ", ") + " -> " + strings.Join(change.CurrentIPs, ", ")
+		t.AppendRow(inventoryRow("IP CHANGED", change.Device, ips))
+	}
+	for _, device := range report.Missing {
+		t.AppendRow(inventoryRow("MISSING", device, strings.Join(device.Addresses, ", ")))
+	}
+	if len(report.New)+len(report.IPChanged)+len(report.Missing) == 0 {
+		t.AppendRow(table.Row{"-", "-", "-", "-", "-", "-", "-"})
+	}
+
+	fmt.Println()
+	t.Render()
+	fmt.Println()
+
+	switch {
+	case report.PreviousScan == nil:
+		fmt.Printf("%d devices recorded as the baseline.\n", report.Seen)
+	case report.ChangesFound:
+		fmt.Printf("%d devices seen: %d new, %d missing, %d with changed addresses since %s.\n",
+			report.Seen, len(report.New), len(report.Missing), len(report.IPChanged),
+			report.PreviousScan.Format(time.RFC3339))
+	default:
+		fmt.Printf("%d devices seen; no changes since %s.\n", report.Seen, report.PreviousScan.Format(time.RFC3339))
+	}
+}
+
+// PrintInventoryDevices displays every device in the inventory.
+func PrintInventoryDevices(devices []InventoryDevice) {
+	t := utils.Table("DarkSimple", "Device Inventory")
+	t.AppendHeader(table.Row{"MAC Address", "Vendor", "Label", "Interface", "Current IPs", "IPs Observed", "First Seen", "Last Seen"})
+	for _, device := range devices {
+		t.AppendRow(table.Row{
+			device.MAC,
+			device.Vendor,
+			device.Label,
+			device.Interface,
+			strings.Join(device.Addresses, ", "),
+			strings.Join(device.IPsObserved, ", "),
+			device.FirstSeen.Format(time.RFC3339),
+			device.LastSeen.Format(time.RFC3339),
+		})
+	}
+	fmt.Println()
+	t.Render()
+	fmt.Println()
+}
+
+// inventoryRow builds a change table row for device.
+func inventoryRow(change string, device InventoryDevice, ips string) table.Row {
+	return table.Row{
+		change,
+		device.MAC,
+		device.Vendor,
+		device.Label,
+		ips,
+		device.FirstSeen.Format(time.RFC3339),
+		device.LastSeen.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mwiater/ghost/utils"
+)
+
+// InventoryDevice is a device recorded in the inventory, identified by its MAC address.
+type InventoryDevice struct {
+	MAC         string    `json:"mac"`
+	Vendor      string    `json:"vendor"`
+	Label       string    `json:"label"`
+	Interface   string    `json:"interface"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	Addresses   []string  `json:"addresses"`   // Addresses the device answered for in its most recent scan
+	IPsObserved []string  `json:"ipsObserved"` // Every address the device has been seen with
+}
+
+// InventoryStore is the on-disk device inventory written by the `inventory` command.
+type InventoryStore struct {
+	LastScan time.Time                   `json:"lastScan"`
+	Devices  map[string]*InventoryDevice `json:"devices"` // Keyed by lower-case MAC address
+}
+
+// InventoryIPChange is a known device that answered from different addresses than last time.
+type InventoryIPChange struct {
+	Device      InventoryDevice `json:"device"`
+	PreviousIPs []string        `json:"previousIPs"`
+	CurrentIPs  []string        `json:"currentIPs"`
+}
+
+// InventoryReport describes how the devices found by a scan differ from the inventory.
+type InventoryReport struct {
+	ScanTime     time.Time           `json:"scanTime"`
+	PreviousScan *time.Time          `json:"previousScan"`
+	Seen         int                 `json:"seen"`
+	New          []InventoryDevice   `json:"new"`
+	Missing      []InventoryDevice   `json:"missing"`
+	IPChanged    []InventoryIPChange `json:"ipChanged"`
+	ChangesFound bool                `json:"changesFound"`
+}
+
+// DefaultInventoryPath returns the default location of the inventory store,
+// ghost/inventory.json in the user's configuration directory.
+func DefaultInventoryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "inventory.json"
+	}
+	return filepath.Join(dir, "ghost", "inventory.json")
+}
+
+// LoadInventory reads the inventory store at path. A missing file yields an empty inventory.
+func LoadInventory(path string) (*InventoryStore, error) {
+	store := &InventoryStore{}
+	if err := utils.ReadJSONFile(path, store); err != nil {
+		return nil, fmt.Errorf("error reading inventory: %w", err)
+	}
+	if store.Devices == nil {
+		store.Devices = make(map[string]*InventoryDevice)
+	}
+	return store, nil
+}
+
+// SaveInventory writes the inventory store to path, replacing the previous file atomically.
+func SaveInventory(path string, store *InventoryStore) error {
+	if err := utils.WriteJSONFile(path, store); err != nil {
+		return fmt.Errorf("error writing inventory: %w", err)
+	}
+	return nil
+}
+
+// Update records the devices found by a scan at scanTime and reports new devices, devices that
+// were present in the previous scan but did not answer this time, and devices whose addresses
+// changed. Only devices last seen on scope are considered missing; an empty scope means any
+// interface.
+func (s *InventoryStore) Update(results []ARPResult, scope string, scanTime time.Time) InventoryReport {
+	report := InventoryReport{
+		ScanTime:  scanTime,
+		New:       []InventoryDevice{},
+		Missing:   []InventoryDevice{},
+		IPChanged: []InventoryIPChange{},
+	}
+	previousScan := s.LastScan
+	if !previousScan.IsZero() {
+		report.PreviousScan = &previousScan
+	}
+
+	// Group the scan results by device, since one device may answer for several addresses
+	current := make(map[string][]ARPResult)
+	for _, result := range results {
+		if result.MACAddress == "" {
+			continue
+		}
+		key := inventoryKey(result.MACAddress)
+		current[key] = append(current[key], result)
+	}
+
+	for key, found := range current {
+		var addresses []string
+		for _, result := range found {
+			addresses = appendUnique(addresses, result.IPAddress)
+		}
+		sort.Strings(addresses)
+
+		device, known := s.Devices[key]
+		if !known {
+			device = &InventoryDevice{MAC: key, FirstSeen: scanTime}
+			s.Devices[key] = device
+		}
+		previousIPs := device.Addresses
+		device.Vendor = found[0].Vendor
+		device.Interface = found[0].Interface
+		device.LastSeen = scanTime
+		device.Addresses = addresses
+		for _, address := range addresses {
+			device.IPsObserved = appendUnique(device.IPsObserved, address)
+		}
+
+		switch {
+		case !known:
+			report.New = append(report.New, *device)
+		case len(previousIPs) > 0 && strings.Join(previousIPs, ",") != strings.Join(addresses, ","):
+			report.IPChanged = append(report.IPChanged, InventoryIPChange{
+				Device:      *device,
+				PreviousIPs: previousIPs,
+				CurrentIPs:  addresses,
+			})
+		}
+	}
+
+	for key, device := range s.Devices {
+		if _, found := current[key]; found || previousScan.IsZero() {
+			continue
+		}
+		if device.LastSeen.Equal(previousScan) && (scope == "" || device.Interface == scope) {
+			report.Missing = append(report.Missing, *device)
+		}
+	}
+
+	s.LastScan = scanTime
+	report.Seen = len(current)
+	sortInventoryDevices(report.New)
+	sortInventoryDevices(report.Missing)
+	sort.Slice(report.IPChanged, func(i, j int) bool {
+		return report.IPChanged[i].Device.MAC < report.IPChanged[j].Device.MAC
+	})
+	// The first scan only establishes the baseline, so its devices do not count as changes
+	report.ChangesFound = !previousScan.IsZero() && (len(report.New) > 0 || len(report.Missing) > 0 || len(report.IPChanged) > 0)
+	return report
+}
+
+// SetLabel assigns a label to the device with the given MAC address; an empty label clears it.
+func (s *InventoryStore) SetLabel(mac, label string) error {
+	device, ok := s.Devices[inventoryKey(mac)]
+	if !ok {
+		return fmt.Errorf("no device with MAC address %s in the inventory", mac)
+	}
+	device.Label = label
+	return nil
+}
+
+// SortedDevices returns the inventory's devices ordered by MAC address.
+func (s *InventoryStore) SortedDevices() []InventoryDevice {
+	devices := make([]InventoryDevice, 0, len(s.Devices))
+	for _, device := range s.Devices {
+		devices = append(devices, *device)
+	}
+	sortInventoryDevices(devices)
+	return devices
+}
+
+// inventoryKey normalizes a MAC address so that aa-bb-… and AA:BB:… map to the same device.
+func inventoryKey(mac string) string {
+	if hw, err := net.ParseMAC(mac); err == nil {
+		return hw.String()
+	}
+	return strings.ToLower(mac)
+}
+
+// appendUnique appends value to values unless it is already present.
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// sortInventoryDevices orders devices by MAC address.
+func sortInventoryDevices(devices []InventoryDevice) {
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].MAC < devices[j].MAC
+	})
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInventoryUpdate(t *testing.T) {
+	first := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	third := second.Add(time.Hour)
+	store := &InventoryStore{Devices: make(map[string]*InventoryDevice)}
+
+	report := store.Update([]ARPResult{
+		{IPAddress: "192.168.1.1", MACAddress: "00:0C:29:AA:BB:01", Interface: "eth0"},
+		{IPAddress: "192.168.1.2", MACAddress: "00-0c-29-aa-bb-02", Interface: "eth0"},
+		{IPAddress: "192.168.1.3", MACAddress: "00:0c:29:aa:bb:03", Interface: "wlan0"},
+		{IPAddress: "192.168.1.9"}, // Unresolved entries carry no device
+	}, "", first)
+	if report.ChangesFound || report.PreviousScan != nil || len(report.New) != 3 || report.Seen != 3 {
+		t.Fatalf("first scan: %+v, want a baseline of 3 new devices without changes", report)
+	}
+
+	// .2 moves to another address, .3 is on another interface and out of scope, .4 is new
+	report = store.Update([]ARPResult{
+		{IPAddress: "192.168.1.1", MACAddress: "00:0c:29:aa:bb:01", Interface: "eth0"},
+		{IPAddress: "192.168.1.20", MACAddress: "00:0c:29:aa:bb:02", Interface: "eth0"},
+		{IPAddress: "192.168.1.4", MACAddress: "00:0c:29:aa:bb:04", Interface: "eth0"},
+	}, "eth0", second)
+	if !report.ChangesFound || report.PreviousScan == nil || !report.PreviousScan.Equal(first) {
+		t.Fatalf("second scan: ChangesFound=%v PreviousScan=%v", report.ChangesFound, report.PreviousScan)
+	}
+	if macs := inventoryMACs(report.New); !reflect.DeepEqual(macs, []string{"00:0c:29:aa:bb:04"}) {
+		t.Errorf("new = %v", macs)
+	}
+	if len(report.Missing) != 0 {
+		t.Errorf("missing = %v, want none: the wlan0 device is out of scope", inventoryMACs(report.Missing))
+	}
+	if len(report.IPChanged) != 1 || !reflect.DeepEqual(report.IPChanged[0].PreviousIPs, []string{"192.168.1.2"}) ||
+		!reflect.DeepEqual(report.IPChanged[0].CurrentIPs, []string{"192.168.1.20"}) {
+		t.Errorf("ipChanged = %+v", report.IPChanged)
+	}
+	if got := store.Devices["00:0c:29:aa:bb:02"].IPsObserved; !reflect.DeepEqual(got, []string{"192.168.1.2", "192.168.1.20"}) {
+		t.Errorf("IPsObserved = %v", got)
+	}
+
+	// A device that answered in the previous scan but not now is missing; earlier ones are not again
+	report = store.Update([]ARPResult{
+		{IPAddress: "192.168.1.1", MACAddress: "00:0c:29:aa:bb:01", Interface: "eth0"},
+		{IPAddress: "192.168.1.4", MACAddress: "00:0c:29:aa:bb:04", Interface: "eth0"},
+	}, "", third)
+	if macs := inventoryMACs(report.Missing); !reflect.DeepEqual(macs, []string{"00:0c:29:aa:bb:02"}) {
+		t.Errorf("missing = %v, want only the device seen in the previous scan", macs)
+	}
+	if !store.Devices["00:0c:29:aa:bb:01"].FirstSeen.Equal(first) || !store.LastScan.Equal(third) {
+		t.Errorf("FirstSeen %v LastScan %v", store.Devices["00:0c:29:aa:bb:01"].FirstSeen, store.LastScan)
+	}
+}
+
+func TestInventoryRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.json")
+	store, err := LoadInventory(path)
+	if err != nil || store.Devices == nil || len(store.Devices) != 0 {
+		t.Fatalf("LoadInventory(missing) = %+v, %v", store, err)
+	}
+	store.Update([]ARPResult{{IPAddress: "10.0.0.5", MACAddress: "00:0c:29:aa:bb:05", Interface: "eth0"}}, "", time.Now())
+	if err := store.SetLabel("00-0C-29-AA-BB-05", "printer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetLabel("00:0c:29:aa:bb:99", "unknown"); err == nil {
+		t.Error("SetLabel accepted an unknown device")
+	}
+	if err := SaveInventory(path, store); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadInventory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices := loaded.SortedDevices()
+	if len(devices) != 1 || devices[0].Label != "printer" || devices[0].Addresses[0] != "10.0.0.5" {
+		t.Errorf("loaded devices = %+v", devices)
+	}
+}
+
+func inventoryMACs(devices []InventoryDevice) []string {
+	var macs []string
+	for _, device := range devices {
+		macs = append(macs, device.MAC)
+	}
+	return macs
+}
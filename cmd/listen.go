@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/mwiater/ghost/utils"
+	"github.com/spf13/cobra"
+)
+
+// ListenCmd opens TCP and UDP listeners and logs everything they receive.
+var ListenCmd = &cobra.Command{
+	Use:   "listen",
+	Short: "Opens TCP and UDP listeners and logs every connection and datagram",
+	Long: `Opens listeners on the given TCP and UDP ports and logs every incoming connection and datagram
+with its source address, which shows what actually gets through a firewall and from where (the
+source shows NAT). Challenges sent by "ghost reach" are answered, so the prober can tell the
+listener apart from a middlebox answering in its place. Runs until interrupted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		spec, _ := cmd.Flags().GetString("ports")
+		bind, _ := cmd.Flags().GetString("bind")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+
+		ports, err := ParsePortSpecs(spec)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		listener, errs := NewReachListener(bind, ports)
+		for _, err := range errs {
+			utils.TerminalColor(fmt.Sprintf("Warning: cannot listen on %v", err), utils.Warn)
+		}
+		if listener.Ports() == 0 {
+			fmt.Println("Error: no port could be opened")
+			os.Exit(1)
+		}
+
+		listener.OnEvent = func(event ReachEvent) {
+			if jsonOutput {
+				// Compact JSON keeps each challenge on one line of the log
+				data, _ := json.Marshal(event)
+				fmt.Println(string(data))
+				return
+			}
+			PrintReachEvent(event)
+		}
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		go func() {
+			<-interrupt
+			listener.Close()
+		}()
+
+		if !jsonOutput {
+			fmt.Printf("Listening on %d port(s). Press Ctrl-C to stop.\n", listener.Ports())
+		}
+		listener.Serve()
+	},
+}
+
+// init registers the ListenCmd with the root command when this package is imported.
+func init() {
+	RootCmd.AddCommand(ListenCmd)
+	ListenCmd.Flags().StringP("ports", "p", "", "Ports to listen on, e.g. 8000-8010,9000/udp (tcp unless /udp is given)")
+	ListenCmd.Flags().String("bind", "", "Address to listen on (default: all addresses)")
+	ListenCmd.Flags().Bool("json", false, "Log events as JSON, one object per line")
+}
+
+// PrintReachEvent logs a connection or datagram received by the listener.
+func PrintReachEvent(event ReachEvent) {
+	what := fmt.Sprintf("%d bytes, no challenge", event.Bytes)
+	if event.Challenge {
+		what = "challenge answered"
+	}
+	if event.Error != "" {
+		what = event.Error
+	}
+	fmt.Printf("%s  %5d/%s  from %-40s %s\n", event.Time.Format("2006-01-02 15:04:05"), event.Port, event.Protocol, event.Source, what)
+}
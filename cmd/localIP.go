@@ -9,16 +9,47 @@ import (
 	"github.com/spf13/cobra"
 )
 
-// LocalIPCmd represents the `localip` command, which finds and returns the first
-// internal IPv4 address, typically within the "192.168" subnet. This is useful for
-// services that need to bind to an internal network interface.
+// LocalIPCmd represents the `localip` command, which finds and returns an internal address of
+// this machine: by default the first IPv4 address in the "192.168" subnet, with --ipv6 an
+// internal IPv6 address, and with --all every local address classified by scope.
 var LocalIPCmd = &cobra.Command{
 	Use:   "localip",
-	Short: "Finds an internal IPv4 address.",
+	Short: "Finds an internal IPv4 or IPv6 address, or lists every local address.",
 	Long: `Searches for and returns the first internal IPv4 address, typically 
 within the "192.168" subnet. If none is found, it returns an error. This is 
-useful for services that need to bind to an internal network interface.`,
+useful for services that need to bind to an internal network interface.
+
+With --ipv6 it returns an internal IPv6 address instead: the first global or 
+unique-local (fc00::/7) address, falling back to a link-local address with its 
+zone (e.g. fe80::1%eth0) when nothing routable is configured.
+
+With --all it lists every address of every interface with its prefix, family 
+and scope (loopback, link-local, unique-local, site-local, private, shared, 
+multicast or global).`,
 	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		ipv6, _ := cmd.Flags().GetBool("ipv6")
+
+		if all {
+			addresses, err := GetLocalAddresses()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			PrintLocalAddresses(addresses)
+			return
+		}
+
+		if ipv6 {
+			localIP, err := GetInternalIPv6()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			PrintLocalIP(localIP)
+			return
+		}
+
 		localIP, err := RunLocalIP()
 		if err != nil {
 			fmt.Println("Error:", err)
@@ -28,6 +59,28 @@ useful for services that need to bind to an internal network interface.`,
 	},
 }
 
+// Address scopes reported by ClassifyIP.
+const (
+	ScopeLoopback    = "loopback"
+	ScopeLinkLocal   = "link-local"
+	ScopeULA         = "unique-local"
+	ScopeSiteLocal   = "site-local"
+	ScopePrivate     = "private"
+	ScopeShared      = "shared (CGNAT)"
+	ScopeMulticast   = "multicast"
+	ScopeGlobal      = "global"
+	ScopeUnspecified = "unspecified"
+)
+
+// LocalAddress describes one address assigned to a local network interface.
+type LocalAddress struct {
+	Interface string
+	Address   string
+	Prefix    string
+	Family    string
+	Scope     string
+}
+
 // RunLocalIP retrieves the internal IPv4 address of the local machine.
 // It returns the IP address as a string and an error if no address is found.
 func RunLocalIP() (string, error) {
@@ -50,6 +103,109 @@ func PrintLocalIP(localIP string) {
 	fmt.Println()
 }
 
+// PrintLocalAddresses displays every local address with its interface, family and scope.
+func PrintLocalAddresses(addresses []LocalAddress) {
+	t := utils.Table("DarkSimple", "localIPCmd")
+	t.AppendHeader(table.Row{"Interface", "Address", "Prefix", "Family", "Scope"})
+	for _, addr := range addresses {
+		t.AppendRow(table.Row{addr.Interface, addr.Address, addr.Prefix, addr.Family, addr.Scope})
+	}
+
+	fmt.Println()
+	t.Render()
+	fmt.Println()
+}
+
+// GetLocalAddresses lists the addresses of all network interfaces, classifying each one.
+// IPv6 link-local addresses include their zone (e.g. fe80::1%eth0) so they can be used directly.
+func GetLocalAddresses() ([]LocalAddress, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []LocalAddress
+	for _, iface := range interfaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			family := "IPv6"
+			if ipNet.IP.To4() != nil {
+				family = "IPv4"
+			}
+			address := ipNet.IP.String()
+			scope := ClassifyIP(ipNet.IP)
+			if family == "IPv6" && scope == ScopeLinkLocal {
+				address += "%" + iface.Name
+			}
+			ones, _ := ipNet.Mask.Size()
+			addresses = append(addresses, LocalAddress{
+				Interface: iface.Name,
+				Address:   address,
+				Prefix:    fmt.Sprintf("/%d", ones),
+				Family:    family,
+				Scope:     scope,
+			})
+		}
+	}
+	return addresses, nil
+}
+
+// ClassifyIP returns the scope of an address: loopback, link-local, unique-local (fc00::/7),
+// site-local (deprecated fec0::/10), private (RFC 1918), shared (100.64.0.0/10), multicast or global.
+func ClassifyIP(ip net.IP) string {
+	switch {
+	case ip.IsUnspecified():
+		return ScopeUnspecified
+	case ip.IsLoopback():
+		return ScopeLoopback
+	case ip.IsLinkLocalUnicast():
+		return ScopeLinkLocal
+	case ip.IsMulticast():
+		return ScopeMulticast
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		if ip4.IsPrivate() {
+			return ScopePrivate
+		}
+		if ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+			return ScopeShared
+		}
+		return ScopeGlobal
+	}
+	if ip.IsPrivate() {
+		return ScopeULA
+	}
+	if ip[0] == 0xfe && ip[1]&0xc0 == 0xc0 {
+		return ScopeSiteLocal
+	}
+	return ScopeGlobal
+}
+
+// GetInternalIPv6 returns the first global or unique-local IPv6 address, falling back to a
+// link-local address (with zone) when nothing routable is configured.
+func GetInternalIPv6() (string, error) {
+	addresses, err := GetLocalAddresses()
+	if err != nil {
+		return "", err
+	}
+
+	for _, scope := range []string{ScopeGlobal, ScopeULA, ScopeLinkLocal} {
+		for _, addr := range addresses {
+			if addr.Family == "IPv6" && addr.Scope == scope {
+				return addr.Address, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no internal IPv6 address found")
+}
+
 // GetInternalIPv4 searches for and returns the first internal IPv4 address it finds,
 // typically one that starts with "192.168". If no such address is found, it returns an error.
 func GetInternalIPv4() (string, error) {
@@ -72,7 +228,9 @@ func GetInternalIPv4() (string, error) {
 }
 
 // init initializes the `localIP` command and adds it to the RootCmd.
-// This command allows users to find and display the first internal IPv4 address.
+// This command allows users to find and display the internal addresses of this machine.
 func init() {
 	RootCmd.AddCommand(LocalIPCmd)
+	LocalIPCmd.Flags().BoolP("all", "a", false, "List every local address with its interface, family and scope")
+	LocalIPCmd.Flags().BoolP("ipv6", "6", false, "Return an internal IPv6 address (global or unique-local, else link-local)")
 }
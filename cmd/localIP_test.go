@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"net"
+	"testing"
+)
+
+func TestClassifyIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"0.0.0.0", ScopeUnspecified},
+		{"::", ScopeUnspecified},
+		{"127.0.0.1", ScopeLoopback},
+		{"::1", ScopeLoopback},
+		{"169.254.10.1", ScopeLinkLocal},
+		{"fe80::1", ScopeLinkLocal},
+		{"224.0.0.251", ScopeMulticast},
+		{"ff02::1", ScopeMulticast},
+		{"10.1.2.3", ScopePrivate},
+		{"172.16.0.1", ScopePrivate},
+		{"192.168.0.10", ScopePrivate},
+		{"100.64.0.1", ScopeShared},
+		{"100.127.255.254", ScopeShared},
+		{"100.128.0.1", ScopeGlobal},
+		{"8.8.8.8", ScopeGlobal},
+		{"fd12:3456::1", ScopeULA},
+		{"fec0::1", ScopeSiteLocal},
+		{"2001:4860:4860::8888", ScopeGlobal},
+	}
+	for _, tt := range tests {
+		if got := ClassifyIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("ClassifyIP(%s) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
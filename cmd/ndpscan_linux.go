@@ -0,0 +1,184 @@
+//go:build linux
+// +build linux
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+// runNDPScan discovers IPv6 neighbors on the local link, the IPv6 counterpart to the ARP scan.
+// It pings the all-nodes multicast group to find responders, then sends each one a Neighbor
+// Solicitation and reads the link-layer address from the Neighbor Advertisement it returns.
+// Replies are collected for opts.Timeout after each round of requests.
+func runNDPScan(opts ARPScanOptions) ([]ARPResult, error) {
+	iface, err := getInterface(opts.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("error getting interface: %w", err)
+	}
+
+	conn, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return nil, fmt.Errorf("error creating ICMPv6 socket: %w", err)
+	}
+	defer conn.Close()
+
+	// NDP messages must be sent with a hop limit of 255 or receivers discard them (RFC 4861).
+	pconn := conn.IPv6PacketConn()
+	if err := pconn.SetMulticastInterface(iface); err != nil {
+		return nil, fmt.Errorf("error selecting interface %s: %w", iface.Name, err)
+	}
+	pconn.SetMulticastHopLimit(255)
+	pconn.SetHopLimit(255)
+	pconn.SetMulticastLoopback(false)
+	var filter ipv6.ICMPFilter
+	filter.SetAll(true)
+	filter.Accept(ipv6.ICMPTypeEchoReply)
+	filter.Accept(ipv6.ICMPTypeNeighborAdvertisement)
+	pconn.SetICMPFilter(&filter)
+
+	// Step 1: find the hosts on the link by pinging ff02::1.
+	echo := icmp.Message{
+		Type: ipv6.ICMPTypeEchoRequest,
+		Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: 1, Data: []byte("ghost-ndp")},
+	}
+	packet, err := echo.Marshal(nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteTo(packet, &net.IPAddr{IP: net.IPv6linklocalallnodes, Zone: iface.Name}); err != nil {
+		return nil, fmt.Errorf("error sending multicast echo request: %w", err)
+	}
+
+	responders := make(map[string]net.IP)
+	neighbors := make(map[string]map[string]ARPResult) // Keyed by address, then MAC address
+	sentAt := make(map[string]time.Time)
+	readNDPReplies(conn, iface, opts.Timeout, sentAt, responders, neighbors)
+
+	// Step 2: resolve each responder's link-layer address with a Neighbor Solicitation,
+	// retrying the ones that have not answered yet.
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		sent := 0
+		for key, ip := range responders {
+			if _, ok := neighbors[key]; ok {
+				continue
+			}
+			solicitation, err := neighborSolicitation(ip, iface.HardwareAddr)
+			if err != nil {
+				continue
+			}
+			sentAt[key] = time.Now()
+			conn.WriteTo(solicitation, &net.IPAddr{IP: solicitedNodeAddress(ip), Zone: iface.Name})
+			sent++
+		}
+		if sent == 0 {
+			break
+		}
+		readNDPReplies(conn, iface, opts.Timeout, sentAt, responders, neighbors)
+	}
+
+	var results []ARPResult
+	for _, byMAC := range neighbors {
+		for _, neighbor := range byMAC {
+			neighbor.Duplicate = len(byMAC) > 1
+			results = append(results, neighbor)
+		}
+	}
+	sortARPResults(results)
+	return results, nil
+}
+
+// readNDPReplies collects echo replies and neighbor advertisements until timeout passes.
+func readNDPReplies(conn *icmp.PacketConn, iface *net.Interface, timeout time.Duration, sentAt map[string]time.Time, responders map[string]net.IP, neighbors map[string]map[string]ARPResult) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		msg, err := icmp.ParseMessage(ipv6.ICMPTypeEchoReply.Protocol(), buf[:n])
+		if err != nil {
+			continue
+		}
+		switch msg.Type {
+		case ipv6.ICMPTypeEchoReply:
+			if addr, ok := peer.(*net.IPAddr); ok {
+				responders[addr.IP.String()] = addr.IP
+			}
+		case ipv6.ICMPTypeNeighborAdvertisement:
+			body, ok := msg.Body.(*icmp.RawBody)
+			if !ok {
+				continue
+			}
+			target, mac, ok := parseNeighborAdvertisement(body.Data)
+			if !ok {
+				continue
+			}
+			result := ARPResult{
+				IPAddress:  ndpDisplayAddress(target, iface.Name),
+				MACAddress: mac.String(),
+				Interface:  iface.Name,
+			}
+			if sent, ok := sentAt[target.String()]; ok {
+				result.ResponseTime = time.Since(sent)
+			}
+			if neighbors[target.String()] == nil {
+				neighbors[target.String()] = make(map[string]ARPResult)
+			}
+			if _, seen := neighbors[target.String()][result.MACAddress]; !seen {
+				neighbors[target.String()][result.MACAddress] = result
+			}
+		}
+	}
+}
+
+// neighborSolicitation builds an ICMPv6 Neighbor Solicitation for target, advertising our own
+// link-layer address in the source link-layer address option.
+func neighborSolicitation(target net.IP, source net.HardwareAddr) ([]byte, error) {
+	data := make([]byte, 4, 4+net.IPv6len+8)
+	data = append(data, target.To16()...)
+	if len(source) == 6 {
+		data = append(data, 1, 1) // Option type 1 (source link-layer address), length 1 (8 bytes)
+		data = append(data, source...)
+	}
+	msg := icmp.Message{
+		Type: ipv6.ICMPTypeNeighborSolicitation,
+		Body: &icmp.RawBody{Data: data},
+	}
+	// The kernel fills in the ICMPv6 checksum for raw sockets, so no pseudo header is needed.
+	return msg.Marshal(nil)
+}
+
+// parseNeighborAdvertisement extracts the target address and target link-layer address option
+// from the body of a Neighbor Advertisement.
+func parseNeighborAdvertisement(data []byte) (net.IP, net.HardwareAddr, bool) {
+	if len(data) < 4+net.IPv6len {
+		return nil, nil, false
+	}
+	target := net.IP(append([]byte(nil), data[4:4+net.IPv6len]...))
+	options := data[4+net.IPv6len:]
+	for len(options) >= 2 {
+		length := int(options[1]) * 8
+		if length == 0 || length > len(options) {
+			break
+		}
+		if options[0] == 2 && length >= 8 { // Target link-layer address
+			return target, net.HardwareAddr(append([]byte(nil), options[2:8]...)), true
+		}
+		options = options[length:]
+	}
+	return nil, nil, false
+}
+
+// solicitedNodeAddress returns the solicited-node multicast address (ff02::1:ffXX:XXXX) for ip.
+func solicitedNodeAddress(ip net.IP) net.IP {
+	ip = ip.To16()
+	return net.IP{0xff, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, 0xff, ip[13], ip[14], ip[15]}
+}
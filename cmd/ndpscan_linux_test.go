@@ -0,0 +1,54 @@
+//go:build linux
+// +build linux
+
+package cmd
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestSolicitedNodeAddress(t *testing.T) {
+	got := solicitedNodeAddress(net.ParseIP("2001:db8::2aa:ff:fe28:9c5a"))
+	if want := net.ParseIP("ff02::1:ff28:9c5a"); !got.Equal(want) {
+		t.Errorf("solicitedNodeAddress = %s, want %s", got, want)
+	}
+}
+
+func TestParseNeighborAdvertisement(t *testing.T) {
+	target := net.ParseIP("fe80::1")
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	body := func(options ...byte) []byte {
+		data := []byte{0x60, 0, 0, 0} // Router, solicited and override flags
+		data = append(data, target.To16()...)
+		return append(data, options...)
+	}
+	targetLinkLayer := append([]byte{2, 1}, mac...)
+	sourceLinkLayer := append([]byte{1, 1}, mac...)
+
+	tests := []struct {
+		name string
+		data []byte
+		ok   bool
+	}{
+		{"target link-layer address", body(targetLinkLayer...), true},
+		{"after another option", body(append(sourceLinkLayer, targetLinkLayer...)...), true},
+		{"no option", body(), false},
+		{"only source link-layer address", body(sourceLinkLayer...), false},
+		{"zero-length option", body(2, 0, 0, 0, 0, 0, 0, 0), false},
+		{"truncated option", body(2, 1, 0x00, 0x11), false},
+		{"truncated body", body()[:10], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, hw, ok := parseNeighborAdvertisement(tt.data)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && (!ip.Equal(target) || !bytes.Equal(hw, mac)) {
+				t.Errorf("got %s %s, want %s %s", ip, hw, target, mac)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+// runNDPScan lists IPv6 neighbors on Windows from the neighbor cache.
+func runNDPScan(opts ARPScanOptions) ([]ARPResult, error) {
+	return neighborTable(opts, true)
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RunNeighborTable lists the entries of the kernel neighbor cache (the ARP table and the IPv6
+// neighbor table) instead of sending requests, so it needs no special privileges. When
+// opts.NeighborFile is set the entries are read from that file instead, which may hold the
+// contents of /proc/net/arp or the output of `ip neigh show`. ipv6 selects which table to list;
+// the entries are filtered by opts.Interface and opts.CIDR when those are set.
+func RunNeighborTable(opts ARPScanOptions, ipv6 bool) ([]ARPResult, error) {
+	results, err := neighborTable(opts, ipv6)
+	addARPVendors(results)
+	return results, err
+}
+
+// neighborTable reads and filters the neighbor entries for RunNeighborTable.
+func neighborTable(opts ARPScanOptions, ipv6 bool) ([]ARPResult, error) {
+	var entries []ARPResult
+	var err error
+	if opts.NeighborFile != "" {
+		entries, err = readNeighborFile(opts.NeighborFile)
+	} else {
+		entries, err = readNeighborTable()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var prefix netip.Prefix
+	if opts.CIDR != "" {
+		prefix, err = netip.ParsePrefix(opts.CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", opts.CIDR, err)
+		}
+	}
+
+	var results []ARPResult
+	for _, entry := range entries {
+		addr, err := netip.ParseAddr(entry.IPAddress)
+		if err != nil || addr.Is6() != ipv6 || addr.IsMulticast() || addr.IsLoopback() {
+			continue
+		}
+		if opts.Interface != "" && entry.Interface != opts.Interface {
+			continue
+		}
+		if prefix.IsValid() && !prefix.Contains(addr.WithZone("")) {
+			continue
+		}
+		results = append(results, entry)
+	}
+
+	markDuplicateARPResults(results)
+	sortARPResults(results)
+	return results, nil
+}
+
+// readNeighborFile reads neighbor entries from a copy of /proc/net/arp or saved `ip neigh` output.
+func readNeighborFile(path string) ([]ARPResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening neighbor file: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	header, _ := reader.Peek(10)
+	if strings.HasPrefix(string(header), "IP address") {
+		return parseProcNetARP(reader)
+	}
+	return parseIPNeigh(reader)
+}
+
+// parseProcNetARP parses the IPv4 neighbor table in the format of /proc/net/arp:
+//
+//	IP address       HW type     Flags       HW address            Mask     Device
+//	192.168.1.1      0x1         0x2         aa:bb:cc:dd:ee:ff     *        eth0
+//
+// The file only records whether an entry is complete or permanent, not its full NUD state.
+func parseProcNetARP(r io.Reader) ([]ARPResult, error) {
+	var results []ARPResult
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || net.ParseIP(fields[0]) == nil {
+			continue
+		}
+		flags, _ := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 32)
+		state := "INCOMPLETE"
+		switch {
+		case flags&0x4 != 0: // ATF_PERM
+			state = "PERMANENT"
+		case flags&0x2 != 0: // ATF_COM
+			state = "COMPLETE"
+		}
+		mac := fields[3]
+		if mac == "00:00:00:00:00:00" {
+			mac = ""
+		}
+		results = append(results, ARPResult{
+			IPAddress:  fields[0],
+			MACAddress: mac,
+			Interface:  fields[5],
+			State:      state,
+		})
+	}
+	return results, scanner.Err()
+}
+
+// parseIPNeigh parses the output of `ip neigh show`, for example:
+//
+//	192.168.1.1 dev eth0 lladdr aa:bb:cc:dd:ee:ff REACHABLE
+//	fe80::1 dev eth0 lladdr aa:bb:cc:dd:ee:ff router STALE
+//	192.168.1.7 dev eth0 FAILED
+func parseIPNeigh(r io.Reader) ([]ARPResult, error) {
+	var results []ARPResult
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+			continue
+		}
+		result := ARPResult{IPAddress: fields[0]}
+		for i := 1; i < len(fields); i++ {
+			switch fields[i] {
+			case "dev":
+				if i+1 < len(fields) {
+					result.Interface = fields[i+1]
+					i++
+				}
+			case "lladdr":
+				if i+1 < len(fields) {
+					result.MACAddress = fields[i+1]
+					i++
+				}
+			case "router", "proxy", "extern_learn", "offload":
+			default:
+				if strings.ToUpper(fields[i]) == fields[i] {
+					result.State = fields[i]
+				}
+			}
+		}
+		if ip := net.ParseIP(result.IPAddress); ip.IsLinkLocalUnicast() && ip.To4() == nil && result.Interface != "" {
+			result.IPAddress = ndpDisplayAddress(ip, result.Interface)
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
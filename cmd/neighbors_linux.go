@@ -0,0 +1,113 @@
+//go:build linux
+// +build linux
+
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Netlink neighbor attributes (linux/neighbour.h).
+const (
+	ndaDst    = 1
+	ndaLLAddr = 2
+)
+
+// ndmsgLen is the size of struct ndmsg, the fixed header of neighbor messages.
+const ndmsgLen = 12
+
+// readNeighborTable reads the kernel neighbor cache: IPv4 entries from /proc/net/arp and, via
+// netlink, the IPv6 neighbor table together with the NUD state (REACHABLE, STALE, ...) of
+// every entry, which /proc/net/arp does not record.
+func readNeighborTable() ([]ARPResult, error) {
+	var results []ARPResult
+	f, err := os.Open("/proc/net/arp")
+	if err == nil {
+		results, err = parseProcNetARP(f)
+		f.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading ARP table: %w", err)
+	}
+
+	neighbors, err := netlinkNeighbors()
+	if err != nil {
+		// Without netlink the IPv4 entries keep the coarse state from /proc/net/arp.
+		return results, nil
+	}
+	states := make(map[string]string)
+	for _, neighbor := range neighbors {
+		if ip := net.ParseIP(neighbor.IPAddress); ip != nil && ip.To4() != nil {
+			states[neighbor.IPAddress+"/"+neighbor.Interface] = neighbor.State
+			continue
+		}
+		results = append(results, neighbor)
+	}
+	for i := range results {
+		if state, ok := states[results[i].IPAddress+"/"+results[i].Interface]; ok {
+			results[i].State = state
+		}
+	}
+	return results, nil
+}
+
+// netlinkNeighbors dumps the neighbor tables of all address families with RTM_GETNEIGH.
+func netlinkNeighbors() ([]ARPResult, error) {
+	msgs, err := netlinkDump(syscall.RTM_GETNEIGH, syscall.AF_UNSPEC, syscall.RTM_NEWNEIGH, ndmsgLen)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ARPResult
+	for _, msg := range msgs {
+		// struct ndmsg: family, pad, pad, ifindex (int32), state (u16), flags, type
+		ifindex := int(int32(binary.NativeEndian.Uint32(msg.Header[4:8])))
+		state := binary.NativeEndian.Uint16(msg.Header[8:10])
+		dst := msg.Attrs[ndaDst]
+		if len(dst) != net.IPv4len && len(dst) != net.IPv6len {
+			continue
+		}
+		ip := net.IP(dst)
+		iface := netlinkInterfaceName(ifindex)
+		result := ARPResult{
+			IPAddress: ip.String(),
+			Interface: iface,
+			State:     neighborStateName(state),
+		}
+		if ip.To4() == nil {
+			result.IPAddress = ndpDisplayAddress(ip, iface)
+		}
+		if lladdr := msg.Attrs[ndaLLAddr]; len(lladdr) > 0 {
+			result.MACAddress = net.HardwareAddr(lladdr).String()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// neighborStateName returns the name `ip neigh` uses for a NUD_* state bit.
+func neighborStateName(state uint16) string {
+	switch state {
+	case 0x01:
+		return "INCOMPLETE"
+	case 0x02:
+		return "REACHABLE"
+	case 0x04:
+		return "STALE"
+	case 0x08:
+		return "DELAY"
+	case 0x10:
+		return "PROBE"
+	case 0x20:
+		return "FAILED"
+	case 0x40:
+		return "NOARP"
+	case 0x80:
+		return "PERMANENT"
+	}
+	return "NONE"
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// formatNeighbors renders entries as "address mac interface state" lines for comparison.
+func formatNeighbors(entries []ARPResult) []string {
+	var lines []string
+	for _, e := range entries {
+		line := fmt.Sprintf("%s %s %s %s", e.IPAddress, valueOrDash(e.MACAddress), valueOrDash(e.Interface), valueOrDash(e.State))
+		if e.Duplicate {
+			line += " duplicate"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestParseProcNetARP(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "neighbors", "proc_net_arp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := parseProcNetARP(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"192.168.1.1 00:0c:29:aa:bb:01 eth0 COMPLETE",
+		"192.168.1.20 - eth0 INCOMPLETE",
+		"192.168.1.30 b8:27:eb:12:34:56 eth0 PERMANENT",
+		"192.168.1.30 00:0c:29:aa:bb:02 eth0 COMPLETE",
+		"10.8.0.1 02:42:ac:11:00:02 docker0 COMPLETE",
+	}
+	if got := formatNeighbors(entries); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseIPNeigh(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "neighbors", "ip_neigh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := parseIPNeigh(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"192.168.1.1 00:0c:29:aa:bb:01 eth0 REACHABLE",
+		"192.168.1.20 - eth0 FAILED",
+		"192.168.1.40 b8:27:eb:12:34:57 eth0 STALE",
+		"10.8.0.1 02:42:ac:11:00:02 docker0 DELAY",
+		"fe80::1%eth0 00:0c:29:aa:bb:01 eth0 REACHABLE",
+		"2001:db8::10 00:0c:29:aa:bb:03 eth0 PERMANENT",
+		"ff02::1 33:33:00:00:00:01 eth0 NOARP",
+	}
+	if got := formatNeighbors(entries); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestNeighborTableFromFile(t *testing.T) {
+	procFile := filepath.Join("testdata", "neighbors", "proc_net_arp")
+	ipFile := filepath.Join("testdata", "neighbors", "ip_neigh")
+	tests := []struct {
+		name    string
+		opts    ARPScanOptions
+		ipv6    bool
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "proc format, all IPv4",
+			opts: ARPScanOptions{NeighborFile: procFile},
+			want: []string{
+				"10.8.0.1 02:42:ac:11:00:02 docker0 COMPLETE",
+				"192.168.1.1 00:0c:29:aa:bb:01 eth0 COMPLETE",
+				"192.168.1.20 - eth0 INCOMPLETE",
+				"192.168.1.30 b8:27:eb:12:34:56 eth0 PERMANENT duplicate",
+				"192.168.1.30 00:0c:29:aa:bb:02 eth0 COMPLETE duplicate",
+			},
+		},
+		{
+			name: "interface filter",
+			opts: ARPScanOptions{NeighborFile: ipFile, Interface: "docker0"},
+			want: []string{"10.8.0.1 02:42:ac:11:00:02 docker0 DELAY"},
+		},
+		{
+			name: "CIDR filter",
+			opts: ARPScanOptions{NeighborFile: ipFile, CIDR: "192.168.1.0/27"},
+			want: []string{
+				"192.168.1.1 00:0c:29:aa:bb:01 eth0 REACHABLE",
+				"192.168.1.20 - eth0 FAILED",
+			},
+		},
+		{
+			name: "IPv6 without multicast",
+			opts: ARPScanOptions{NeighborFile: ipFile},
+			ipv6: true,
+			want: []string{
+				"2001:db8::10 00:0c:29:aa:bb:03 eth0 PERMANENT",
+				"fe80::1%eth0 00:0c:29:aa:bb:01 eth0 REACHABLE",
+			},
+		},
+		{
+			name: "IPv6 CIDR matches scoped addresses",
+			opts: ARPScanOptions{NeighborFile: ipFile, CIDR: "fe80::/64"},
+			ipv6: true,
+			want: []string{"fe80::1%eth0 00:0c:29:aa:bb:01 eth0 REACHABLE"},
+		},
+		{name: "invalid CIDR", opts: ARPScanOptions{NeighborFile: ipFile, CIDR: "192.168.1.0"}, wantErr: true},
+		{name: "missing file", opts: ARPScanOptions{NeighborFile: filepath.Join("testdata", "missing")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := neighborTable(tt.opts, tt.ipv6)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got := formatNeighbors(entries); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
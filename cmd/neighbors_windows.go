@@ -0,0 +1,66 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// readNeighborTable reads the IPv4 and IPv6 neighbor caches with
+// 'netsh interface ipv4|ipv6 show neighbors', which, unlike 'arp -a', reports each entry's state.
+func readNeighborTable() ([]ARPResult, error) {
+	var results []ARPResult
+	for _, family := range []string{"ipv4", "ipv6"} {
+		cmd := exec.Command("netsh", "interface", family, "show", "neighbors")
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("error running netsh: %w", err)
+		}
+		results = append(results, parseNetshNeighbors(string(output))...)
+	}
+	return results, nil
+}
+
+// parseNetshNeighbors parses the output of 'netsh interface ipv4 show neighbors':
+//
+//	Interface 12: Ethernet
+//
+//	Internet Address                              Physical Address   Type
+//	--------------------------------------------  -----------------  -----------
+//	192.168.1.1                                   00-11-22-33-44-55  Reachable
+//	192.168.1.9                                                      Unreachable
+func parseNetshNeighbors(output string) []ARPResult {
+	var results []ARPResult
+	currentInterface := ""
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Interface ") {
+			if _, name, ok := strings.Cut(line, ": "); ok {
+				currentInterface = strings.TrimSpace(name)
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || net.ParseIP(strings.SplitN(fields[0], "%", 2)[0]) == nil {
+			continue
+		}
+		result := ARPResult{IPAddress: fields[0], Interface: currentInterface}
+		state := fields[1:]
+		if _, err := net.ParseMAC(fields[1]); err == nil {
+			result.MACAddress = strings.ReplaceAll(strings.ToLower(fields[1]), "-", ":")
+			state = fields[2:]
+		}
+		if len(state) > 0 {
+			result.State = strings.ToUpper(state[0])
+		}
+		if result.MACAddress == "00:00:00:00:00:00" {
+			result.MACAddress = ""
+		}
+		results = append(results, result)
+	}
+	return results
+}
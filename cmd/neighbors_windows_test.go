@@ -0,0 +1,27 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseNetshNeighbors(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "neighbors", "netsh_neighbors"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"192.168.1.1 00:0c:29:aa:bb:01 Ethernet REACHABLE",
+		"192.168.1.9 - Ethernet UNREACHABLE",
+		"192.168.1.255 ff:ff:ff:ff:ff:ff Ethernet PERMANENT",
+		"224.0.0.22 - Loopback Pseudo-Interface 1 PERMANENT",
+	}
+	if got := formatNeighbors(parseNetshNeighbors(string(data))); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
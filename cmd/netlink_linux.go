@@ -0,0 +1,100 @@
+//go:build linux
+// +build linux
+
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// netlinkMessage is one message of a netlink dump: its type, the fixed header that follows
+// the netlink header (ndmsg, rtmsg, ...) and its attributes keyed by type.
+type netlinkMessage struct {
+	Type   uint16
+	Header []byte
+	Attrs  map[uint16][]byte
+}
+
+// netlinkDump requests a dump of the routing-family table selected by proto (RTM_GETNEIGH,
+// RTM_GETROUTE, RTM_GETRULE, ...) for the address family and returns the messages of type
+// want. headerLen is the size of the fixed header preceding the attributes.
+func netlinkDump(proto, family int, want uint16, headerLen int) ([]netlinkMessage, error) {
+	data, err := syscall.NetlinkRIB(proto, family)
+	if err != nil {
+		return nil, fmt.Errorf("netlink dump: %w", err)
+	}
+	return parseNetlinkDump(data, want, headerLen)
+}
+
+// parseNetlinkDump splits the raw bytes of a netlink dump into messages of type want.
+func parseNetlinkDump(data []byte, want uint16, headerLen int) ([]netlinkMessage, error) {
+	msgs, err := syscall.ParseNetlinkMessage(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing netlink messages: %w", err)
+	}
+	var result []netlinkMessage
+	for _, m := range msgs {
+		if m.Header.Type == syscall.NLMSG_DONE {
+			break
+		}
+		if m.Header.Type == syscall.NLMSG_ERROR {
+			if len(m.Data) >= 4 {
+				if errno := int32(binary.NativeEndian.Uint32(m.Data[:4])); errno < 0 {
+					return nil, fmt.Errorf("netlink: %w", syscall.Errno(-errno))
+				}
+			}
+			continue
+		}
+		if m.Header.Type != want || len(m.Data) < headerLen {
+			continue
+		}
+		result = append(result, netlinkMessage{
+			Type:   m.Header.Type,
+			Header: m.Data[:headerLen],
+			Attrs:  parseNetlinkAttrs(m.Data[netlinkAlign(headerLen):]),
+		})
+	}
+	return result, nil
+}
+
+// parseNetlinkAttrs decodes a run of rtattr structures (2-byte length, 2-byte type, payload).
+func parseNetlinkAttrs(b []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(b) >= 4 {
+		length := int(binary.NativeEndian.Uint16(b[0:2]))
+		attrType := binary.NativeEndian.Uint16(b[2:4])
+		if length < 4 || length > len(b) {
+			break
+		}
+		attrs[attrType&^0x8000] = b[4:length] // Clear NLA_F_NESTED
+		b = b[min(netlinkAlign(length), len(b)):]
+	}
+	return attrs
+}
+
+// netlinkAlign rounds n up to the 4-byte alignment netlink uses for headers and attributes.
+func netlinkAlign(n int) int {
+	return (n + 3) &^ 3
+}
+
+// netlinkUint32 decodes a native-endian 32-bit attribute, returning 0 when it is missing.
+func netlinkUint32(attrs map[uint16][]byte, attrType uint16) uint32 {
+	if b := attrs[attrType]; len(b) >= 4 {
+		return binary.NativeEndian.Uint32(b)
+	}
+	return 0
+}
+
+// netlinkInterfaceName resolves an interface index to its name, falling back to the number.
+func netlinkInterfaceName(index int) string {
+	if index == 0 {
+		return ""
+	}
+	if iface, err := net.InterfaceByIndex(index); err == nil {
+		return iface.Name
+	}
+	return fmt.Sprintf("if%d", index)
+}
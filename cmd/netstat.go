@@ -3,10 +3,19 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"net/netip"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/mwiater/ghost/utils"
 	"github.com/shirou/gopsutil/net"
+	"github.com/shirou/gopsutil/process"
 	"github.com/spf13/cobra"
 )
 
@@ -15,11 +24,102 @@ import (
 var NetstatCmd = &cobra.Command{
 	Use:   "netstat",
 	Short: "Displays active network connections on the system",
+	Long: `Displays the active TCP, UDP and Unix sockets of the system with the PID, name and user of the
+owning process. The protocol column tells IPv4 (TCP4, UDP4) from IPv6 (TCP6, UDP6) sockets.
+
+The list can be narrowed down with filters, which all have to match: listening sockets only,
+states, protocols, local or remote ports, PIDs, process names and remote networks, e.g.
+
+  ghost netstat --listen --proto tcp
+  ghost netstat --state established --remote 10.0.0.0/8 --sort process
+  ghost netstat --port 443,8000-8080 --process nginx
+
+--proto unix on its own lists the Unix domain sockets of /proc/net/unix (Linux) instead: the
+path (or @name for abstract sockets), type (stream, dgram, seqpacket), state, inode and the
+owning process, e.g. to see which process serves docker.sock.
+
+--summary aggregates the (filtered) connections instead of listing them: counts by TCP state,
+the top remote hosts and remote ports, sockets per process and the listening sockets, split
+into those exposed on all interfaces and those on loopback only. --resolve adds the reverse
+DNS names of the top remote hosts, looked up concurrently within --resolve-timeout.
+
+--follow polls the TCP and UDP connections every --interval until Ctrl-C and reports the ones
+that were opened or closed in between, with the owning process and, for closed connections,
+the observed duration: as a live table on a terminal, or one event per line (JSON lines with
+--json). The filters apply to the stream; a connection that stops matching them, or enters a
+closing state such as TIME_WAIT, counts as closed.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		connections, err := RunNetstat()
+		filter, err := netstatFilterFromFlags(cmd)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		sortKey, _ := cmd.Flags().GetString("sort")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		summaryOutput, _ := cmd.Flags().GetBool("summary")
+		summaryOpts := NetstatSummaryOptions{}
+		summaryOpts.Top, _ = cmd.Flags().GetInt("top")
+		summaryOpts.Resolve, _ = cmd.Flags().GetBool("resolve")
+		summaryOpts.ResolveTimeout, _ = cmd.Flags().GetDuration("resolve-timeout")
+
+		if follow, _ := cmd.Flags().GetBool("follow"); follow {
+			if summaryOutput {
+				fmt.Println("Error: --follow and --summary cannot be combined")
+				os.Exit(1)
+			}
+			if containsString(filter.Protocols, "unix") {
+				fmt.Println("Error: --follow only follows TCP and UDP connections, not --proto unix")
+				os.Exit(1)
+			}
+			interval, _ := cmd.Flags().GetDuration("interval")
+			runNetstatFollow(NetstatFollowOptions{Interval: interval, Filter: filter}, jsonOutput)
+			return
+		}
+
+		if len(filter.Protocols) == 1 && filter.Protocols[0] == "unix" && !summaryOutput {
+			sockets, err := GetUnixSockets()
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			sockets = FilterUnixSockets(sockets, filter)
+			if err := SortUnixSockets(sockets, sortKey); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			if jsonOutput {
+				utils.PrintJSON(sockets)
+				return
+			}
+			PrintUnixSockets(sockets)
+			return
+		}
+
+		connections, err := GetConnections()
 		if err != nil {
 			log.Fatalf("Error fetching network connections: %v", err)
 		}
+		connections = FilterConnections(connections, filter)
+
+		if summaryOutput {
+			summary := SummarizeConnections(connections, summaryOpts)
+			if jsonOutput {
+				utils.PrintJSON(summary)
+				return
+			}
+			PrintConnectionSummary(summary)
+			return
+		}
+
+		if err := SortConnections(connections, sortKey); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			utils.PrintJSON(connections)
+			return
+		}
 		PrintConnections(connections)
 	},
 }
@@ -27,6 +127,63 @@ var NetstatCmd = &cobra.Command{
 // init registers the NetstatCmd with the root command when this package is imported.
 func init() {
 	RootCmd.AddCommand(NetstatCmd)
+	NetstatCmd.Flags().BoolP("listen", "l", false, "Show listening sockets only (TCP LISTEN and unconnected UDP)")
+	NetstatCmd.Flags().StringSlice("state", nil, "Show sockets in these states only, e.g. established,time_wait")
+	NetstatCmd.Flags().StringSlice("proto", nil, "Show these protocols only: tcp, udp, unix, tcp4, tcp6, udp4 or udp6")
+	NetstatCmd.Flags().String("port", "", "Show sockets with a local or remote port in this list, e.g. 22,8000-8080")
+	NetstatCmd.Flags().Int32Slice("pid", nil, "Show sockets of these process IDs only")
+	NetstatCmd.Flags().String("process", "", "Show sockets of processes whose name contains this text (case-insensitive)")
+	NetstatCmd.Flags().StringSlice("remote", nil, "Show connections to these remote networks or addresses only, e.g. 10.0.0.0/8")
+	NetstatCmd.Flags().String("sort", "proto", "Sort by proto, local, remote, port, state, pid, process or user")
+	NetstatCmd.Flags().Bool("summary", false, "Show aggregated counts instead of the connection list")
+	NetstatCmd.Flags().Int("top", 10, "Entries in the top remote hosts, ports and processes of --summary")
+	NetstatCmd.Flags().Bool("resolve", false, "Look up the reverse DNS names of the top remote hosts of --summary")
+	NetstatCmd.Flags().Duration("resolve-timeout", 2*time.Second, "Time allowed for all reverse DNS lookups together")
+	NetstatCmd.Flags().BoolP("follow", "f", false, "Report connections as they are opened and closed until Ctrl-C")
+	NetstatCmd.Flags().Duration("interval", time.Second, "Time between two polls of --follow")
+	NetstatCmd.Flags().Bool("json", false, "Print the connections as JSON (one event per line with --follow)")
+}
+
+// Connection is a socket together with the process that owns it.
+type Connection struct {
+	Protocol      string `json:"protocol"`     // TCP4, TCP6, UDP4, UDP6 or UNIX
+	LocalAddress  string `json:"localAddress"` // IP address, or the path of a Unix socket
+	LocalPort     uint32 `json:"localPort"`
+	RemoteAddress string `json:"remoteAddress,omitempty"` // Empty for listening and unconnected sockets
+	RemotePort    uint32 `json:"remotePort,omitempty"`
+	State         string `json:"state"`             // TCP state, e.g. ESTABLISHED; NONE for UDP
+	PID           int32  `json:"pid,omitempty"`     // 0 when the owner is unknown
+	Process       string `json:"process,omitempty"` // Name of the owning process
+	User          string `json:"user,omitempty"`    // User the owning process runs as
+}
+
+// Local returns the local endpoint in host:port form, or the path of a Unix socket.
+func (c Connection) Local() string {
+	return connectionEndpoint(c.Protocol, c.LocalAddress, c.LocalPort)
+}
+
+// Remote returns the remote endpoint in host:port form, or "" when there is none.
+func (c Connection) Remote() string {
+	if c.RemoteAddress == "" {
+		return ""
+	}
+	return connectionEndpoint(c.Protocol, c.RemoteAddress, c.RemotePort)
+}
+
+// connectionEndpoint joins an address and port, bracketing IPv6 addresses.
+func connectionEndpoint(protocol, address string, port uint32) string {
+	if protocol == "UNIX" {
+		return address
+	}
+	return fmt.Sprintf("%s:%d", bracketIPv6(address), port)
+}
+
+// bracketIPv6 puts brackets around IPv6 addresses, as used in host:port.
+func bracketIPv6(address string) string {
+	if strings.Contains(address, ":") {
+		return "[" + address + "]"
+	}
+	return address
 }
 
 // RunNetstat retrieves all active network connections without printing.
@@ -34,45 +191,374 @@ func RunNetstat() ([]net.ConnectionStat, error) {
 	return net.Connections("all")
 }
 
+// GetConnections retrieves the active network connections with the name and user of the
+// owning processes.
+func GetConnections() ([]Connection, error) {
+	stats, err := RunNetstat()
+	if err != nil {
+		return nil, err
+	}
+	owners := newProcessOwners()
+	connections := make([]Connection, 0, len(stats))
+	for _, stat := range stats {
+		connections = append(connections, owners.connection(stat))
+	}
+	return connections, nil
+}
+
+// processOwners looks up and caches the name and user of processes by PID.
+type processOwners struct {
+	names map[int32]string
+	users map[int32]string
+	uids  map[int32]string
+}
+
+// newProcessOwners returns an empty process cache.
+func newProcessOwners() *processOwners {
+	return &processOwners{
+		names: make(map[int32]string),
+		users: make(map[int32]string),
+		uids:  make(map[int32]string),
+	}
+}
+
+// connection converts a gopsutil connection, adding the owning process.
+func (o *processOwners) connection(stat net.ConnectionStat) Connection {
+	c := Connection{
+		Protocol:     mapProtocol(stat.Family, stat.Type),
+		LocalAddress: stat.Laddr.IP,
+		LocalPort:    stat.Laddr.Port,
+		State:        stat.Status,
+		PID:          stat.Pid,
+	}
+	if stat.Raddr.IP != "" && !(stat.Raddr.Port == 0 && isUnspecifiedAddress(stat.Raddr.IP)) {
+		c.RemoteAddress = stat.Raddr.IP
+		c.RemotePort = stat.Raddr.Port
+	}
+	if c.PID > 0 {
+		c.Process = o.name(c.PID)
+		if len(stat.Uids) > 0 {
+			c.User = o.uidName(stat.Uids[0])
+		} else {
+			c.User = o.user(c.PID)
+		}
+	}
+	return c
+}
+
+// name returns the name of a process, or "" when it has exited.
+func (o *processOwners) name(pid int32) string {
+	name, ok := o.names[pid]
+	if !ok {
+		if p, err := process.NewProcess(pid); err == nil {
+			name, _ = p.Name()
+		}
+		o.names[pid] = name
+	}
+	return name
+}
+
+// user returns the user a process runs as, for platforms that do not report its UIDs.
+func (o *processOwners) user(pid int32) string {
+	username, ok := o.users[pid]
+	if !ok {
+		if p, err := process.NewProcess(pid); err == nil {
+			username, _ = p.Username()
+		}
+		o.users[pid] = username
+	}
+	return username
+}
+
+// uidName resolves a user ID to its name, falling back to the number.
+func (o *processOwners) uidName(uid int32) string {
+	name, ok := o.uids[uid]
+	if !ok {
+		name = strconv.Itoa(int(uid))
+		if u, err := user.LookupId(name); err == nil {
+			name = u.Username
+		}
+		o.uids[uid] = name
+	}
+	return name
+}
+
+// isUnspecifiedAddress reports whether address is 0.0.0.0 or ::, as reported for the remote end
+// of listening sockets.
+func isUnspecifiedAddress(address string) bool {
+	addr, err := netip.ParseAddr(address)
+	return err == nil && addr.IsUnspecified()
+}
+
+// NetstatFilter selects connections; every field that is set has to match.
+type NetstatFilter struct {
+	Listen    bool           // Listening sockets only: TCP LISTEN and unconnected UDP
+	States    []string       // Socket states, e.g. ESTABLISHED
+	Protocols []string       // tcp, udp, unix, tcp4, tcp6, udp4 or udp6
+	Ports     []PortRange    // Local or remote port
+	PIDs      []int32        // Owning process IDs
+	Process   string         // Text contained in the owning process name, case-insensitive
+	Remote    []netip.Prefix // Networks the remote address is in
+}
+
+// netstatProtocols lists the values accepted by NetstatFilter.Protocols.
+var netstatProtocols = []string{"tcp", "udp", "unix", "tcp4", "tcp6", "udp4", "udp6"}
+
+// netstatFilterFromFlags builds a NetstatFilter from the filter flags of the netstat command.
+func netstatFilterFromFlags(cmd *cobra.Command) (NetstatFilter, error) {
+	var filter NetstatFilter
+	filter.Listen, _ = cmd.Flags().GetBool("listen")
+	filter.PIDs, _ = cmd.Flags().GetInt32Slice("pid")
+	filter.Process, _ = cmd.Flags().GetString("process")
+
+	states, _ := cmd.Flags().GetStringSlice("state")
+	for _, state := range states {
+		filter.States = append(filter.States, normalizeSocketState(state))
+	}
+
+	protocols, _ := cmd.Flags().GetStringSlice("proto")
+	for _, protocol := range protocols {
+		protocol = strings.ToLower(strings.TrimSpace(protocol))
+		valid := false
+		for _, known := range netstatProtocols {
+			valid = valid || protocol == known
+		}
+		if !valid {
+			return filter, fmt.Errorf("invalid protocol %q (use %s)", protocol, strings.Join(netstatProtocols, ", "))
+		}
+		filter.Protocols = append(filter.Protocols, protocol)
+	}
+
+	if ports, _ := cmd.Flags().GetString("port"); ports != "" {
+		ranges, err := ParsePortRanges(ports)
+		if err != nil {
+			return filter, err
+		}
+		filter.Ports = ranges
+	}
+
+	remotes, _ := cmd.Flags().GetStringSlice("remote")
+	for _, remote := range remotes {
+		prefix, err := netip.ParsePrefix(remote)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(remote)
+			if addrErr != nil {
+				return filter, fmt.Errorf("invalid remote network %q", remote)
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		filter.Remote = append(filter.Remote, prefix.Masked())
+	}
+	return filter, nil
+}
+
+// normalizeSocketState converts a state as typed by users, e.g. "time-wait", to the form the
+// connection list uses, e.g. "TIME_WAIT".
+func normalizeSocketState(state string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(state), "-", "_"))
+}
+
+// Match reports whether a connection passes the filter.
+func (f NetstatFilter) Match(c Connection) bool {
+	if f.Listen && !c.IsListening() {
+		return false
+	}
+	if len(f.States) > 0 && !containsString(f.States, normalizeSocketState(c.State)) {
+		return false
+	}
+	if len(f.Protocols) > 0 {
+		protocol := strings.ToLower(c.Protocol)
+		family := strings.TrimRight(protocol, "46")
+		if !containsString(f.Protocols, protocol) && !containsString(f.Protocols, family) {
+			return false
+		}
+	}
+	if len(f.Ports) > 0 {
+		match := false
+		for _, r := range f.Ports {
+			match = match || (c.LocalPort >= r.First && c.LocalPort <= r.Last) ||
+				(c.RemoteAddress != "" && c.RemotePort >= r.First && c.RemotePort <= r.Last)
+		}
+		if !match {
+			return false
+		}
+	}
+	if len(f.PIDs) > 0 {
+		match := false
+		for _, pid := range f.PIDs {
+			match = match || c.PID == pid
+		}
+		if !match {
+			return false
+		}
+	}
+	if f.Process != "" && !strings.Contains(strings.ToLower(c.Process), strings.ToLower(f.Process)) {
+		return false
+	}
+	if len(f.Remote) > 0 {
+		addr, err := netip.ParseAddr(c.RemoteAddress)
+		if err != nil {
+			return false
+		}
+		addr = addr.WithZone("").Unmap()
+		match := false
+		for _, prefix := range f.Remote {
+			match = match || prefix.Contains(addr)
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+// IsListening reports whether the socket waits for connections: a TCP socket in the LISTEN
+// state, an unconnected UDP socket or a listening Unix socket.
+func (c Connection) IsListening() bool {
+	switch {
+	case strings.HasPrefix(c.Protocol, "TCP"):
+		return c.State == "LISTEN"
+	case strings.HasPrefix(c.Protocol, "UDP"):
+		return c.RemoteAddress == ""
+	}
+	return c.State == "LISTEN" || c.State == "LISTENING"
+}
+
+// containsString reports whether list contains value.
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterConnections returns the connections that pass the filter.
+func FilterConnections(conns []Connection, filter NetstatFilter) []Connection {
+	var filtered []Connection
+	for _, c := range conns {
+		if filter.Match(c) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// netstatSortKeys compares two connections for each --sort key.
+var netstatSortKeys = map[string]func(a, b Connection) int{
+	"proto": func(a, b Connection) int { return strings.Compare(a.Protocol, b.Protocol) },
+	"local": func(a, b Connection) int {
+		return compareEndpoints(a.LocalAddress, a.LocalPort, b.LocalAddress, b.LocalPort)
+	},
+	"remote": func(a, b Connection) int {
+		return compareEndpoints(a.RemoteAddress, a.RemotePort, b.RemoteAddress, b.RemotePort)
+	},
+	"port":  func(a, b Connection) int { return int(a.LocalPort) - int(b.LocalPort) },
+	"state": func(a, b Connection) int { return strings.Compare(a.State, b.State) },
+	"pid":   func(a, b Connection) int { return int(a.PID) - int(b.PID) },
+	"process": func(a, b Connection) int {
+		return strings.Compare(strings.ToLower(a.Process), strings.ToLower(b.Process))
+	},
+	"user": func(a, b Connection) int { return strings.Compare(a.User, b.User) },
+}
+
+// SortConnections sorts connections in place by a key of netstatSortKeys; ties are ordered by
+// protocol and local endpoint.
+func SortConnections(conns []Connection, key string) error {
+	compare, ok := netstatSortKeys[strings.ToLower(key)]
+	if !ok {
+		return fmt.Errorf("invalid sort key %q (use proto, local, remote, port, state, pid, process or user)", key)
+	}
+	sort.SliceStable(conns, func(i, j int) bool {
+		a, b := conns[i], conns[j]
+		for _, cmp := range []func(a, b Connection) int{compare, netstatSortKeys["proto"], netstatSortKeys["local"]} {
+			if c := cmp(a, b); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// compareEndpoints orders endpoints by address, numerically for IP addresses, then by port.
+// Empty addresses sort last.
+func compareEndpoints(addrA string, portA uint32, addrB string, portB uint32) int {
+	if (addrA == "") != (addrB == "") {
+		if addrA == "" {
+			return 1
+		}
+		return -1
+	}
+	a, errA := netip.ParseAddr(addrA)
+	b, errB := netip.ParseAddr(addrB)
+	c := strings.Compare(addrA, addrB)
+	if errA == nil && errB == nil {
+		c = a.Compare(b)
+	}
+	if c != 0 {
+		return c
+	}
+	return int(portA) - int(portB)
+}
+
 // PrintConnections formats and displays the network connection information.
-// It presents connection details including protocol, local address, remote address, and state using the go-pretty table package.
-func PrintConnections(conns []net.ConnectionStat) {
+// It presents connection details including protocol, local address, remote address, state and
+// the owning process using the go-pretty table package.
+func PrintConnections(conns []Connection) {
 	// Create a new table using utils.Table function for consistent styling
 	t := utils.Table("DarkSimple", "Active Network Connections")
-	t.AppendHeader(table.Row{"Protocol", "Local Address", "Remote Address", "State"})
+	t.AppendHeader(table.Row{"Protocol", "Local Address", "Remote Address", "State", "PID", "Process", "User"})
 
 	for _, conn := range conns {
-		protocol := mapProtocol(conn.Type)
-		localAddr := fmt.Sprintf("%s:%d", conn.Laddr.IP, conn.Laddr.Port)
-		remoteAddr := fmt.Sprintf("%s:%d", conn.Raddr.IP, conn.Raddr.Port)
-		if conn.Raddr.IP == "" {
+		remoteAddr := conn.Remote()
+		if remoteAddr == "" {
 			remoteAddr = "N/A"
 		}
+		pid := "-"
+		if conn.PID > 0 {
+			pid = strconv.Itoa(int(conn.PID))
+		}
 
 		// Append each connection to the table
 		t.AppendRow(table.Row{
-			protocol,
-			localAddr,
+			conn.Protocol,
+			valueOrDash(conn.Local()),
 			remoteAddr,
-			conn.Status,
+			conn.State,
+			pid,
+			valueOrDash(conn.Process),
+			valueOrDash(conn.User),
 		})
 	}
 
 	// Render the table
 	fmt.Println()
 	t.Render()
-	fmt.Println("Netstat complete.")
+	fmt.Printf("Netstat complete: %d connection(s).\n", len(conns))
 }
 
-// mapProtocol converts a protocol type represented by an integer into a human-readable string.
-// It maps the protocol type to either "TCP", "UDP", or "UNKNOWN" based on the provided value.
-func mapProtocol(protocolType uint32) string {
-	switch protocolType {
-	case 1:
-		return "TCP"
-	case 2:
-		return "UDP"
+// mapProtocol converts the address family and socket type of a connection into a
+// human-readable protocol: TCP4, TCP6, UDP4, UDP6, UNIX or UNKNOWN.
+func mapProtocol(family, socketType uint32) string {
+	if family == syscall.AF_UNIX {
+		return "UNIX"
+	}
+	var protocol string
+	switch socketType {
+	case syscall.SOCK_STREAM:
+		protocol = "TCP"
+	case syscall.SOCK_DGRAM:
+		protocol = "UDP"
 	default:
 		return "UNKNOWN"
 	}
+	switch family {
+	case syscall.AF_INET:
+		return protocol + "4"
+	case syscall.AF_INET6:
+		return protocol + "6"
+	}
+	return protocol
 }
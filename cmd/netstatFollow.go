@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+)
+
+// Kinds of ConnectionEvent.
+const (
+	ConnectionOpened = "opened"
+	ConnectionClosed = "closed"
+)
+
+// netstatFollowHistory is the number of events the live display keeps on screen.
+const netstatFollowHistory = 20
+
+// closingStates are TCP states of connections that have been shut down; they count as closed
+// even while the kernel still lists them, e.g. for the minute of TIME_WAIT.
+var closingStates = map[string]bool{
+	"FIN_WAIT1": true, "FIN_WAIT2": true, "TIME_WAIT": true, "CLOSE": true, "CLOSED": true,
+	"CLOSING": true, "LAST_ACK": true,
+}
+
+// ConnectionEvent reports a connection that appeared or went away between two polls.
+type ConnectionEvent struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"` // ConnectionOpened or ConnectionClosed
+	Connection
+	FirstSeen time.Time     `json:"firstSeen"`
+	Duration  time.Duration `json:"duration,omitempty"` // Observed lifetime, for closed connections
+	Partial   bool          `json:"partial,omitempty"`  // Open before following started, so Duration is a lower bound
+}
+
+// NetstatFollowOptions controls FollowConnections.
+type NetstatFollowOptions struct {
+	Interval time.Duration         // Time between two polls of the connection list
+	Filter   NetstatFilter         // Connections to follow; one that stops matching counts as closed
+	OnEvent  func(ConnectionEvent) // Called for every opened and closed connection
+	OnPoll   func(open int)        // Called after every poll with the number of open connections, e.g. to refresh a live display
+}
+
+// trackedConnection is a connection seen in the previous poll.
+type trackedConnection struct {
+	Connection
+	firstSeen time.Time
+	partial   bool
+}
+
+// FollowConnections polls the TCP and UDP connections every opts.Interval until ctx is canceled
+// and reports connections that were opened or closed in between, so that short-lived
+// connections a single netstat misses still show up. Connections already open at the start are
+// not reported as opened; when they close, their duration counts from the start. Only the
+// polls are observed, so connections shorter than the interval can be missed.
+func FollowConnections(ctx context.Context, opts NetstatFollowOptions) error {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if containsString(opts.Filter.Protocols, "unix") {
+		return fmt.Errorf("only TCP and UDP connections can be followed, not Unix sockets")
+	}
+
+	var tracked map[string]trackedConnection
+	for {
+		pollStart := time.Now()
+		connections, err := GetConnections()
+		if err != nil {
+			return err
+		}
+
+		current := make(map[string]trackedConnection)
+		for _, c := range FilterConnections(connections, opts.Filter) {
+			if c.Protocol == "UNIX" || closingStates[c.State] {
+				continue
+			}
+			key := followKey(c)
+			if _, dup := current[key]; dup {
+				continue
+			}
+			if previous, ok := tracked[key]; ok {
+				// Keep what was learned earlier, e.g. the process, which may no longer be known
+				if c.PID == 0 {
+					c.PID, c.Process, c.User = previous.PID, previous.Process, previous.User
+				}
+				current[key] = trackedConnection{Connection: c, firstSeen: previous.firstSeen, partial: previous.partial}
+				continue
+			}
+			current[key] = trackedConnection{Connection: c, firstSeen: pollStart, partial: tracked == nil}
+			if tracked != nil && opts.OnEvent != nil {
+				opts.OnEvent(ConnectionEvent{Time: pollStart, Event: ConnectionOpened, Connection: c, FirstSeen: pollStart})
+			}
+		}
+
+		if opts.OnEvent != nil {
+			for _, event := range closedConnectionEvents(tracked, current, pollStart) {
+				opts.OnEvent(event)
+			}
+		}
+		tracked = current
+		if opts.OnPoll != nil {
+			opts.OnPoll(len(tracked))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(pollStart.Add(opts.Interval))):
+		}
+	}
+}
+
+// followKey identifies a connection across polls.
+func followKey(c Connection) string {
+	return c.Protocol + " " + c.Local() + " " + c.Remote()
+}
+
+// closedConnectionEvents reports the tracked connections that are missing from current, ordered
+// by local endpoint like a netstat listing rather than in map order.
+func closedConnectionEvents(tracked, current map[string]trackedConnection, now time.Time) []ConnectionEvent {
+	var keys []string
+	for key := range tracked {
+		if _, open := current[key]; !open {
+			keys = append(keys, key)
+		}
+	}
+	// Sorted keys break the ties of the stable sort, e.g. between clients of the same listener
+	sort.Strings(keys)
+	closed := make([]Connection, 0, len(keys))
+	for _, key := range keys {
+		closed = append(closed, tracked[key].Connection)
+	}
+	SortConnections(closed, "local")
+
+	events := make([]ConnectionEvent, 0, len(closed))
+	for _, c := range closed {
+		previous := tracked[followKey(c)]
+		events = append(events, ConnectionEvent{
+			Time:       now,
+			Event:      ConnectionClosed,
+			Connection: previous.Connection,
+			FirstSeen:  previous.firstSeen,
+			Duration:   now.Sub(previous.firstSeen),
+			Partial:    previous.partial,
+		})
+	}
+	return events
+}
+
+// formatEventDuration formats the observed duration of a closed connection; durations of
+// connections that were open before following started are lower bounds.
+func formatEventDuration(event ConnectionEvent) string {
+	if event.Event != ConnectionClosed {
+		return "-"
+	}
+	duration := event.Duration.Round(time.Second).String()
+	if event.Duration < time.Second {
+		duration = event.Duration.Round(time.Millisecond).String()
+	}
+	if event.Partial {
+		return ">" + duration
+	}
+	return duration
+}
+
+// PrintConnectionEvent displays one event on a single line.
+func PrintConnectionEvent(event ConnectionEvent) {
+	remote := event.Remote()
+	if remote == "" {
+		remote = "N/A"
+	}
+	line := fmt.Sprintf("[%s] %-6s %-4s %s -> %s %s", event.Time.Format("15:04:05"), event.Event, event.Protocol, event.Local(), remote, event.State)
+	if event.PID > 0 {
+		line += fmt.Sprintf(" pid %d (%s)", event.PID, valueOrDash(event.Process))
+	}
+	if event.Event == ConnectionClosed {
+		line += " after " + formatEventDuration(event)
+	}
+	fmt.Println(line)
+}
+
+// PrintConnectionEvents displays the most recent events in a formatted table.
+func PrintConnectionEvents(events []ConnectionEvent) {
+	t := utils.Table("DarkSimple", "Connection Events")
+	t.AppendHeader(table.Row{"Time", "Event", "Protocol", "Local Address", "Remote Address", "State", "PID", "Process", "Duration"})
+	for _, event := range events {
+		pid := "-"
+		if event.PID > 0 {
+			pid = strconv.Itoa(int(event.PID))
+		}
+		remote := event.Remote()
+		if remote == "" {
+			remote = "N/A"
+		}
+		t.AppendRow(table.Row{
+			event.Time.Format("15:04:05"),
+			event.Event,
+			event.Protocol,
+			event.Local(),
+			remote,
+			event.State,
+			pid,
+			valueOrDash(event.Process),
+			formatEventDuration(event),
+		})
+	}
+	fmt.Println()
+	t.Render()
+}
+
+// runNetstatFollow follows connections for the command line until the user presses Ctrl-C: as
+// a table of recent events redrawn after every poll on a terminal, as one JSON object per line
+// with jsonOutput, and otherwise as one line per event.
+func runNetstatFollow(opts NetstatFollowOptions, jsonOutput bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	live := !jsonOutput && utils.IsTerminal()
+	started := time.Now()
+	var recent []ConnectionEvent
+	opened, closed := 0, 0
+	opts.OnEvent = func(event ConnectionEvent) {
+		if event.Event == ConnectionOpened {
+			opened++
+		} else {
+			closed++
+		}
+		switch {
+		case jsonOutput:
+			// Printed as each poll finds it, so the stream can be piped into jq while it runs
+			data, _ := json.Marshal(event)
+			fmt.Println(string(data))
+		case live:
+			recent = append(recent, event)
+			if len(recent) > netstatFollowHistory {
+				recent = recent[len(recent)-netstatFollowHistory:]
+			}
+		default:
+			PrintConnectionEvent(event)
+		}
+	}
+	if live {
+		opts.OnPoll = func(open int) {
+			utils.ClearTerminal()
+			PrintConnectionEvents(recent)
+			fmt.Printf("%d connection(s) open; %d opened and %d closed since %s. Press Ctrl-C to stop.\n",
+				open, opened, closed, started.Format("15:04:05"))
+		}
+	} else if !jsonOutput {
+		fmt.Printf("Following connections every %s. Press Ctrl-C to stop.\n", opts.Interval)
+	}
+
+	if err := FollowConnections(ctx, opts); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
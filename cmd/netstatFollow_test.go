@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClosedConnectionEvents(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start.Add(90 * time.Second)
+	conns := []trackedConnection{
+		{Connection: Connection{Protocol: "TCP4", LocalAddress: "10.0.0.10", LocalPort: 443, RemoteAddress: "198.51.100.7", RemotePort: 50000}, firstSeen: start},
+		{Connection: Connection{Protocol: "TCP4", LocalAddress: "10.0.0.10", LocalPort: 443, RemoteAddress: "198.51.100.2", RemotePort: 50000}, firstSeen: start.Add(time.Minute)},
+		{Connection: Connection{Protocol: "TCP4", LocalAddress: "10.0.0.9", LocalPort: 41000, RemoteAddress: "203.0.113.5", RemotePort: 22}, firstSeen: start, partial: true},
+		{Connection: Connection{Protocol: "UDP4", LocalAddress: "10.0.0.9", LocalPort: 41000}, firstSeen: start},
+		{Connection: Connection{Protocol: "TCP4", LocalAddress: "10.0.0.1", LocalPort: 80}, firstSeen: start},
+	}
+	tracked := make(map[string]trackedConnection)
+	for _, c := range conns {
+		tracked[followKey(c.Connection)] = c
+	}
+	current := map[string]trackedConnection{followKey(conns[4].Connection): conns[4]}
+
+	// Map order differs from run to run; the events must not
+	for run := 0; run < 20; run++ {
+		events := closedConnectionEvents(tracked, current, now)
+		var got []string
+		for _, event := range events {
+			got = append(got, strings.TrimSpace(event.Protocol+" "+event.Local()+" "+event.Remote()))
+		}
+		want := []string{
+			"TCP4 10.0.0.9:41000 203.0.113.5:22",
+			"UDP4 10.0.0.9:41000",
+			"TCP4 10.0.0.10:443 198.51.100.2:50000",
+			"TCP4 10.0.0.10:443 198.51.100.7:50000",
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("closed events in order %q, want %q", got, want)
+		}
+		if events[0].Event != ConnectionClosed || !events[0].Partial || events[0].Duration != 90*time.Second || events[2].Duration != 30*time.Second {
+			t.Fatalf("events = %+v", events)
+		}
+	}
+	if events := closedConnectionEvents(nil, current, now); len(events) != 0 {
+		t.Errorf("the first poll reported %+v", events)
+	}
+}
+
+func TestFollowConnectionsLoopback(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+
+	events := make(chan ConnectionEvent, 16)
+	polled := make(chan struct{}, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- FollowConnections(ctx, NetstatFollowOptions{
+			Interval: 50 * time.Millisecond,
+			Filter:   NetstatFilter{Protocols: []string{"tcp4"}, Ports: []PortRange{{port, port}}},
+			OnEvent:  func(event ConnectionEvent) { events <- event },
+			OnPoll:   func(int) { polled <- struct{}{} },
+		})
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+	// The listener is open before the first poll, so it is not reported as opened
+	<-polled
+
+	client, err := net.Dial("tcp4", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints := map[string]bool{client.LocalAddr().String(): true, server.LocalAddr().String(): true}
+
+	// next waits for an event of one of the two ends of the connection
+	next := func(kind string) ConnectionEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			if event.Event != kind {
+				t.Fatalf("got %s event %+v while waiting for %s", event.Event, event, kind)
+			}
+			return event
+		case <-time.After(3 * time.Second):
+			t.Fatalf("no %s event", kind)
+		}
+		return ConnectionEvent{}
+	}
+	for i := 0; i < 2; i++ {
+		event := next(ConnectionOpened)
+		if !endpoints[event.Local()] || event.Partial {
+			t.Errorf("opened event %+v is not for the test connection", event)
+		}
+	}
+
+	client.Close()
+	server.Close()
+	for i := 0; i < 2; i++ {
+		event := next(ConnectionClosed)
+		if !endpoints[event.Local()] || event.Duration <= 0 || event.Partial {
+			t.Errorf("closed event %+v", event)
+		}
+	}
+}
+
+func TestFollowConnectionsRejectsUnix(t *testing.T) {
+	err := FollowConnections(context.Background(), NetstatFollowOptions{Filter: NetstatFilter{Protocols: []string{"tcp", "unix"}}})
+	if err == nil || !strings.Contains(err.Error(), "Unix sockets") {
+		t.Errorf("error = %v, want Unix sockets rejected", err)
+	}
+}
@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+)
+
+// Exposure of a listening socket.
+const (
+	ExposureAll      = "all interfaces" // Bound to 0.0.0.0 or ::
+	ExposureLoopback = "loopback only"  // Bound to 127.0.0.0/8 or ::1
+	ExposureAddress  = "one address"    // Bound to a single non-loopback address
+)
+
+// ConnectionCount is the number of connections sharing a key: a state, remote host, remote port
+// or process.
+type ConnectionCount struct {
+	Key      string `json:"key"`
+	Count    int    `json:"count"`
+	Hostname string `json:"hostname,omitempty"` // Reverse DNS name of a remote host, with --resolve
+	Service  string `json:"service,omitempty"`  // Well-known service of a remote port
+}
+
+// ListeningSocket is a socket waiting for connections and how far it is exposed.
+type ListeningSocket struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     uint32 `json:"port"`
+	Exposure string `json:"exposure"` // ExposureAll, ExposureLoopback or ExposureAddress
+	PID      int32  `json:"pid,omitempty"`
+	Process  string `json:"process,omitempty"`
+}
+
+// ConnectionSummary aggregates a list of connections.
+type ConnectionSummary struct {
+	Total       int               `json:"total"`
+	States      []ConnectionCount `json:"states"`      // TCP connections by state
+	RemoteHosts []ConnectionCount `json:"remoteHosts"` // Most frequent remote addresses
+	RemotePorts []ConnectionCount `json:"remotePorts"` // Most frequent remote ports of outgoing connections, e.g. 443/tcp
+	Processes   []ConnectionCount `json:"processes"`   // Sockets per process
+	Listening   []ListeningSocket `json:"listening"`   // Listening sockets, exposed ones first
+	Exposed     int               `json:"exposed"`     // Listening sockets bound to all interfaces
+	Loopback    int               `json:"loopback"`    // Listening sockets bound to loopback only
+}
+
+// NetstatSummaryOptions controls SummarizeConnections.
+type NetstatSummaryOptions struct {
+	Top            int           // Entries in the remote host, remote port and process lists
+	Resolve        bool          // Look up the reverse DNS names of the top remote hosts
+	ResolveTimeout time.Duration // Time allowed for all reverse lookups together
+	Concurrency    int           // Reverse lookups in flight at once
+}
+
+// withDefaults fills in unset options.
+func (o NetstatSummaryOptions) withDefaults() NetstatSummaryOptions {
+	if o.Top <= 0 {
+		o.Top = 10
+	}
+	if o.ResolveTimeout <= 0 {
+		o.ResolveTimeout = 2 * time.Second
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 16
+	}
+	return o
+}
+
+// SummarizeConnections aggregates connections, e.g. from GetConnections: counts by TCP state,
+// the top remote hosts and ports, sockets per process and the listening sockets with their
+// exposure. Remote ports only count connections this host opened, since the remote port of an
+// accepted connection is the client's ephemeral port.
+func SummarizeConnections(conns []Connection, opts NetstatSummaryOptions) ConnectionSummary {
+	opts = opts.withDefaults()
+	summary := ConnectionSummary{Total: len(conns)}
+
+	listeningPorts := make(map[string]bool)
+	for _, c := range conns {
+		if c.IsListening() && c.Protocol != "UNIX" {
+			listeningPorts[c.Protocol+"/"+strconv.Itoa(int(c.LocalPort))] = true
+		}
+	}
+
+	states := make(map[string]int)
+	hosts := make(map[string]int)
+	ports := make(map[string]int)
+	processes := make(map[string]int)
+	for _, c := range conns {
+		if strings.HasPrefix(c.Protocol, "TCP") {
+			states[c.State]++
+		}
+		if c.PID > 0 {
+			processes[fmt.Sprintf("%s (%d)", valueOrDash(c.Process), c.PID)]++
+		}
+		if c.IsListening() {
+			if c.Protocol != "UNIX" {
+				summary.Listening = append(summary.Listening, listeningSocket(c))
+			}
+			continue
+		}
+		if c.RemoteAddress == "" || c.Protocol == "UNIX" {
+			continue
+		}
+		hosts[c.RemoteAddress]++
+		if !listeningPorts[c.Protocol+"/"+strconv.Itoa(int(c.LocalPort))] {
+			protocol := strings.ToLower(strings.TrimRight(c.Protocol, "46"))
+			ports[PortSpec{Port: int(c.RemotePort), Protocol: protocol}.String()]++
+		}
+	}
+
+	summary.States = sortedCounts(states, 0)
+	summary.RemoteHosts = sortedCounts(hosts, opts.Top)
+	summary.RemotePorts = sortedCounts(ports, opts.Top)
+	summary.Processes = sortedCounts(processes, opts.Top)
+	for i := range summary.RemotePorts {
+		port, protocol, _ := strings.Cut(summary.RemotePorts[i].Key, "/")
+		number, _ := strconv.Atoi(port)
+		if name := serviceName(number, protocol); name != "unknown" {
+			summary.RemotePorts[i].Service = name
+		}
+	}
+
+	exposureOrder := map[string]int{ExposureAll: 0, ExposureAddress: 1, ExposureLoopback: 2}
+	sort.SliceStable(summary.Listening, func(i, j int) bool {
+		a, b := summary.Listening[i], summary.Listening[j]
+		if a.Exposure != b.Exposure {
+			return exposureOrder[a.Exposure] < exposureOrder[b.Exposure]
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		return a.Protocol < b.Protocol
+	})
+	for _, socket := range summary.Listening {
+		switch socket.Exposure {
+		case ExposureAll:
+			summary.Exposed++
+		case ExposureLoopback:
+			summary.Loopback++
+		}
+	}
+
+	if opts.Resolve {
+		resolveConnectionCounts(summary.RemoteHosts, opts.ResolveTimeout, opts.Concurrency)
+	}
+	return summary
+}
+
+// listeningSocket describes a listening connection and classifies its exposure.
+func listeningSocket(c Connection) ListeningSocket {
+	socket := ListeningSocket{
+		Protocol: c.Protocol,
+		Address:  c.LocalAddress,
+		Port:     c.LocalPort,
+		Exposure: ExposureAddress,
+		PID:      c.PID,
+		Process:  c.Process,
+	}
+	if addr, err := netip.ParseAddr(c.LocalAddress); err == nil {
+		switch {
+		case addr.IsUnspecified():
+			socket.Exposure = ExposureAll
+		case addr.Unmap().IsLoopback():
+			socket.Exposure = ExposureLoopback
+		}
+	}
+	return socket
+}
+
+// sortedCounts orders counts from most to least frequent, ties by key, keeping at most top
+// entries (all with 0).
+func sortedCounts(counts map[string]int, top int) []ConnectionCount {
+	result := make([]ConnectionCount, 0, len(counts))
+	for key, count := range counts {
+		result = append(result, ConnectionCount{Key: key, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Key < result[j].Key
+	})
+	if top > 0 && len(result) > top {
+		result = result[:top]
+	}
+	return result
+}
+
+// resolveConnectionCounts looks up the reverse DNS names of the remote hosts concurrently.
+// Lookups still running when the timeout expires are abandoned and leave the name empty.
+func resolveConnectionCounts(hosts []ConnectionCount, timeout time.Duration, concurrency int) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := range hosts {
+		wg.Add(1)
+		go func(count *ConnectionCount) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}: // Acquire a slot in the semaphore
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			names, err := net.DefaultResolver.LookupAddr(ctx, count.Key)
+			if err == nil && len(names) > 0 {
+				// Each goroutine writes only its own entry
+				count.Hostname = strings.TrimSuffix(names[0], ".")
+			}
+		}(&hosts[i])
+	}
+	wg.Wait()
+}
+
+// PrintConnectionSummary displays the aggregated views of a connection summary.
+func PrintConnectionSummary(summary ConnectionSummary) {
+	fmt.Println()
+	t := utils.Table("DarkSimple", "TCP States")
+	t.AppendHeader(table.Row{"State", "Connections"})
+	for _, state := range summary.States {
+		t.AppendRow(table.Row{state.Key, state.Count})
+	}
+	t.Render()
+	fmt.Println()
+
+	t = utils.Table("DarkSimple", "Top Remote Hosts")
+	t.AppendHeader(table.Row{"Remote Host", "Hostname", "Connections"})
+	for _, host := range summary.RemoteHosts {
+		t.AppendRow(table.Row{host.Key, valueOrDash(host.Hostname), host.Count})
+	}
+	t.Render()
+	fmt.Println()
+
+	t = utils.Table("DarkSimple", "Top Remote Ports")
+	t.AppendHeader(table.Row{"Remote Port", "Service", "Connections"})
+	for _, port := range summary.RemotePorts {
+		t.AppendRow(table.Row{port.Key, valueOrDash(port.Service), port.Count})
+	}
+	t.Render()
+	fmt.Println()
+
+	t = utils.Table("DarkSimple", "Processes")
+	t.AppendHeader(table.Row{"Process", "Sockets"})
+	for _, process := range summary.Processes {
+		t.AppendRow(table.Row{process.Key, process.Count})
+	}
+	t.Render()
+	fmt.Println()
+
+	t = utils.Table("DarkSimple", "Listening Sockets")
+	t.AppendHeader(table.Row{"Protocol", "Address", "Exposure", "PID", "Process"})
+	for _, socket := range summary.Listening {
+		pid := "-"
+		if socket.PID > 0 {
+			pid = strconv.Itoa(int(socket.PID))
+		}
+		t.AppendRow(table.Row{
+			socket.Protocol,
+			connectionEndpoint(socket.Protocol, socket.Address, socket.Port),
+			socket.Exposure,
+			pid,
+			valueOrDash(socket.Process),
+		})
+	}
+	t.Render()
+	fmt.Printf("%d connection(s); %d listening socket(s) exposed on all interfaces, %d on loopback only.\n",
+		summary.Total, summary.Exposed, summary.Loopback)
+	fmt.Println()
+}
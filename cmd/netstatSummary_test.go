@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeConnections(t *testing.T) {
+	conns := []Connection{
+		// Listening sockets
+		{Protocol: "TCP4", LocalAddress: "0.0.0.0", LocalPort: 22, State: "LISTEN", PID: 10, Process: "sshd"},
+		{Protocol: "TCP6", LocalAddress: "::1", LocalPort: 5432, State: "LISTEN", PID: 20, Process: "postgres"},
+		{Protocol: "TCP4", LocalAddress: "192.168.1.5", LocalPort: 8080, State: "LISTEN", PID: 30, Process: "app"},
+		{Protocol: "UDP4", LocalAddress: "127.0.0.53", LocalPort: 53, State: "NONE", PID: 40, Process: "resolved"},
+		{Protocol: "UNIX", LocalAddress: "/run/app.sock", State: "LISTEN", PID: 30, Process: "app"},
+		// Accepted by sshd, so the remote port is the client's
+		{Protocol: "TCP4", LocalAddress: "192.168.1.5", LocalPort: 22, RemoteAddress: "192.168.1.20", RemotePort: 50123, State: "ESTABLISHED", PID: 10, Process: "sshd"},
+		// Opened by this host
+		{Protocol: "TCP4", LocalAddress: "192.168.1.5", LocalPort: 40001, RemoteAddress: "140.82.112.3", RemotePort: 443, State: "ESTABLISHED", PID: 30, Process: "app"},
+		{Protocol: "TCP4", LocalAddress: "192.168.1.5", LocalPort: 40002, RemoteAddress: "140.82.112.3", RemotePort: 443, State: "TIME_WAIT"},
+		{Protocol: "TCP6", LocalAddress: "2001:db8::5", LocalPort: 40003, RemoteAddress: "2001:db8::80", RemotePort: 80, State: "ESTABLISHED", PID: 30, Process: "app"},
+		{Protocol: "UDP4", LocalAddress: "192.168.1.5", LocalPort: 40004, RemoteAddress: "192.168.1.1", RemotePort: 123, State: "NONE", PID: 50, Process: "chronyd"},
+		{Protocol: "UDP6", LocalAddress: "2001:db8::5", LocalPort: 40005, RemoteAddress: "2001:db8::53", RemotePort: 53, State: "NONE", PID: 40, Process: "resolved"},
+	}
+	summary := SummarizeConnections(conns, NetstatSummaryOptions{Top: 3})
+
+	if summary.Total != len(conns) {
+		t.Errorf("total = %d, want %d", summary.Total, len(conns))
+	}
+	wantStates := []ConnectionCount{{Key: "ESTABLISHED", Count: 3}, {Key: "LISTEN", Count: 3}, {Key: "TIME_WAIT", Count: 1}}
+	if !reflect.DeepEqual(summary.States, wantStates) {
+		t.Errorf("states = %+v, want %+v", summary.States, wantStates)
+	}
+	wantHosts := []ConnectionCount{{Key: "140.82.112.3", Count: 2}, {Key: "192.168.1.1", Count: 1}, {Key: "192.168.1.20", Count: 1}}
+	if !reflect.DeepEqual(summary.RemoteHosts, wantHosts) {
+		t.Errorf("remote hosts = %+v, want the top 3 %+v", summary.RemoteHosts, wantHosts)
+	}
+	// UDP ports are named from the UDP services, and the sshd client's port is not counted
+	wantPorts := []ConnectionCount{
+		{Key: "443/tcp", Count: 2, Service: "https"},
+		{Key: "123/udp", Count: 1, Service: "ntp"},
+		{Key: "53/udp", Count: 1, Service: "domain"},
+	}
+	if !reflect.DeepEqual(summary.RemotePorts, wantPorts) {
+		t.Errorf("remote ports = %+v, want %+v", summary.RemotePorts, wantPorts)
+	}
+	wantProcesses := []ConnectionCount{{Key: "app (30)", Count: 4}, {Key: "resolved (40)", Count: 2}, {Key: "sshd (10)", Count: 2}}
+	if !reflect.DeepEqual(summary.Processes, wantProcesses) {
+		t.Errorf("processes = %+v, want %+v", summary.Processes, wantProcesses)
+	}
+
+	var listening []string
+	for _, socket := range summary.Listening {
+		listening = append(listening, socket.Exposure+" "+socket.Address)
+	}
+	wantListening := []string{"all interfaces 0.0.0.0", "one address 192.168.1.5", "loopback only 127.0.0.53", "loopback only ::1"}
+	if !reflect.DeepEqual(listening, wantListening) || summary.Exposed != 1 || summary.Loopback != 2 {
+		t.Errorf("listening = %q (%d exposed, %d loopback), want %q", listening, summary.Exposed, summary.Loopback, wantListening)
+	}
+}
+
+func TestServiceName(t *testing.T) {
+	tests := []struct {
+		port  int
+		proto string
+		want  string
+	}{
+		{22, "tcp", "ssh"},
+		{443, "TCP", "https"},
+		{123, "udp", "ntp"},
+		{22, "udp", "unknown"},
+		{53, "sctp", "unknown"},
+		{65000, "tcp", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := serviceName(tt.port, tt.proto); got != tt.want {
+			t.Errorf("serviceName(%d, %s) = %s, want %s", tt.port, tt.proto, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestParsePortRanges(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []PortRange
+		err  bool
+	}{
+		{spec: "22", want: []PortRange{{22, 22}}},
+		{spec: "443, 8000 - 8080,,", want: []PortRange{{443, 443}, {8000, 8080}}},
+		{spec: "1-65535", want: []PortRange{{1, 65535}}},
+		{spec: "0", err: true},
+		{spec: "65536", err: true},
+		{spec: "8080-8000", err: true},
+		{spec: "http", err: true},
+		{spec: "22/tcp", err: true},
+		{spec: ",", err: true},
+	}
+	for _, tt := range tests {
+		ranges, err := ParsePortRanges(tt.spec)
+		if (err != nil) != tt.err || !reflect.DeepEqual(ranges, tt.want) {
+			t.Errorf("ParsePortRanges(%q) = %v, %v; want %v, error %v", tt.spec, ranges, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestNetstatFilterMatch(t *testing.T) {
+	conns := []Connection{
+		{Protocol: "TCP4", LocalAddress: "0.0.0.0", LocalPort: 22, State: "LISTEN", PID: 100, Process: "sshd"},
+		{Protocol: "TCP4", LocalAddress: "10.0.0.5", LocalPort: 22, RemoteAddress: "10.1.2.3", RemotePort: 51000, State: "ESTABLISHED", PID: 101, Process: "sshd"},
+		{Protocol: "TCP6", LocalAddress: "::1", LocalPort: 40000, RemoteAddress: "::ffff:192.168.1.9", RemotePort: 8080, State: "TIME_WAIT"},
+		{Protocol: "UDP4", LocalAddress: "0.0.0.0", LocalPort: 53, State: "NONE", PID: 200, Process: "dnsmasq"},
+		{Protocol: "UDP6", LocalAddress: "fe80::1%eth0", LocalPort: 546, RemoteAddress: "fe80::2%eth0", RemotePort: 547, State: "NONE", PID: 300, Process: "NetworkManager"},
+		{Protocol: "UNIX", LocalAddress: "/run/docker.sock", State: "LISTEN", PID: 400, Process: "dockerd"},
+	}
+	tests := []struct {
+		name   string
+		filter NetstatFilter
+		want   []int // Indexes of the matching connections
+	}{
+		{"none", NetstatFilter{}, []int{0, 1, 2, 3, 4, 5}},
+		{"listening", NetstatFilter{Listen: true}, []int{0, 3, 5}},
+		{"state", NetstatFilter{States: []string{"TIME_WAIT", "ESTABLISHED"}}, []int{1, 2}},
+		{"family", NetstatFilter{Protocols: []string{"tcp"}}, []int{0, 1, 2}},
+		{"version", NetstatFilter{Protocols: []string{"udp6", "tcp4"}}, []int{0, 1, 4}},
+		// The remote port of a listening socket is not a port it uses
+		{"local or remote port", NetstatFilter{Ports: []PortRange{{8000, 8080}, {53, 53}}}, []int{2, 3}},
+		{"pid", NetstatFilter{PIDs: []int32{101, 300}}, []int{1, 4}},
+		{"process", NetstatFilter{Process: "SSH"}, []int{0, 1}},
+		{"remote network", NetstatFilter{Remote: []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16"), netip.MustParsePrefix("fe80::/10")}}, []int{2, 4}},
+		{"all have to match", NetstatFilter{Process: "sshd", Listen: true, Ports: []PortRange{{22, 22}}}, []int{0}},
+	}
+	for _, tt := range tests {
+		var got []int
+		for i, c := range conns {
+			if tt.filter.Match(c) {
+				got = append(got, i)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: matched %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := FilterConnections(conns, NetstatFilter{Protocols: []string{"unix"}}); len(got) != 1 || got[0].Process != "dockerd" {
+		t.Errorf("FilterConnections = %+v", got)
+	}
+}
+
+func TestSortConnections(t *testing.T) {
+	conns := []Connection{
+		{Protocol: "UDP4", LocalAddress: "10.0.0.10", LocalPort: 53, Process: "dnsmasq", PID: 30},
+		{Protocol: "TCP4", LocalAddress: "10.0.0.9", LocalPort: 443, RemoteAddress: "10.0.0.100", RemotePort: 5000, Process: "nginx", PID: 20},
+		{Protocol: "TCP4", LocalAddress: "10.0.0.10", LocalPort: 80, RemoteAddress: "9.9.9.9", RemotePort: 6000, Process: "Nginx", PID: 10},
+		{Protocol: "TCP6", LocalAddress: "::", LocalPort: 22, Process: "sshd", PID: 40},
+	}
+	tests := []struct {
+		key  string
+		want []uint32 // Local ports in the expected order
+	}{
+		{"proto", []uint32{443, 80, 22, 53}},
+		// Numerically by address, so 10.0.0.9 comes before 10.0.0.10, then by port
+		{"local", []uint32{443, 53, 80, 22}},
+		{"remote", []uint32{80, 443, 22, 53}}, // No remote address sorts last
+		{"port", []uint32{22, 53, 80, 443}},
+		{"PID", []uint32{80, 443, 53, 22}},
+		{"process", []uint32{53, 443, 80, 22}}, // Case-insensitive, ties by protocol and local endpoint
+	}
+	for _, tt := range tests {
+		sorted := append([]Connection(nil), conns...)
+		if err := SortConnections(sorted, tt.key); err != nil {
+			t.Fatal(err)
+		}
+		var got []uint32
+		for _, c := range sorted {
+			got = append(got, c.LocalPort)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sorted by %s: %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	if err := SortConnections(conns, "size"); err == nil {
+		t.Error("an unknown sort key was accepted")
+	}
+}
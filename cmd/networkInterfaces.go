@@ -100,6 +100,9 @@ func PrintNetworkInterfacesInfo(networkInterfacesInfo []gopsutilNet.InterfaceSta
 				}
 			default:
 				t.AppendRow(table.Row{field.Name, value})
+				if field.Name == "HardwareAddr" && iface.HardwareAddr != "" {
+					t.AppendRow(table.Row{"Vendor", utils.DescribeVendor(iface.HardwareAddr)})
+				}
 			}
 		}
 
@@ -0,0 +1,442 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// PingCmd sends ICMP echo requests to one or more hosts, or sweeps a whole subnet.
+var PingCmd = &cobra.Command{
+	Use:   "ping [host...]",
+	Short: "Checks reachability and latency of hosts with ICMP echo requests",
+	Long: `Sends ICMP echo requests to each host and reports round-trip times and packet loss
+(min/avg/max/mdev). Hosts may be names, IPv4 or IPv6 addresses, or CIDR blocks, as accepted by
+portscanner. Unprivileged ICMP sockets are used where the system allows them
+(net.ipv4.ping_group_range on Linux); otherwise raw sockets are used, which need root.
+
+With --sweep <cidr> every address of the subnet is pinged concurrently and the live hosts are listed.
+
+Exits with status 2 when a host did not answer any request (with --sweep, when no host answered),
+so scripts can tell an unreachable host from an error, which exits with status 1.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := PingOptions{}
+		opts.Count, _ = cmd.Flags().GetInt("count")
+		opts.Interval, _ = cmd.Flags().GetDuration("interval")
+		opts.Size, _ = cmd.Flags().GetInt("size")
+		opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+		sweep, _ := cmd.Flags().GetString("sweep")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+
+		family, err := addressFamilyFlag(cmd)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if sweep != "" {
+			// A sweep only needs to know whether each host answers, so send one request unless told otherwise
+			if !cmd.Flags().Changed("count") {
+				opts.Count = 1
+			}
+			results, err := RunPingSweep(sweep, family, opts, concurrency)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			if jsonOutput {
+				utils.PrintJSON(results)
+			} else {
+				PrintPingSweepResults(sweep, results)
+			}
+			if len(results) == 0 {
+				os.Exit(utils.FindingsExitCode)
+			}
+			return
+		}
+
+		if len(args) == 0 {
+			fmt.Println("Error: specify at least one host, or a subnet with --sweep")
+			os.Exit(1)
+		}
+		targets, err := ParseScanTargets(strings.Join(args, ","), family)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		var results []PingResult
+		for _, target := range targets {
+			if !jsonOutput {
+				fmt.Printf("PING %s (%s): %d data bytes\n", target.Name, target.Address, opts.Size)
+				opts.OnReply = func(reply PingReply) {
+					fmt.Printf("%d bytes from %s: icmp_seq=%d time=%s\n", reply.Bytes, reply.From, reply.Seq, formatPingRTT(reply.RTT))
+				}
+			}
+			result, err := RunPing(target, opts)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			results = append(results, result)
+		}
+
+		if jsonOutput {
+			utils.PrintJSON(results)
+		} else {
+			PrintPingResults(results)
+		}
+		for _, result := range results {
+			if result.Received == 0 {
+				os.Exit(utils.FindingsExitCode)
+			}
+		}
+	},
+}
+
+// PingOptions controls how many echo requests are sent and how long to wait for replies.
+type PingOptions struct {
+	Count    int             // Number of echo requests to send to each host
+	Interval time.Duration   // Time between requests to the same host
+	Size     int             // Number of data bytes in each request
+	Timeout  time.Duration   // How long to wait for each reply
+	OnReply  func(PingReply) // Called for each reply as it arrives; may be nil
+}
+
+// PingReply is a single echo reply.
+type PingReply struct {
+	From  string        `json:"from"`
+	Seq   int           `json:"seq"`
+	Bytes int           `json:"bytes"`
+	RTT   time.Duration `json:"rtt"`
+}
+
+// PingResult summarizes the replies received from one host.
+type PingResult struct {
+	Host     string        `json:"host"`
+	Address  string        `json:"address"`
+	Sent     int           `json:"sent"`
+	Received int           `json:"received"`
+	Loss     float64       `json:"loss"` // Percentage of requests that got no reply
+	Min      time.Duration `json:"min"`
+	Avg      time.Duration `json:"avg"`
+	Max      time.Duration `json:"max"`
+	MDev     time.Duration `json:"mdev"`   // Standard deviation of the round-trip times, as ping reports it
+	Socket   string        `json:"socket"` // "datagram" (unprivileged) or "raw"
+}
+
+// init registers the PingCmd with the root command when this package is imported.
+func init() {
+	RootCmd.AddCommand(PingCmd)
+	PingCmd.Flags().IntP("count", "c", 4, "Number of echo requests to send to each host")
+	PingCmd.Flags().DurationP("interval", "i", 1*time.Second, "Time between echo requests")
+	PingCmd.Flags().IntP("size", "s", 56, "Number of data bytes to send")
+	PingCmd.Flags().DurationP("timeout", "W", 1*time.Second, "Time to wait for each reply")
+	PingCmd.Flags().String("sweep", "", "Ping every address of a subnet (CIDR) concurrently and list the live hosts")
+	PingCmd.Flags().Int("concurrency", 64, "Number of hosts to ping at once in sweep mode")
+	PingCmd.Flags().BoolP("ipv4", "4", false, "Only use IPv4 addresses")
+	PingCmd.Flags().BoolP("ipv6", "6", false, "Only use IPv6 addresses")
+	PingCmd.Flags().Bool("json", false, "Print the results as JSON")
+}
+
+// withDefaults fills in zero-valued options with the command's defaults.
+func (opts PingOptions) withDefaults() PingOptions {
+	if opts.Count <= 0 {
+		opts.Count = 4
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 1 * time.Second
+	}
+	if opts.Size < 0 {
+		opts.Size = 56
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 1 * time.Second
+	}
+	return opts
+}
+
+// RunPing sends opts.Count echo requests to target and collects the replies.
+func RunPing(target ScanTarget, opts PingOptions) (PingResult, error) {
+	opts = opts.withDefaults()
+	result := PingResult{Host: target.Name, Address: target.Address}
+
+	pinger, err := newPinger(target)
+	if err != nil {
+		return result, err
+	}
+	defer pinger.conn.Close()
+	result.Socket = "datagram"
+	if pinger.raw {
+		result.Socket = "raw"
+	}
+
+	payload := make([]byte, opts.Size)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	var rtts []time.Duration
+	for seq := 1; seq <= opts.Count; seq++ {
+		sentAt := time.Now()
+		if err := pinger.send(seq, payload); err != nil {
+			return result, fmt.Errorf("sending echo request to %s: %w", target.Address, err)
+		}
+		result.Sent++
+
+		if rtt, n, ok := pinger.receive(seq, sentAt, opts.Timeout); ok {
+			result.Received++
+			rtts = append(rtts, rtt)
+			if opts.OnReply != nil {
+				opts.OnReply(PingReply{From: target.Address, Seq: seq, Bytes: n, RTT: rtt})
+			}
+		}
+
+		if seq < opts.Count {
+			time.Sleep(time.Until(sentAt.Add(opts.Interval)))
+		}
+	}
+
+	result.Loss = 100 * float64(result.Sent-result.Received) / float64(result.Sent)
+	result.Min, result.Avg, result.Max, result.MDev = pingStats(rtts)
+	return result, nil
+}
+
+// RunPingSweep pings every address of cidr, concurrency hosts at a time, and returns the hosts
+// that answered, in address order.
+func RunPingSweep(cidr string, family string, opts PingOptions, concurrency int) ([]PingResult, error) {
+	if !strings.Contains(cidr, "/") {
+		return nil, fmt.Errorf("--sweep needs a subnet in CIDR notation, e.g. 192.168.1.0/24")
+	}
+	targets, err := ParseScanTargets(cidr, family)
+	if err != nil {
+		return nil, err
+	}
+	if concurrency <= 0 {
+		concurrency = 64
+	}
+
+	var mu sync.Mutex
+	var live []PingResult
+	var firstErr error
+	var wg sync.WaitGroup
+	targetCh := make(chan ScanTarget)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range targetCh {
+				result, err := RunPing(target, opts)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if err == nil && result.Received > 0 {
+					live = append(live, result)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, target := range targets {
+		targetCh <- target
+	}
+	close(targetCh)
+	wg.Wait()
+
+	// Socket errors affect every host alike, so only report them when nothing answered
+	if len(live) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	sortPingResults(live)
+	return live, nil
+}
+
+// PrintPingResults displays the per-host statistics in a formatted table.
+func PrintPingResults(results []PingResult) {
+	t := utils.Table("DarkSimple", "Ping Statistics")
+	t.AppendHeader(table.Row{"Host", "Address", "Sent", "Received", "Loss", "Min", "Avg", "Max", "MDev"})
+	for _, result := range results {
+		t.AppendRow(table.Row{
+			result.Host,
+			result.Address,
+			result.Sent,
+			result.Received,
+			fmt.Sprintf("%.1f%%", result.Loss),
+			formatPingRTT(result.Min),
+			formatPingRTT(result.Avg),
+			formatPingRTT(result.Max),
+			formatPingRTT(result.MDev),
+		})
+	}
+	fmt.Println()
+	t.Render()
+	fmt.Println()
+}
+
+// PrintPingSweepResults displays the hosts that answered a sweep.
+func PrintPingSweepResults(cidr string, results []PingResult) {
+	t := utils.Table("DarkSimple", "Ping Sweep: "+cidr)
+	t.AppendHeader(table.Row{"Address", "Received", "Loss", "Avg RTT"})
+	for _, result := range results {
+		t.AppendRow(table.Row{
+			result.Address,
+			fmt.Sprintf("%d/%d", result.Received, result.Sent),
+			fmt.Sprintf("%.1f%%", result.Loss),
+			formatPingRTT(result.Avg),
+		})
+	}
+	fmt.Println()
+	t.Render()
+	fmt.Printf("\n%d live hosts\n", len(results))
+}
+
+// formatPingRTT formats a round-trip time in milliseconds, or "-" when nothing was measured.
+func formatPingRTT(rtt time.Duration) string {
+	if rtt <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.3fms", float64(rtt)/float64(time.Millisecond))
+}
+
+// pingStats returns the minimum, mean, maximum and standard deviation of rtts.
+func pingStats(rtts []time.Duration) (min, avg, max, mdev time.Duration) {
+	if len(rtts) == 0 {
+		return 0, 0, 0, 0
+	}
+	min, max = rtts[0], rtts[0]
+	var sum, sumSquares float64
+	for _, rtt := range rtts {
+		if rtt < min {
+			min = rtt
+		}
+		if rtt > max {
+			max = rtt
+		}
+		sum += float64(rtt)
+		sumSquares += float64(rtt) * float64(rtt)
+	}
+	mean := sum / float64(len(rtts))
+	variance := sumSquares/float64(len(rtts)) - mean*mean
+	return min, time.Duration(mean), max, time.Duration(math.Sqrt(math.Max(variance, 0)))
+}
+
+// sortPingResults orders results by address, numerically rather than as strings.
+func sortPingResults(results []PingResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return addressLess(results[i].Address, results[j].Address)
+	})
+}
+
+// pinger sends echo requests to a single address over an ICMP socket.
+type pinger struct {
+	conn *icmp.PacketConn
+	raw  bool // Raw socket: replies for other processes arrive too and must be matched by ID
+	ipv6 bool
+	dst  net.Addr
+	ip   net.IP
+	id   int
+}
+
+// newPinger opens an ICMP socket for target, preferring an unprivileged datagram socket and
+// falling back to a raw socket when the system does not allow those.
+func newPinger(target ScanTarget) (*pinger, error) {
+	host, zone, _ := strings.Cut(target.Address, "%")
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address %q", target.Address)
+	}
+	p := &pinger{ip: ip, ipv6: target.IsIPv6(), id: os.Getpid() & 0xffff}
+
+	network, rawNetwork, listen := "udp4", "ip4:icmp", "0.0.0.0"
+	if p.ipv6 {
+		network, rawNetwork, listen = "udp6", "ip6:ipv6-icmp", "::"
+	}
+	conn, err := icmp.ListenPacket(network, listen)
+	if err == nil {
+		p.conn = conn
+		p.dst = &net.UDPAddr{IP: ip, Zone: zone}
+		return p, nil
+	}
+	conn, rawErr := icmp.ListenPacket(rawNetwork, listen)
+	if rawErr != nil {
+		if errors.Is(rawErr, os.ErrPermission) {
+			return nil, fmt.Errorf("opening ICMP socket: unprivileged ICMP is not enabled (see net.ipv4.ping_group_range) and raw sockets need root: %w", rawErr)
+		}
+		return nil, fmt.Errorf("opening ICMP socket: %w", rawErr)
+	}
+	p.conn = conn
+	p.raw = true
+	p.dst = &net.IPAddr{IP: ip, Zone: zone}
+	return p, nil
+}
+
+// send transmits echo request seq carrying payload.
+func (p *pinger) send(seq int, payload []byte) error {
+	var msgType icmp.Type = ipv4.ICMPTypeEcho
+	if p.ipv6 {
+		msgType = ipv6.ICMPTypeEchoRequest
+	}
+	msg := icmp.Message{
+		Type: msgType,
+		Body: &icmp.Echo{ID: p.id, Seq: seq, Data: payload},
+	}
+	packet, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+	_, err = p.conn.WriteTo(packet, p.dst)
+	return err
+}
+
+// receive waits up to timeout for the reply to echo request seq, sent at sentAt, and returns
+// its round-trip time and size.
+func (p *pinger) receive(seq int, sentAt time.Time, timeout time.Duration) (time.Duration, int, bool) {
+	p.conn.SetReadDeadline(sentAt.Add(timeout))
+	protocol := ipv4.ICMPTypeEchoReply.Protocol()
+	if p.ipv6 {
+		protocol = ipv6.ICMPTypeEchoReply.Protocol()
+	}
+	buf := make([]byte, 65536)
+	for {
+		n, peer, err := p.conn.ReadFrom(buf)
+		if err != nil {
+			return 0, 0, false
+		}
+		received := time.Now()
+		var from net.IP
+		switch addr := peer.(type) {
+		case *net.UDPAddr:
+			from = addr.IP
+		case *net.IPAddr:
+			from = addr.IP
+		}
+		if !from.Equal(p.ip) {
+			continue
+		}
+		msg, err := icmp.ParseMessage(protocol, buf[:n])
+		if err != nil || (msg.Type != ipv4.ICMPTypeEchoReply && msg.Type != ipv6.ICMPTypeEchoReply) {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		// Datagram sockets rewrite the ID to the socket's port and only deliver our own replies
+		if !ok || echo.Seq != seq || (p.raw && echo.ID != p.id) {
+			continue
+		}
+		return received.Sub(sentAt), n, true
+	}
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPingStats(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name                string
+		rtts                []time.Duration
+		min, avg, max, mdev time.Duration
+	}{
+		{"empty", nil, 0, 0, 0, 0},
+		{"one reply", []time.Duration{5 * ms}, 5 * ms, 5 * ms, 5 * ms, 0},
+		{"equal", []time.Duration{3 * ms, 3 * ms, 3 * ms}, 3 * ms, 3 * ms, 3 * ms, 0},
+		// Population standard deviation, as ping prints it: sqrt(((2-4)² + (4-4)² + (6-4)²) / 3)
+		{"spread", []time.Duration{4 * ms, 2 * ms, 6 * ms}, 2 * ms, 4 * ms, 6 * ms, 1632993 * time.Nanosecond},
+		{"two", []time.Duration{10 * ms, 20 * ms}, 10 * ms, 15 * ms, 20 * ms, 5 * ms},
+	}
+	for _, tt := range tests {
+		min, avg, max, mdev := pingStats(tt.rtts)
+		if min != tt.min || avg != tt.avg || max != tt.max || mdev != tt.mdev {
+			t.Errorf("%s: pingStats = %v/%v/%v/%v, want %v/%v/%v/%v", tt.name, min, avg, max, mdev, tt.min, tt.avg, tt.max, tt.mdev)
+		}
+	}
+}
+
+func TestPingOptionsDefaults(t *testing.T) {
+	got := PingOptions{Count: -1, Size: -1}.withDefaults()
+	if got.Count != 4 || got.Interval != time.Second || got.Size != 56 || got.Timeout != time.Second {
+		t.Errorf("defaults = %+v", got)
+	}
+	// An empty payload is allowed, like ping -s 0
+	set := PingOptions{Count: 2, Interval: 200 * time.Millisecond, Size: 0, Timeout: 3 * time.Second}
+	if got := set.withDefaults(); got.Count != 2 || got.Interval != set.Interval || got.Size != 0 || got.Timeout != set.Timeout {
+		t.Errorf("explicit options changed to %+v", got)
+	}
+}
+
+func TestRunPingSweepValidation(t *testing.T) {
+	tests := []struct {
+		cidr    string
+		family  string
+		wantErr string
+	}{
+		{"192.168.1.1", "", "CIDR notation"},
+		{"example.com", "", "CIDR notation"},
+		{"10.0.0.0/8", "", "too large"},
+		{"fd00::/64", "", "too large"},
+		{"fd00::/120", FamilyIPv4, "address family"},
+		{"192.168.1.0/24", FamilyIPv6, "address family"},
+		{"192.168.1.0/33", "", "invalid CIDR"},
+	}
+	for _, tt := range tests {
+		results, err := RunPingSweep(tt.cidr, tt.family, PingOptions{}, 0)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("RunPingSweep(%q, %q) = %+v, %v; want an error containing %q", tt.cidr, tt.family, results, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRunPingLoopback(t *testing.T) {
+	var replies []PingReply
+	opts := PingOptions{Count: 2, Interval: 10 * time.Millisecond, Size: 16, OnReply: func(reply PingReply) { replies = append(replies, reply) }}
+	result, err := RunPing(ScanTarget{Name: "localhost", Address: "127.0.0.1"}, opts)
+	if errors.Is(err, os.ErrPermission) {
+		t.Skip("ICMP sockets are not permitted:", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent != 2 || result.Received != 2 || result.Loss != 0 || result.Min <= 0 || result.Min > result.Max {
+		t.Errorf("result = %+v, want both requests answered", result)
+	}
+	// The reply carries the 8-byte ICMP header in front of the data
+	if len(replies) != 2 || replies[0].Seq != 1 || replies[1].Seq != 2 || replies[0].Bytes != 24 || replies[0].From != "127.0.0.1" {
+		t.Errorf("replies = %+v", replies)
+	}
+}
+
+func TestRunPingSweepLoopback(t *testing.T) {
+	results, err := RunPingSweep("127.0.0.0/30", FamilyIPv4, PingOptions{Count: 1}, 2)
+	if errors.Is(err, os.ErrPermission) {
+		t.Skip("ICMP sockets are not permitted:", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Address != "127.0.0.1" || results[1].Address != "127.0.0.2" {
+		t.Errorf("live hosts = %+v, want 127.0.0.1 and 127.0.0.2 in order", results)
+	}
+}
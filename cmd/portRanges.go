@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortRange is an inclusive range of port numbers.
+type PortRange struct {
+	First uint32 `json:"first"`
+	Last  uint32 `json:"last"`
+}
+
+// ParsePortRanges parses a comma-separated list of ports and port ranges, e.g. "22,8000-8080".
+// Ports run from 1 to 65535.
+func ParsePortRanges(spec string) ([]PortRange, error) {
+	var ranges []PortRange
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		first, last, found := strings.Cut(entry, "-")
+		if !found {
+			last = first
+		}
+		start, err1 := strconv.ParseUint(strings.TrimSpace(first), 10, 16)
+		end, err2 := strconv.ParseUint(strings.TrimSpace(last), 10, 16)
+		if err1 != nil || err2 != nil || start < 1 || start > end {
+			return nil, fmt.Errorf("invalid port range %q", entry)
+		}
+		ranges = append(ranges, PortRange{First: uint32(start), Last: uint32(end)})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no ports specified")
+	}
+	return ranges, nil
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+)
+
+// PortScanRecord is a single open port as stored in the scan history.
+type PortScanRecord struct {
+	Host      string    `json:"host"`
+	Port      int       `json:"port"`
+	Proto     string    `json:"proto"`
+	Service   string    `json:"service"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PortScanSnapshot is one saved scan: the targets that were scanned and the ports found open.
+type PortScanSnapshot struct {
+	Targets   string           `json:"targets"`
+	Timestamp time.Time        `json:"timestamp"`
+	Ports     []PortScanRecord `json:"ports"`
+}
+
+// PortScanHistory is the on-disk history store written by `portscanner --save`.
+type PortScanHistory struct {
+	Scans []PortScanSnapshot `json:"scans"`
+}
+
+// PortScanComparison describes the difference between the current scan and the last saved one.
+type PortScanComparison struct {
+	Targets       string           `json:"targets"`
+	PreviousScan  *time.Time       `json:"previousScan"`
+	CurrentScan   time.Time        `json:"currentScan"`
+	NewlyOpened   []PortScanRecord `json:"newlyOpened"`
+	NewlyClosed   []PortScanRecord `json:"newlyClosed"`
+	Unchanged     int              `json:"unchanged"`
+	ChangesFound  bool             `json:"changesFound"`
+	FirstBaseline bool             `json:"firstBaseline"`
+}
+
+// portScanTargetsKey builds the key used to match scans of the same targets in the history.
+func portScanTargetsKey(host string, startPort, endPort int) string {
+	return fmt.Sprintf("%s tcp/%d-%d", host, startPort, endPort)
+}
+
+// NewPortScanSnapshot converts the open ports of a scan into a snapshot for the history store.
+func NewPortScanSnapshot(targets string, openPorts []PortDetail, timestamp time.Time) PortScanSnapshot {
+	snapshot := PortScanSnapshot{
+		Targets:   targets,
+		Timestamp: timestamp,
+		Ports:     []PortScanRecord{},
+	}
+	for _, port := range openPorts {
+		snapshot.Ports = append(snapshot.Ports, PortScanRecord{
+			Host:      port.Host,
+			Port:      port.Port,
+			Proto:     "tcp",
+			Service:   port.Service,
+			Timestamp: timestamp,
+		})
+	}
+	sortPortScanRecords(snapshot.Ports)
+	return snapshot
+}
+
+// LoadPortScanHistory reads the history store at path. A missing file yields an empty history.
+func LoadPortScanHistory(path string) (*PortScanHistory, error) {
+	history := &PortScanHistory{}
+	if err := utils.ReadJSONFile(path, history); err != nil {
+		return nil, fmt.Errorf("error reading scan history: %w", err)
+	}
+	return history, nil
+}
+
+// SavePortScanHistory writes the history store to path, replacing the previous file atomically.
+func SavePortScanHistory(path string, history *PortScanHistory) error {
+	if err := utils.WriteJSONFile(path, history); err != nil {
+		return fmt.Errorf("error writing scan history: %w", err)
+	}
+	return nil
+}
+
+// LastScan returns the most recent snapshot for the given targets, or nil if there is none.
+func (h *PortScanHistory) LastScan(targets string) *PortScanSnapshot {
+	var last *PortScanSnapshot
+	for i := range h.Scans {
+		if h.Scans[i].Targets != targets {
+			continue
+		}
+		if last == nil || h.Scans[i].Timestamp.After(last.Timestamp) {
+			last = &h.Scans[i]
+		}
+	}
+	return last
+}
+
+// ComparePortScans reports which ports were opened or closed between previous and current.
+// A nil previous snapshot is treated as a first baseline and never counts as a change.
+func ComparePortScans(previous *PortScanSnapshot, current PortScanSnapshot) PortScanComparison {
+	comparison := PortScanComparison{
+		Targets:     current.Targets,
+		CurrentScan: current.Timestamp,
+		NewlyOpened: []PortScanRecord{},
+		NewlyClosed: []PortScanRecord{},
+	}
+	if previous == nil {
+		comparison.FirstBaseline = true
+		return comparison
+	}
+	previousTime := previous.Timestamp
+	comparison.PreviousScan = &previousTime
+
+	recordKey := func(r PortScanRecord) string {
+		return r.Host + "/" + r.Proto + "/" + strconv.Itoa(r.Port)
+	}
+	before := make(map[string]bool, len(previous.Ports))
+	for _, r := range previous.Ports {
+		before[recordKey(r)] = true
+	}
+	now := make(map[string]bool, len(current.Ports))
+	for _, r := range current.Ports {
+		now[recordKey(r)] = true
+		if before[recordKey(r)] {
+			comparison.Unchanged++
+		} else {
+			comparison.NewlyOpened = append(comparison.NewlyOpened, r)
+		}
+	}
+	for _, r := range previous.Ports {
+		if !now[recordKey(r)] {
+			comparison.NewlyClosed = append(comparison.NewlyClosed, r)
+		}
+	}
+
+	sortPortScanRecords(comparison.NewlyOpened)
+	sortPortScanRecords(comparison.NewlyClosed)
+	comparison.ChangesFound = len(comparison.NewlyOpened) > 0 || len(comparison.NewlyClosed) > 0
+	return comparison
+}
+
+// PrintPortScanComparison displays newly opened and newly closed ports in a table.
+func PrintPortScanComparison(comparison PortScanComparison) {
+	t := utils.Table("DarkSimple", "Port Scan Changes")
+	t.AppendHeader(table.Row{"Change", "Host", "Port", "Proto", "Service", "Last Seen"})
+
+	for _, r := range comparison.NewlyOpened {
+		t.AppendRow(table.Row{"OPENED", r.Host, r.Port, r.Proto, r.Service, r.Timestamp.Format(time.RFC3339)})
+	}
+	for _, r := range comparison.NewlyClosed {
+		t.AppendRow(table.Row{"CLOSED", r.Host, r.Port, r.Proto, r.Service, r.Timestamp.Format(time.RFC3339)})
+	}
+	if !comparison.ChangesFound {
+		t.AppendRow(table.Row{"-", "-", "-", "-", "-", "-"})
+	}
+
+	fmt.Println()
+	t.Render()
+	fmt.Println()
+
+	switch {
+	case comparison.FirstBaseline:
+		fmt.Println("No previous scan for these targets; saved as the baseline.")
+	case comparison.ChangesFound:
+		fmt.Printf("%d newly opened, %d newly closed, %d unchanged since %s.\n",
+			len(comparison.NewlyOpened), len(comparison.NewlyClosed), comparison.Unchanged,
+			comparison.PreviousScan.Format(time.RFC3339))
+	default:
+		fmt.Printf("No changes since %s.\n", comparison.PreviousScan.Format(time.RFC3339))
+	}
+}
+
+// sortPortScanRecords orders records by host, then protocol, then port.
+func sortPortScanRecords(records []PortScanRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Host != records[j].Host {
+			return records[i].Host < records[j].Host
+		}
+		if records[i].Proto != records[j].Proto {
+			return records[i].Proto < records[j].Proto
+		}
+		return records[i].Port < records[j].Port
+	})
+}
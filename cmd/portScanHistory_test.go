@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestComparePortScans(t *testing.T) {
+	before := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	after := before.Add(time.Hour)
+	snapshot := func(at time.Time, ports ...int) PortScanSnapshot {
+		var open []PortDetail
+		for _, port := range ports {
+			open = append(open, PortDetail{Host: "10.0.0.1", Port: port, Service: serviceName(port, "tcp")})
+		}
+		return NewPortScanSnapshot("10.0.0.1 tcp/1-1024", open, at)
+	}
+
+	tests := []struct {
+		name                   string
+		previous               *PortScanSnapshot
+		current                PortScanSnapshot
+		opened, closed, kept   []int
+		changed, firstBaseline bool
+	}{
+		{name: "first baseline", current: snapshot(after, 22, 80), firstBaseline: true},
+		{name: "unchanged", previous: ptr(snapshot(before, 22, 80)), current: snapshot(after, 80, 22), kept: []int{22, 80}},
+		{name: "opened and closed", previous: ptr(snapshot(before, 22, 80)), current: snapshot(after, 22, 443, 8080),
+			opened: []int{443, 8080}, closed: []int{80}, kept: []int{22}, changed: true},
+		{name: "all closed", previous: ptr(snapshot(before, 22)), current: snapshot(after), closed: []int{22}, changed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComparePortScans(tt.previous, tt.current)
+			if got.FirstBaseline != tt.firstBaseline || got.ChangesFound != tt.changed {
+				t.Fatalf("FirstBaseline=%v ChangesFound=%v, want %v %v", got.FirstBaseline, got.ChangesFound, tt.firstBaseline, tt.changed)
+			}
+			if !equalRecordPorts(got.NewlyOpened, tt.opened) || !equalRecordPorts(got.NewlyClosed, tt.closed) {
+				t.Errorf("opened %v closed %v, want %v %v", recordPorts(got.NewlyOpened), recordPorts(got.NewlyClosed), tt.opened, tt.closed)
+			}
+			if got.Unchanged != len(tt.kept) {
+				t.Errorf("Unchanged = %d, want %d", got.Unchanged, len(tt.kept))
+			}
+			if tt.previous != nil && (got.PreviousScan == nil || !got.PreviousScan.Equal(before)) {
+				t.Errorf("PreviousScan = %v, want %v", got.PreviousScan, before)
+			}
+		})
+	}
+}
+
+func TestPortScanHistoryRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ghost", "history.json")
+
+	history, err := LoadPortScanHistory(path)
+	if err != nil || len(history.Scans) != 0 {
+		t.Fatalf("LoadPortScanHistory(missing) = %v, %v; want an empty history", history, err)
+	}
+
+	first := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	history.Scans = append(history.Scans,
+		NewPortScanSnapshot("a", []PortDetail{{Host: "a", Port: 22}}, first),
+		NewPortScanSnapshot("b", nil, first.Add(time.Minute)),
+		NewPortScanSnapshot("a", []PortDetail{{Host: "a", Port: 80}}, first.Add(time.Hour)),
+	)
+	if err := SavePortScanHistory(path, history); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	loaded, err := LoadPortScanHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := loaded.LastScan("a")
+	if last == nil || !last.Timestamp.Equal(first.Add(time.Hour)) || !equalRecordPorts(last.Ports, []int{80}) {
+		t.Errorf("LastScan(a) = %+v, want the scan of %v with port 80", last, first.Add(time.Hour))
+	}
+	if loaded.LastScan("c") != nil {
+		t.Error("LastScan(c) found a scan for unknown targets")
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPortScanHistory(path); err == nil {
+		t.Error("LoadPortScanHistory accepted a corrupt file")
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func recordPorts(records []PortScanRecord) []int {
+	var ports []int
+	for _, r := range records {
+		ports = append(ports, r.Port)
+	}
+	return ports
+}
+
+func equalRecordPorts(records []PortScanRecord, want []int) bool {
+	got := recordPorts(records)
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,409 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mwiater/ghost/utils"
+	"github.com/spf13/cobra"
+)
+
+// Output formats supported by `portscanner --output-format`.
+const (
+	PortScanFormatTable    = "table"
+	PortScanFormatJSON     = "json"
+	PortScanFormatNmapXML  = "nmap-xml"
+	PortScanFormatGrepable = "grepable"
+)
+
+// portScanOutputFormat reads --output-format (and its --json shorthand) and validates the value.
+func portScanOutputFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("output-format")
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		if cmd.Flags().Changed("output-format") && format != PortScanFormatJSON {
+			return "", fmt.Errorf("--json conflicts with --output-format %s", format)
+		}
+		format = PortScanFormatJSON
+	}
+	switch format {
+	case PortScanFormatTable, PortScanFormatJSON, PortScanFormatNmapXML, PortScanFormatGrepable:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (expected table, json, nmap-xml or grepable)", format)
+}
+
+// PrintPortScanResults writes the scan results to stdout in the requested format.
+// args is the command line that started the scan; it is recorded in the nmap-compatible formats.
+func PrintPortScanResults(results []PortScanResult, format string, args []string) error {
+	switch format {
+	case PortScanFormatJSON:
+		utils.PrintJSON(results)
+	case PortScanFormatNmapXML:
+		return writeNmapXML(os.Stdout, results, args)
+	case PortScanFormatGrepable:
+		return writeNmapGrepable(os.Stdout, results, args)
+	default:
+		var openPorts []PortDetail
+		var hosts []string
+		for _, result := range results {
+			openPorts = append(openPorts, result.OpenPorts...)
+			hosts = append(hosts, result.Host)
+		}
+		PrintPortScanSummary(openPorts, strings.Join(hosts, ", "))
+	}
+	return nil
+}
+
+// nmapRun mirrors the <nmaprun> root element of nmap's XML output (nmap.dtd, xmloutputversion 1.05).
+type nmapRun struct {
+	XMLName          xml.Name     `xml:"nmaprun"`
+	Scanner          string       `xml:"scanner,attr"`
+	Args             string       `xml:"args,attr"`
+	Start            int64        `xml:"start,attr"`
+	StartStr         string       `xml:"startstr,attr"`
+	Version          string       `xml:"version,attr"`
+	XMLOutputVersion string       `xml:"xmloutputversion,attr"`
+	ScanInfo         nmapScanInfo `xml:"scaninfo"`
+	Verbose          nmapLevel    `xml:"verbose"`
+	Debugging        nmapLevel    `xml:"debugging"`
+	Hosts            []nmapHost   `xml:"host"`
+	RunStats         nmapRunStats `xml:"runstats"`
+}
+
+type nmapScanInfo struct {
+	Type        string `xml:"type,attr"`
+	Protocol    string `xml:"protocol,attr"`
+	NumServices int    `xml:"numservices,attr"`
+	Services    string `xml:"services,attr"`
+}
+
+type nmapLevel struct {
+	Level int `xml:"level,attr"`
+}
+
+type nmapHost struct {
+	StartTime int64         `xml:"starttime,attr"`
+	EndTime   int64         `xml:"endtime,attr"`
+	Status    nmapStatus    `xml:"status"`
+	Address   nmapAddress   `xml:"address"`
+	Hostnames nmapHostnames `xml:"hostnames"`
+	Ports     nmapPorts     `xml:"ports"`
+	Times     nmapTimes     `xml:"times"`
+}
+
+type nmapStatus struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL int    `xml:"reason_ttl,attr"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type nmapHostnames struct {
+	Hostnames []nmapHostname `xml:"hostname"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPorts struct {
+	ExtraPorts []nmapExtraPorts `xml:"extraports"`
+	Ports      []nmapPort       `xml:"port"`
+}
+
+type nmapExtraPorts struct {
+	State   string            `xml:"state,attr"`
+	Count   int               `xml:"count,attr"`
+	Reasons []nmapExtraReason `xml:"extrareasons"`
+}
+
+type nmapExtraReason struct {
+	Reason string `xml:"reason,attr"`
+	Count  int    `xml:"count,attr"`
+}
+
+type nmapPort struct {
+	Protocol string       `xml:"protocol,attr"`
+	PortID   int          `xml:"portid,attr"`
+	State    nmapState    `xml:"state"`
+	Service  *nmapService `xml:"service,omitempty"`
+}
+
+type nmapState struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL int    `xml:"reason_ttl,attr"`
+}
+
+type nmapService struct {
+	Name   string `xml:"name,attr"`
+	Method string `xml:"method,attr"`
+	Conf   int    `xml:"conf,attr"`
+}
+
+type nmapTimes struct {
+	SRTT   int64 `xml:"srtt,attr"`
+	RTTVar int64 `xml:"rttvar,attr"`
+	TO     int64 `xml:"to,attr"`
+}
+
+type nmapRunStats struct {
+	Finished nmapFinished  `xml:"finished"`
+	Hosts    nmapHostStats `xml:"hosts"`
+}
+
+type nmapFinished struct {
+	Time    int64  `xml:"time,attr"`
+	TimeStr string `xml:"timestr,attr"`
+	Elapsed string `xml:"elapsed,attr"`
+	Summary string `xml:"summary,attr"`
+	Exit    string `xml:"exit,attr"`
+}
+
+type nmapHostStats struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+// nmapStateReasons maps port states to the reason nmap reports for a TCP connect scan.
+var nmapStateReasons = map[string]string{
+	PortStateOpen:     "syn-ack",
+	PortStateClosed:   "conn-refused",
+	PortStateFiltered: "no-response",
+}
+
+// writeNmapXML writes results as XML following nmap.dtd, so tools that parse nmap output can read
+// it. The scanner and version attributes name ghost, and args records the actual command line.
+func writeNmapXML(w io.Writer, results []PortScanResult, args []string) error {
+	start, end := portScanSpan(results)
+	run := nmapRun{
+		Scanner:          "ghost",
+		Args:             strings.Join(args, " "),
+		Start:            start.Unix(),
+		StartStr:         nmapTimeString(start),
+		Version:          GhostVersion(),
+		XMLOutputVersion: "1.05",
+		ScanInfo:         nmapScanInfo{Type: "connect", Protocol: "tcp"},
+	}
+	if len(results) > 0 {
+		run.ScanInfo.NumServices = results[0].EndPort - results[0].StartPort + 1
+		run.ScanInfo.Services = fmt.Sprintf("%d-%d", results[0].StartPort, results[0].EndPort)
+	}
+
+	up := 0
+	for _, result := range results {
+		host := nmapHost{
+			StartTime: result.StartTime.Unix(),
+			EndTime:   result.EndTime.Unix(),
+			Status:    nmapStatus{State: "down", Reason: "no-response"},
+			Address:   nmapAddress{Addr: result.Address, AddrType: nmapAddrType(result.Address)},
+			Times: nmapTimes{
+				SRTT:   result.SRTT.Microseconds(),
+				RTTVar: result.RTTVar.Microseconds(),
+				TO:     portProbeTimeout.Microseconds(),
+			},
+		}
+		if portScanHostUp(result) {
+			host.Status = nmapStatus{State: "up", Reason: "conn-refused"}
+			if len(result.OpenPorts) > 0 {
+				host.Status.Reason = "syn-ack"
+			}
+			up++
+		}
+		if result.Host != result.Address {
+			host.Hostnames.Hostnames = []nmapHostname{{Name: result.Host, Type: "user"}}
+		}
+
+		ignored, listed := portScanIgnoredState(result)
+		if count := len(portScanPortsInState(result, ignored)); count > 0 {
+			host.Ports.ExtraPorts = []nmapExtraPorts{{
+				State:   ignored,
+				Count:   count,
+				Reasons: []nmapExtraReason{{Reason: nmapStateReasons[ignored], Count: count}},
+			}}
+		}
+		for _, port := range portScanListedPorts(result, listed) {
+			nport := nmapPort{
+				Protocol: "tcp",
+				PortID:   port.port,
+				State:    nmapState{State: port.state, Reason: nmapStateReasons[port.state]},
+			}
+			if port.service != "unknown" {
+				nport.Service = &nmapService{Name: port.service, Method: "table", Conf: 3}
+			}
+			host.Ports.Ports = append(host.Ports.Ports, nport)
+		}
+		run.Hosts = append(run.Hosts, host)
+	}
+
+	run.RunStats = nmapRunStats{
+		Finished: nmapFinished{
+			Time:    end.Unix(),
+			TimeStr: nmapTimeString(end),
+			Elapsed: fmt.Sprintf("%.2f", end.Sub(start).Seconds()),
+			Summary: nmapDoneSummary(len(results), up, end.Sub(start)),
+			Exit:    "success",
+		},
+		Hosts: nmapHostStats{Up: up, Down: len(results) - up, Total: len(results)},
+	}
+
+	if _, err := io.WriteString(w, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(run); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeNmapGrepable writes results in nmap's grepable (-oG) format. The header carries ghost's
+// version where nmap puts its own, so parsers that match "# Nmap <version> scan initiated" accept it.
+func writeNmapGrepable(w io.Writer, results []PortScanResult, args []string) error {
+	start, end := portScanSpan(results)
+	up := 0
+
+	fmt.Fprintf(w, "# Nmap %s scan initiated %s as: %s\n", GhostVersion(), nmapTimeString(start), strings.Join(args, " "))
+	for _, result := range results {
+		label := fmt.Sprintf("Host: %s (%s)", result.Address, nmapGrepableHostname(result))
+		if !portScanHostUp(result) {
+			fmt.Fprintf(w, "%s\tStatus: Down\n", label)
+			continue
+		}
+		up++
+		fmt.Fprintf(w, "%s\tStatus: Up\n", label)
+
+		ignored, listed := portScanIgnoredState(result)
+		var entries []string
+		for _, port := range portScanListedPorts(result, listed) {
+			service := port.service
+			if service == "unknown" {
+				service = ""
+			}
+			entries = append(entries, fmt.Sprintf("%d/%s/tcp//%s///", port.port, port.state, service))
+		}
+		line := fmt.Sprintf("%s\tPorts: %s", label, strings.Join(entries, ", "))
+		if count := len(portScanPortsInState(result, ignored)); count > 0 {
+			line += fmt.Sprintf("\tIgnored State: %s (%d)", ignored, count)
+		}
+		fmt.Fprintln(w, line)
+	}
+	_, err := fmt.Fprintf(w, "# Nmap done at %s -- %s\n", nmapTimeString(end), nmapDoneSummary(len(results), up, end.Sub(start)))
+	return err
+}
+
+// listedPort is a port that is reported individually rather than folded into the ignored state.
+type listedPort struct {
+	port    int
+	state   string
+	service string
+}
+
+// portScanIgnoredState picks the most common non-open state, which nmap summarizes as extraports
+// ("Ignored State" in grepable output); ports in the other non-open state are listed individually.
+func portScanIgnoredState(result PortScanResult) (ignored string, listed string) {
+	if len(result.FilteredPorts) > len(result.ClosedPorts) {
+		return PortStateFiltered, PortStateClosed
+	}
+	return PortStateClosed, PortStateFiltered
+}
+
+// portScanPortsInState returns the non-open ports of result that are in the given state.
+func portScanPortsInState(result PortScanResult, state string) []int {
+	if state == PortStateFiltered {
+		return result.FilteredPorts
+	}
+	return result.ClosedPorts
+}
+
+// portScanListedPorts returns the open ports plus the ports in the listed non-open state, ordered by port.
+func portScanListedPorts(result PortScanResult, listedState string) []listedPort {
+	var ports []listedPort
+	open := 0
+	others := portScanPortsInState(result, listedState)
+	other := 0
+	for open < len(result.OpenPorts) || other < len(others) {
+		if other >= len(others) || (open < len(result.OpenPorts) && result.OpenPorts[open].Port < others[other]) {
+			detail := result.OpenPorts[open]
+			ports = append(ports, listedPort{port: detail.Port, state: PortStateOpen, service: detail.Service})
+			open++
+			continue
+		}
+		ports = append(ports, listedPort{port: others[other], state: listedState, service: serviceName(others[other], "tcp")})
+		other++
+	}
+	return ports
+}
+
+// portScanHostUp reports whether the host answered at all, i.e. at least one port was open or refused.
+func portScanHostUp(result PortScanResult) bool {
+	return len(result.OpenPorts) > 0 || len(result.ClosedPorts) > 0
+}
+
+// portScanSpan returns the earliest start and latest end time across all results.
+func portScanSpan(results []PortScanResult) (time.Time, time.Time) {
+	if len(results) == 0 {
+		now := time.Now()
+		return now, now
+	}
+	start, end := results[0].StartTime, results[0].EndTime
+	for _, result := range results[1:] {
+		if result.StartTime.Before(start) {
+			start = result.StartTime
+		}
+		if result.EndTime.After(end) {
+			end = result.EndTime
+		}
+	}
+	return start, end
+}
+
+// nmapAddrType returns the nmap addrtype attribute value for an address.
+func nmapAddrType(address string) string {
+	ip := net.ParseIP(strings.SplitN(address, "%", 2)[0])
+	if ip != nil && ip.To4() == nil {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// nmapGrepableHostname returns the hostname shown in parentheses in grepable output.
+func nmapGrepableHostname(result PortScanResult) string {
+	if result.Host == result.Address {
+		return ""
+	}
+	return result.Host
+}
+
+// nmapTimeString formats a time the way nmap does in startstr/timestr attributes.
+func nmapTimeString(t time.Time) string {
+	return t.Format("Mon Jan _2 15:04:05 2006")
+}
+
+// nmapDoneSummary builds nmap's closing summary line.
+func nmapDoneSummary(total, up int, elapsed time.Duration) string {
+	addresses := "IP addresses"
+	if total == 1 {
+		addresses = "IP address"
+	}
+	hosts := "hosts"
+	if up == 1 {
+		hosts = "host"
+	}
+	return fmt.Sprintf("%s %s (%s %s up) scanned in %s seconds",
+		strconv.Itoa(total), addresses, strconv.Itoa(up), hosts, strconv.FormatFloat(elapsed.Seconds(), 'f', 2, 64))
+}
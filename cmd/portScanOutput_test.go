@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteNmapXML(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	results := []PortScanResult{{
+		Host:          "router.lan",
+		Address:       "192.168.0.1",
+		StartPort:     20,
+		EndPort:       25,
+		StartTime:     start,
+		EndTime:       start.Add(2 * time.Second),
+		OpenPorts:     []PortDetail{{Host: "router.lan", Port: 22, Service: "ssh"}},
+		ClosedPorts:   []int{20, 21, 23, 24},
+		FilteredPorts: []int{25},
+	}}
+
+	var buf bytes.Buffer
+	if err := writeNmapXML(&buf, results, []string{"ghost", "portscanner"}); err != nil {
+		t.Fatal(err)
+	}
+	var run nmapRun
+	if err := xml.Unmarshal(buf.Bytes(), &run); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, buf.String())
+	}
+
+	if run.Scanner != "ghost" || run.Version != GhostVersion() || run.XMLOutputVersion != "1.05" {
+		t.Errorf("scanner=%q version=%q xmloutputversion=%q, want ghost %q 1.05", run.Scanner, run.Version, run.XMLOutputVersion, GhostVersion())
+	}
+	if run.Args != "ghost portscanner" {
+		t.Errorf("args = %q", run.Args)
+	}
+	if len(run.Hosts) != 1 {
+		t.Fatalf("got %d hosts, want 1", len(run.Hosts))
+	}
+	host := run.Hosts[0]
+	if host.Status.State != "up" || host.Address.AddrType != "ipv4" {
+		t.Errorf("status %q addrtype %q, want up ipv4", host.Status.State, host.Address.AddrType)
+	}
+	if len(host.Ports.ExtraPorts) != 1 || host.Ports.ExtraPorts[0].State != PortStateClosed || host.Ports.ExtraPorts[0].Count != 4 {
+		t.Errorf("extraports = %+v, want 4 closed", host.Ports.ExtraPorts)
+	}
+	listed := map[int]string{}
+	for _, port := range host.Ports.Ports {
+		listed[port.PortID] = port.State.State
+	}
+	if len(listed) != 2 || listed[22] != PortStateOpen || listed[25] != PortStateFiltered {
+		t.Errorf("listed ports = %v, want 22 open and 25 filtered", listed)
+	}
+	if run.RunStats.Hosts.Up != 1 || run.RunStats.Hosts.Total != 1 {
+		t.Errorf("runstats hosts = %+v", run.RunStats.Hosts)
+	}
+}
+
+func TestWriteNmapGrepable(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	results := []PortScanResult{
+		{
+			Host:          "router.lan",
+			Address:       "192.168.0.1",
+			StartTime:     start,
+			EndTime:       start.Add(2 * time.Second),
+			OpenPorts:     []PortDetail{{Host: "router.lan", Port: 22, Service: "ssh"}, {Host: "router.lan", Port: 8022, Service: "unknown"}},
+			ClosedPorts:   []int{20, 21, 23, 24},
+			FilteredPorts: []int{25},
+		},
+		{
+			// Mostly filtered, so the closed port is listed and the filtered ones are ignored
+			Host:          "192.168.0.2",
+			Address:       "192.168.0.2",
+			StartTime:     start.Add(time.Second),
+			EndTime:       start.Add(3500 * time.Millisecond),
+			OpenPorts:     []PortDetail{{Host: "192.168.0.2", Port: 80, Service: "http"}},
+			ClosedPorts:   []int{443},
+			FilteredPorts: []int{21, 22, 23},
+		},
+		{
+			Host:          "192.168.0.3",
+			Address:       "192.168.0.3",
+			StartTime:     start,
+			EndTime:       start.Add(3 * time.Second),
+			FilteredPorts: []int{22, 80},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeNmapGrepable(&buf, results, []string{"ghost", "portscanner", "--output-format", "grepable"}); err != nil {
+		t.Fatal(err)
+	}
+	// The golden file holds VERSION where the header names the version of the test binary
+	got := strings.Replace(buf.String(), "# Nmap "+GhostVersion()+" ", "# Nmap VERSION ", 1)
+	want, err := os.ReadFile(filepath.Join("testdata", "portscan", "grepable.gnmap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("grepable output =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import "strings"
+
+// wellKnownTCPServices maps commonly used TCP ports to their IANA service names.
+// It is intentionally small: it only needs to label the ports people usually care about.
+var wellKnownTCPServices = map[int]string{
+	20:    "ftp-data",
+	21:    "ftp",
+	22:    "ssh",
+	23:    "telnet",
+	25:    "smtp",
+	53:    "domain",
+	67:    "dhcps",
+	68:    "dhcpc",
+	69:    "tftp",
+	80:    "http",
+	88:    "kerberos-sec",
+	110:   "pop3",
+	111:   "rpcbind",
+	119:   "nntp",
+	123:   "ntp",
+	135:   "msrpc",
+	137:   "netbios-ns",
+	139:   "netbios-ssn",
+	143:   "imap",
+	161:   "snmp",
+	179:   "bgp",
+	389:   "ldap",
+	443:   "https",
+	445:   "microsoft-ds",
+	465:   "smtps",
+	514:   "shell",
+	515:   "printer",
+	548:   "afp",
+	554:   "rtsp",
+	587:   "submission",
+	631:   "ipp",
+	636:   "ldapssl",
+	873:   "rsync",
+	902:   "iss-realsecure",
+	993:   "imaps",
+	995:   "pop3s",
+	1080:  "socks",
+	1433:  "ms-sql-s",
+	1521:  "oracle",
+	1723:  "pptp",
+	1883:  "mqtt",
+	2049:  "nfs",
+	2375:  "docker",
+	2376:  "docker-s",
+	3000:  "ppp",
+	3306:  "mysql",
+	3389:  "ms-wbt-server",
+	5000:  "upnp",
+	5060:  "sip",
+	5432:  "postgresql",
+	5672:  "amqp",
+	5900:  "vnc",
+	5985:  "wsman",
+	5986:  "wsmans",
+	6379:  "redis",
+	6443:  "sun-sr-https",
+	8000:  "http-alt",
+	8080:  "http-proxy",
+	8443:  "https-alt",
+	9000:  "cslistener",
+	9090:  "zeus-admin",
+	9092:  "XmlIpcRegSvc",
+	9200:  "wap-wsp",
+	11211: "memcache",
+	27017: "mongod",
+}
+
+// wellKnownUDPServices maps commonly used UDP ports to their IANA service names.
+var wellKnownUDPServices = map[int]string{
+	53:   "domain",
+	67:   "dhcps",
+	68:   "dhcpc",
+	69:   "tftp",
+	123:  "ntp",
+	137:  "netbios-ns",
+	138:  "netbios-dgm",
+	161:  "snmp",
+	162:  "snmptrap",
+	443:  "https",
+	500:  "isakmp",
+	514:  "syslog",
+	1194: "openvpn",
+	1900: "ssdp",
+	4500: "ipsec-nat-t",
+	5353: "mdns",
+}
+
+// serviceName returns the well-known service name for a port and protocol, or "unknown".
+func serviceName(port int, proto string) string {
+	services := wellKnownTCPServices
+	if strings.EqualFold(proto, "udp") {
+		services = wellKnownUDPServices
+	} else if !strings.EqualFold(proto, "tcp") {
+		return "unknown"
+	}
+	if name, ok := services[port]; ok {
+		return name
+	}
+	return "unknown"
+}
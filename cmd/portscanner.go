@@ -4,13 +4,17 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"net"
+	"os"
 	"os/exec"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
@@ -21,53 +25,174 @@ import (
 
 // PortDetail holds comprehensive information about an open port.
 type PortDetail struct {
-	Port     int
-	Process  string
-	PID      string
-	Owner    string
-	Protocol string
-	State    string
-	Local    string
-	Foreign  string
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Service  string `json:"service"`
+	Process  string `json:"process"`
+	PID      string `json:"pid"`
+	Owner    string `json:"owner"`
+	Protocol string `json:"protocol"`
+	State    string `json:"state"`
+	Local    string `json:"local"`
+	Foreign  string `json:"foreign"`
 }
 
+// PortScanResult holds the outcome of scanning a range of ports on a single host.
+type PortScanResult struct {
+	Host          string        `json:"host"`
+	Address       string        `json:"address"`
+	StartPort     int           `json:"startPort"`
+	EndPort       int           `json:"endPort"`
+	StartTime     time.Time     `json:"startTime"`
+	EndTime       time.Time     `json:"endTime"`
+	OpenPorts     []PortDetail  `json:"openPorts"`
+	ClosedPorts   []int         `json:"closedPorts"`
+	FilteredPorts []int         `json:"filteredPorts"`
+	SRTT          time.Duration `json:"srtt"`
+	RTTVar        time.Duration `json:"rttvar"`
+}
+
+// Port states reported by probePort, named after nmap's port states.
+const (
+	PortStateOpen     = "open"
+	PortStateClosed   = "closed"
+	PortStateFiltered = "filtered"
+)
+
+// portProbeTimeout is how long a single TCP connect attempt may take before the port is considered filtered.
+const portProbeTimeout = 1 * time.Second
+
 // PortScannerCmd defines the Cobra command for scanning a range of ports on a specified host.
 var PortScannerCmd = &cobra.Command{
 	Use:   "portscanner",
 	Short: "Scans a range of ports on a specified host",
+	Long: `Scans a range of TCP ports on one or more hosts. --host accepts a comma-separated list of
+host names, IPv4 or IPv6 addresses (scoped link-local addresses such as fe80::1%eth0 included)
+and CIDR blocks.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		host, _ := cmd.Flags().GetString("host")
 		startPort, _ := cmd.Flags().GetInt("start-port")
 		endPort, _ := cmd.Flags().GetInt("end-port")
 
+		family, err := addressFamilyFlag(cmd)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		targets, err := ParseScanTargets(host, family)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		savePath, _ := cmd.Flags().GetString("save")
+		compare, _ := cmd.Flags().GetBool("compare")
+		outputFormat, err := portScanOutputFormat(cmd)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if compare && savePath == "" {
+			fmt.Println("Error: --compare requires --save <file> to locate the scan history")
+			os.Exit(1)
+		}
+		if compare && outputFormat != PortScanFormatTable && outputFormat != PortScanFormatJSON {
+			fmt.Printf("Error: --compare supports the %q and %q output formats only\n", PortScanFormatTable, PortScanFormatJSON)
+			os.Exit(1)
+		}
+
 		numWorkers := runtime.NumCPU() // Limit concurrency to the number of available CPUs
-		openPorts := RunPortScanner(host, startPort, endPort, numWorkers)
-		PrintPortScanSummary(openPorts, host)
+		var results []PortScanResult
+		var openPorts []PortDetail
+		for _, target := range targets {
+			result := RunPortScan(target, startPort, endPort, numWorkers)
+			results = append(results, result)
+			openPorts = append(openPorts, result.OpenPorts...)
+		}
+		scanStart := results[0].StartTime
+
+		if savePath != "" {
+			history, err := LoadPortScanHistory(savePath)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			snapshot := NewPortScanSnapshot(portScanTargetsKey(host, startPort, endPort), openPorts, scanStart)
+			comparison := ComparePortScans(history.LastScan(snapshot.Targets), snapshot)
+
+			history.Scans = append(history.Scans, snapshot)
+			if err := SavePortScanHistory(savePath, history); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+
+			if compare {
+				if outputFormat == PortScanFormatJSON {
+					utils.PrintJSON(comparison)
+				} else {
+					PrintPortScanComparison(comparison)
+				}
+				if comparison.ChangesFound {
+					os.Exit(utils.FindingsExitCode)
+				}
+				return
+			}
+		}
+
+		if err := PrintPortScanResults(results, outputFormat, os.Args); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
 	},
 }
 
 // init registers the PortScannerCmd with the root command and defines command-line flags.
 func init() {
 	RootCmd.AddCommand(PortScannerCmd)
-	PortScannerCmd.Flags().StringP("host", "H", "localhost", "Host to scan")
+	PortScannerCmd.Flags().StringP("host", "H", "localhost", "Hosts, addresses or CIDR blocks to scan (comma-separated)")
+	PortScannerCmd.Flags().BoolP("ipv4", "4", false, "Resolve and scan IPv4 addresses only")
+	PortScannerCmd.Flags().BoolP("ipv6", "6", false, "Resolve and scan IPv6 addresses only")
 	PortScannerCmd.Flags().IntP("start-port", "s", 1, "Starting port to scan")
 	PortScannerCmd.Flags().IntP("end-port", "e", 1024, "Ending port to scan")
+	PortScannerCmd.Flags().String("save", "", "Append the results to this scan history file")
+	PortScannerCmd.Flags().Bool("compare", false, "Report ports opened or closed since the last scan saved with --save (exits 2 on changes)")
+	PortScannerCmd.Flags().String("output-format", PortScanFormatTable, "Output format: table, json, nmap-xml or grepable")
+	PortScannerCmd.Flags().Bool("json", false, "Output results as JSON (shorthand for --output-format json)")
 }
 
 // RunPortScanner executes the port scanning process for a specified host and port range without printing.
+// It returns only the open ports; use RunPortScan for the full result including timings and port states.
 func RunPortScanner(host string, startPort, endPort, numWorkers int) []PortDetail {
-	return scanPortsConcurrently(host, startPort, endPort, numWorkers)
+	return RunPortScan(ScanTarget{Name: host, Address: host}, startPort, endPort, numWorkers).OpenPorts
+}
+
+// RunPortScan scans a port range on a target and returns the open, closed and filtered ports with timings.
+func RunPortScan(target ScanTarget, startPort, endPort, numWorkers int) PortScanResult {
+	result := PortScanResult{
+		Host:      target.Name,
+		Address:   target.Address,
+		StartPort: startPort,
+		EndPort:   endPort,
+		StartTime: time.Now(),
+	}
+	scanPortsConcurrently(&result, numWorkers)
+	result.EndTime = time.Now()
+	return result
 }
 
 // scanPortsConcurrently scans ports using multiple workers, displaying progress with a progress bar.
-func scanPortsConcurrently(host string, start, end, numWorkers int) []PortDetail {
-	var openPorts []PortDetail
-	var mu sync.Mutex // Mutex to protect access to the openPorts slice
+// Port states and round-trip times are recorded in result.
+func scanPortsConcurrently(result *PortScanResult, numWorkers int) {
+	var mu sync.Mutex // Mutex to protect access to the result
+	var rtts []time.Duration
 
-	totalPorts := end - start + 1
+	host := result.Address
+	totalPorts := result.EndPort - result.StartPort + 1
 	updateFrequency := 20 // Frequency of progress bar updates
 	progressBar := progressbar.NewOptions(totalPorts,
 		progressbar.OptionSetDescription("Scanning ports"),
+		progressbar.OptionSetWriter(os.Stderr),
 		progressbar.OptionFullWidth(),
 	)
 
@@ -81,19 +206,30 @@ func scanPortsConcurrently(host string, start, end, numWorkers int) []PortDetail
 		go func() {
 			defer wg.Done()
 			for port := range portCh {
-				if scanPort(host, port) {
-					details := getPortDetails(host, port)
-					mu.Lock()
-					openPorts = append(openPorts, details)
-					mu.Unlock()
+				state, rtt := probePort(host, port)
+				var details PortDetail
+				if state == PortStateOpen {
+					details = getPortDetails(host, port)
+					details.Host = result.Host
 				}
 
-				// Increment the number of scanned ports
 				mu.Lock()
+				switch state {
+				case PortStateOpen:
+					result.OpenPorts = append(result.OpenPorts, details)
+					rtts = append(rtts, rtt)
+				case PortStateClosed:
+					result.ClosedPorts = append(result.ClosedPorts, port)
+					rtts = append(rtts, rtt)
+				default:
+					result.FilteredPorts = append(result.FilteredPorts, port)
+				}
+
+				// Increment the number of scanned ports
 				scannedPorts++
 				// Only update the progress bar every nth port scan
 				if scannedPorts%updateFrequency == 0 || scannedPorts == totalPorts {
-					progressBar.Describe(fmt.Sprintf("%d/%d ports scanned (%d open ports so far)", scannedPorts, totalPorts, len(openPorts)))
+					progressBar.Describe(fmt.Sprintf("%d/%d ports scanned (%d open ports so far)", scannedPorts, totalPorts, len(result.OpenPorts)))
 					progressBar.Add(updateFrequency)
 				}
 				mu.Unlock()
@@ -102,7 +238,7 @@ func scanPortsConcurrently(host string, start, end, numWorkers int) []PortDetail
 	}
 
 	// Distribute ports to workers
-	for port := start; port <= end; port++ {
+	for port := result.StartPort; port <= result.EndPort; port++ {
 		portCh <- port
 	}
 	close(portCh) // Close the channel to signal workers to stop
@@ -110,19 +246,74 @@ func scanPortsConcurrently(host string, start, end, numWorkers int) []PortDetail
 	// Wait for all workers to finish
 	wg.Wait()
 
-	fmt.Println() // Print a new line after progress bar completes
-	return openPorts
+	fmt.Fprintln(os.Stderr) // Print a new line after progress bar completes
+	sort.Slice(result.OpenPorts, func(i, j int) bool { return result.OpenPorts[i].Port < result.OpenPorts[j].Port })
+	sort.Ints(result.ClosedPorts)
+	sort.Ints(result.FilteredPorts)
+	result.SRTT, result.RTTVar = rttStats(rtts)
 }
 
-// scanPort checks if a specific port on the host is open by attempting to establish a TCP connection.
-func scanPort(host string, port int) bool {
+// probePort attempts a TCP connection to a port and classifies it as open, closed (refused) or
+// filtered (timed out or unreachable), returning the connect round-trip time.
+func probePort(host string, port int) (string, time.Duration) {
 	address := net.JoinHostPort(host, strconv.Itoa(port))
-	conn, err := net.DialTimeout("tcp", address, 1*time.Second)
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, portProbeTimeout)
+	rtt := time.Since(start)
 	if err != nil {
-		return false
+		if isConnectionRefused(err) {
+			return PortStateClosed, rtt
+		}
+		return PortStateFiltered, rtt
 	}
 	conn.Close()
-	return true
+	return PortStateOpen, rtt
+}
+
+// isConnectionRefused reports whether err was caused by the remote host actively refusing the connection.
+func isConnectionRefused(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	// 10061 is WSAECONNREFUSED, which Windows reports instead of ECONNREFUSED.
+	return errno == syscall.ECONNREFUSED || errno == 10061
+}
+
+// addressFamilyFlag reads the --ipv4/--ipv6 flags of a command and returns the selected address family.
+func addressFamilyFlag(cmd *cobra.Command) (string, error) {
+	ipv4, _ := cmd.Flags().GetBool("ipv4")
+	ipv6, _ := cmd.Flags().GetBool("ipv6")
+	switch {
+	case ipv4 && ipv6:
+		return "", fmt.Errorf("--ipv4 and --ipv6 are mutually exclusive")
+	case ipv4:
+		return FamilyIPv4, nil
+	case ipv6:
+		return FamilyIPv6, nil
+	}
+	return FamilyAny, nil
+}
+
+// rttStats returns the mean and mean deviation of the given round-trip times.
+func rttStats(rtts []time.Duration) (time.Duration, time.Duration) {
+	if len(rtts) == 0 {
+		return 0, 0
+	}
+	var sum time.Duration
+	for _, rtt := range rtts {
+		sum += rtt
+	}
+	mean := sum / time.Duration(len(rtts))
+	var deviation time.Duration
+	for _, rtt := range rtts {
+		d := rtt - mean
+		if d < 0 {
+			d = -d
+		}
+		deviation += d
+	}
+	return mean, deviation / time.Duration(len(rtts))
 }
 
 // PrintPortScanSummary displays the final summary of the port scan results in a table.
@@ -132,7 +323,9 @@ func PrintPortScanSummary(openPorts []PortDetail, host string) {
 	// Prepare the table using utils.Table for consistent formatting
 	t := utils.Table("DarkSimple", "Port Scan Results")
 	t.AppendHeader(table.Row{
+		"Host",
 		"Port",
+		"Service",
 		"Protocol",
 		"Local Address",
 		"Foreign Address",
@@ -144,12 +337,14 @@ func PrintPortScanSummary(openPorts []PortDetail, host string) {
 
 	if len(openPorts) == 0 {
 		// If no open ports are found, show a message
-		t.AppendRow(table.Row{"-", "-", "-", "-", "-", "-", "-", "-"})
+		t.AppendRow(table.Row{host, "-", "-", "-", "-", "-", "-", "-", "-", "-"})
 	} else {
 		// Add each open port to the table
 		for _, port := range openPorts {
 			t.AppendRow(table.Row{
+				port.Host,
 				strconv.Itoa(port.Port),
+				port.Service,
 				port.Protocol,
 				port.Local,
 				port.Foreign,
@@ -170,7 +365,9 @@ func PrintPortScanSummary(openPorts []PortDetail, host string) {
 // getPortDetails retrieves detailed port information depending on the operating system.
 func getPortDetails(host string, port int) PortDetail {
 	var detail PortDetail
+	detail.Host = host
 	detail.Port = port
+	detail.Service = serviceName(port, "tcp")
 
 	switch runtime.GOOS {
 	case "linux", "darwin":
@@ -183,7 +380,7 @@ func getPortDetails(host string, port int) PortDetail {
 			detail.Owner = "N/A"
 			detail.Protocol = "TCP"
 			detail.State = "LISTEN"
-			detail.Local = fmt.Sprintf("%s:%d", host, port)
+			detail.Local = net.JoinHostPort(host, strconv.Itoa(port))
 			detail.Foreign = "N/A"
 			return detail
 		}
@@ -221,7 +418,7 @@ func getPortDetails(host string, port int) PortDetail {
 			detail.Owner = "N/A"
 			detail.Protocol = "N/A"
 			detail.State = "N/A"
-			detail.Local = fmt.Sprintf("%s:%d", host, port)
+			detail.Local = net.JoinHostPort(host, strconv.Itoa(port))
 			detail.Foreign = "N/A"
 			return detail
 		}
@@ -259,7 +456,7 @@ func getPortDetails(host string, port int) PortDetail {
 			detail.Owner = "N/A"
 			detail.Protocol = "TCP"
 			detail.State = "LISTEN"
-			detail.Local = fmt.Sprintf("%s:%d", host, port)
+			detail.Local = net.JoinHostPort(host, strconv.Itoa(port))
 			detail.Foreign = "N/A"
 		}
 	default:
@@ -268,7 +465,7 @@ func getPortDetails(host string, port int) PortDetail {
 		detail.Owner = "N/A"
 		detail.Protocol = "N/A"
 		detail.State = "N/A"
-		detail.Local = fmt.Sprintf("%s:%d", host, port)
+		detail.Local = net.JoinHostPort(host, strconv.Itoa(port))
 		detail.Foreign = "N/A"
 	}
 
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+	"github.com/spf13/cobra"
+)
+
+// ReachCmd tests which ports of a host running `ghost listen` can be reached.
+var ReachCmd = &cobra.Command{
+	Use:   "reach <host>",
+	Short: "Tests firewall rules against a host running ghost listen",
+	Long: `Tests each port against a "ghost listen" instance on the host: a random token is sent over TCP
+or UDP and the listener must send it back. Each port is reported as:
+
+  reachable    the listener answered with the token
+  refused      the connection was refused (TCP reset or ICMP port unreachable)
+  filtered     nothing came back before the timeout (dropped by a firewall)
+  intercepted  something answered or accepted the connection, but not the listener
+               (a transparent proxy, a port forward to another service, ...)
+
+Exits with status 2 when any port is not reachable.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		spec, _ := cmd.Flags().GetString("ports")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		opts := ReachOptions{}
+		opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+		opts.Retries, _ = cmd.Flags().GetInt("retries")
+		opts.Concurrency, _ = cmd.Flags().GetInt("concurrency")
+
+		var err error
+		opts.Family, err = addressFamilyFlag(cmd)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		ports, err := ParsePortSpecs(spec)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		report, err := RunReach(args[0], ports, opts)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if jsonOutput {
+			utils.PrintJSON(report)
+		} else {
+			PrintReachReport(report)
+		}
+		for _, result := range report.Results {
+			if result.Status != ReachReachable {
+				os.Exit(utils.FindingsExitCode)
+			}
+		}
+	},
+}
+
+// init registers the ReachCmd with the root command when this package is imported.
+func init() {
+	RootCmd.AddCommand(ReachCmd)
+	ReachCmd.Flags().StringP("ports", "p", "", "Ports to test, e.g. 8000-8010,9000/udp (tcp unless /udp is given)")
+	ReachCmd.Flags().Duration("timeout", 2*time.Second, "Time to wait for the connection and for the answer")
+	ReachCmd.Flags().Int("retries", 2, "Extra challenges sent to UDP ports that did not answer")
+	ReachCmd.Flags().Int("concurrency", 32, "Number of ports tested at the same time")
+	ReachCmd.Flags().BoolP("ipv4", "4", false, "Resolve and test IPv4 addresses only")
+	ReachCmd.Flags().BoolP("ipv6", "6", false, "Resolve and test IPv6 addresses only")
+	ReachCmd.Flags().Bool("json", false, "Print the results as JSON")
+}
+
+// PrintReachReport displays the reachability of every port tested.
+func PrintReachReport(report ReachReport) {
+	counts := make(map[string]int)
+	t := utils.Table("DarkSimple", "Reachability")
+	t.AppendHeader(table.Row{"Port", "Status", "RTT", "Detail"})
+	for _, result := range report.Results {
+		counts[result.Status]++
+		rtt := "-"
+		if result.RTT > 0 {
+			rtt = formatPingRTT(result.RTT)
+		}
+		t.AppendRow(table.Row{PortSpec{Port: result.Port, Protocol: result.Protocol}, result.Status, rtt, valueOrDash(result.Detail)})
+	}
+	fmt.Println()
+	t.Render()
+	fmt.Printf("%s (%s): %d reachable, %d refused, %d filtered, %d intercepted.\n", report.Host, report.Address,
+		counts[ReachReachable], counts[ReachRefused], counts[ReachFiltered], counts[ReachIntercepted])
+	fmt.Println()
+}
@@ -0,0 +1,426 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reachability of a port as reported by RunReach.
+const (
+	ReachReachable   = "reachable"   // The listener answered the challenge
+	ReachRefused     = "refused"     // The connection was refused (TCP RST or ICMP port unreachable)
+	ReachFiltered    = "filtered"    // Nothing came back before the timeout
+	ReachIntercepted = "intercepted" // Something answered, but not the listener
+)
+
+const (
+	// reachChallenge starts the line a prober sends; the token follows it.
+	reachChallenge = "GHOST-REACH"
+	// reachAnswer starts the listener's answer: the token, then the port it arrived on.
+	reachAnswer = "GHOST-REACH-OK"
+	// reachListenTimeout limits how long the listener waits for the challenge on a connection.
+	reachListenTimeout = 5 * time.Second
+)
+
+// PortSpec is a port and the protocol to use on it.
+type PortSpec struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"` // "tcp" or "udp"
+}
+
+// String returns the port as "8000/tcp".
+func (p PortSpec) String() string {
+	return fmt.Sprintf("%d/%s", p.Port, p.Protocol)
+}
+
+// ParsePortSpecs parses a comma-separated list of ports and port ranges, each optionally
+// followed by /tcp or /udp (default tcp), e.g. "8000-8010,9000/udp". The result is sorted and
+// free of duplicates.
+func ParsePortSpecs(spec string) ([]PortSpec, error) {
+	seen := make(map[PortSpec]bool)
+	var ports []PortSpec
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		protocol := "tcp"
+		if i := strings.LastIndex(entry, "/"); i >= 0 {
+			protocol = strings.ToLower(entry[i+1:])
+			entry = entry[:i]
+			if protocol != "tcp" && protocol != "udp" {
+				return nil, fmt.Errorf("invalid protocol %q in port list (use tcp or udp)", protocol)
+			}
+		}
+		// The entry holds no comma, so it is a single port or range
+		ranges, err := ParsePortRanges(entry)
+		if err != nil {
+			return nil, err
+		}
+		for port := ranges[0].First; port <= ranges[0].Last; port++ {
+			p := PortSpec{Port: int(port), Protocol: protocol}
+			if !seen[p] {
+				seen[p] = true
+				ports = append(ports, p)
+			}
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports specified")
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Port != ports[j].Port {
+			return ports[i].Port < ports[j].Port
+		}
+		return ports[i].Protocol < ports[j].Protocol
+	})
+	return ports, nil
+}
+
+// ReachEvent is a connection or datagram received by a listener.
+type ReachEvent struct {
+	Time      time.Time `json:"time"`
+	Protocol  string    `json:"protocol"`
+	Port      int       `json:"port"`
+	Source    string    `json:"source"`
+	Challenge bool      `json:"challenge"` // It carried a reach challenge, which was answered
+	Bytes     int       `json:"bytes"`     // Bytes received before the challenge was answered or the connection ended
+	Error     string    `json:"error,omitempty"`
+}
+
+// ReachListener listens on a set of TCP and UDP ports, reports everything it receives and
+// answers reach challenges.
+type ReachListener struct {
+	// OnEvent, when set, is called for every connection and datagram received. It may be
+	// called from several goroutines at once.
+	OnEvent func(ReachEvent)
+
+	listeners []net.Listener
+	packets   []net.PacketConn
+	wg        sync.WaitGroup
+}
+
+// NewReachListener opens listeners on bind for every port. Ports that cannot be opened are
+// returned as errors; the listener is usable as long as at least one port is open.
+func NewReachListener(bind string, ports []PortSpec) (*ReachListener, []error) {
+	l := &ReachListener{}
+	var errs []error
+	for _, port := range ports {
+		address := net.JoinHostPort(bind, strconv.Itoa(port.Port))
+		var err error
+		if port.Protocol == "udp" {
+			var conn net.PacketConn
+			if conn, err = net.ListenPacket("udp", address); err == nil {
+				l.packets = append(l.packets, conn)
+			}
+		} else {
+			var listener net.Listener
+			if listener, err = net.Listen("tcp", address); err == nil {
+				l.listeners = append(l.listeners, listener)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", port, err))
+		}
+	}
+	return l, errs
+}
+
+// Ports returns the number of ports the listener has open.
+func (l *ReachListener) Ports() int {
+	return len(l.listeners) + len(l.packets)
+}
+
+// Serve answers connections and datagrams on every port until the listener is closed.
+func (l *ReachListener) Serve() {
+	for _, listener := range l.listeners {
+		l.wg.Add(1)
+		go l.acceptTCP(listener)
+	}
+	for _, conn := range l.packets {
+		l.wg.Add(1)
+		go l.readUDP(conn)
+	}
+	l.wg.Wait()
+}
+
+// Close closes every port.
+func (l *ReachListener) Close() {
+	for _, listener := range l.listeners {
+		listener.Close()
+	}
+	for _, conn := range l.packets {
+		conn.Close()
+	}
+}
+
+// event passes an event to OnEvent.
+func (l *ReachListener) event(event ReachEvent) {
+	if l.OnEvent != nil {
+		l.OnEvent(event)
+	}
+}
+
+// acceptTCP answers the connections of one TCP port.
+func (l *ReachListener) acceptTCP(listener net.Listener) {
+	defer l.wg.Done()
+	port := listener.Addr().(*net.TCPAddr).Port
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go func() {
+			defer conn.Close()
+			event := ReachEvent{Time: time.Now(), Protocol: "tcp", Port: port, Source: conn.RemoteAddr().String()}
+			conn.SetDeadline(time.Now().Add(reachListenTimeout))
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			event.Bytes = len(line)
+			if token, ok := parseReachChallenge(line); ok {
+				_, err = fmt.Fprintf(conn, "%s %s %d\n", reachAnswer, token, port)
+				event.Challenge = err == nil
+			}
+			if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) && event.Bytes == 0 {
+				event.Error = err.Error()
+			}
+			l.event(event)
+		}()
+	}
+}
+
+// readUDP answers the datagrams of one UDP port.
+func (l *ReachListener) readUDP(conn net.PacketConn) {
+	defer l.wg.Done()
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	buf := make([]byte, 65536)
+	for {
+		n, source, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		event := ReachEvent{Time: time.Now(), Protocol: "udp", Port: port, Source: source.String(), Bytes: n}
+		if token, ok := parseReachChallenge(string(buf[:n])); ok {
+			_, err = conn.WriteTo([]byte(fmt.Sprintf("%s %s %d\n", reachAnswer, token, port)), source)
+			event.Challenge = err == nil
+			if err != nil {
+				event.Error = err.Error()
+			}
+		}
+		l.event(event)
+	}
+}
+
+// parseReachChallenge returns the token of a challenge line.
+func parseReachChallenge(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 || fields[0] != reachChallenge {
+		return "", false
+	}
+	return fields[1], true
+}
+
+// ReachOptions configures RunReach.
+type ReachOptions struct {
+	Family      string        // FamilyAny, FamilyIPv4 or FamilyIPv6
+	Timeout     time.Duration // Time to wait for a connection and for the answer
+	Retries     int           // Extra challenges sent on UDP ports that did not answer
+	Concurrency int           // Number of ports tested at the same time
+}
+
+// withDefaults returns the options with zero values replaced by defaults.
+func (o ReachOptions) withDefaults() ReachOptions {
+	if o.Timeout <= 0 {
+		o.Timeout = 2 * time.Second
+	}
+	if o.Retries < 0 {
+		o.Retries = 0
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 32
+	}
+	return o
+}
+
+// ReachResult is the outcome of testing one port.
+type ReachResult struct {
+	Port     int           `json:"port"`
+	Protocol string        `json:"protocol"`
+	Status   string        `json:"status"`
+	RTT      time.Duration `json:"rtt"` // Time from sending the challenge to the answer
+	Detail   string        `json:"detail,omitempty"`
+}
+
+// ReachReport is the outcome of testing every port of a host.
+type ReachReport struct {
+	Host    string        `json:"host"`
+	Address string        `json:"address"`
+	Results []ReachResult `json:"results"`
+}
+
+// RunReach tests each port of host against a listener started with `ghost listen`, sending a
+// random token and checking that it comes back. host must be a single host or address.
+func RunReach(host string, ports []PortSpec, opts ReachOptions) (ReachReport, error) {
+	opts = opts.withDefaults()
+	report := ReachReport{Host: host, Results: make([]ReachResult, len(ports))}
+	targets, err := ParseScanTargets(host, opts.Family)
+	if err != nil {
+		return report, err
+	}
+	if len(targets) != 1 {
+		return report, fmt.Errorf("reach tests one host at a time, but %q is %d addresses", host, len(targets))
+	}
+	report.Address = targets[0].Address
+
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, port PortSpec) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			address := net.JoinHostPort(report.Address, strconv.Itoa(port.Port))
+			if port.Protocol == "udp" {
+				report.Results[i] = reachUDP(address, port, opts)
+			} else {
+				report.Results[i] = reachTCP(address, port, opts)
+			}
+		}(i, port)
+	}
+	wg.Wait()
+	return report, nil
+}
+
+// reachToken returns a random challenge token.
+func reachToken() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// reachTCP connects to a TCP port, sends a challenge and checks the answer.
+func reachTCP(address string, port PortSpec, opts ReachOptions) ReachResult {
+	result := ReachResult{Port: port.Port, Protocol: port.Protocol}
+	conn, err := net.DialTimeout("tcp", address, opts.Timeout)
+	if err != nil {
+		result.Status = ReachFiltered
+		if isConnectionRefused(err) {
+			result.Status = ReachRefused
+		}
+		result.Detail = reachErrorDetail(err)
+		return result
+	}
+	defer conn.Close()
+
+	token := reachToken()
+	start := time.Now()
+	conn.SetDeadline(start.Add(opts.Timeout))
+	if _, err := fmt.Fprintf(conn, "%s %s\n", reachChallenge, token); err != nil {
+		result.Status = ReachIntercepted
+		result.Detail = "connection accepted, but sending failed: " + reachErrorDetail(err)
+		return result
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	result.RTT = time.Since(start)
+	if err != nil && line == "" {
+		result.Status = ReachIntercepted
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			result.Detail = "connection accepted, but no answer"
+		} else {
+			result.Detail = "connection accepted, then closed without an answer"
+		}
+		return result
+	}
+	result.Status, result.Detail = checkReachAnswer(line, token, port.Port)
+	return result
+}
+
+// reachUDP sends challenges to a UDP port until one is answered or the retries run out.
+func reachUDP(address string, port PortSpec, opts ReachOptions) ReachResult {
+	result := ReachResult{Port: port.Port, Protocol: port.Protocol, Status: ReachFiltered, Detail: "no answer"}
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		result.Detail = reachErrorDetail(err)
+		return result
+	}
+	defer conn.Close()
+
+	token := reachToken()
+	buf := make([]byte, 2048)
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		start := time.Now()
+		if _, err := fmt.Fprintf(conn, "%s %s\n", reachChallenge, token); err != nil {
+			result.Detail = reachErrorDetail(err)
+			continue
+		}
+		conn.SetReadDeadline(start.Add(opts.Timeout))
+		n, err := conn.Read(buf)
+		if err != nil {
+			// A connected UDP socket reports ICMP port unreachable as a refused read
+			if isConnectionRefused(err) {
+				result.Status, result.Detail = ReachRefused, "ICMP port unreachable"
+				return result
+			}
+			continue
+		}
+		result.RTT = time.Since(start)
+		result.Status, result.Detail = checkReachAnswer(string(buf[:n]), token, port.Port)
+		return result
+	}
+	return result
+}
+
+// checkReachAnswer compares an answer with the expected token. An answer with the right token
+// from a different port means the traffic was forwarded, which is reported in the detail.
+func checkReachAnswer(answer, token string, port int) (string, string) {
+	fields := strings.Fields(answer)
+	if len(fields) != 3 || fields[0] != reachAnswer || fields[1] != token {
+		return ReachIntercepted, "unexpected answer " + strconv.Quote(reachSnippet(answer))
+	}
+	if fields[2] != strconv.Itoa(port) {
+		return ReachReachable, "answered by the listener on port " + fields[2]
+	}
+	return ReachReachable, ""
+}
+
+// reachSnippet shortens an unexpected answer for display.
+func reachSnippet(answer string) string {
+	answer = strings.TrimSpace(answer)
+	if len(answer) > 40 {
+		return answer[:40] + "..."
+	}
+	return answer
+}
+
+// reachErrorDetail describes a connection error without repeating the address.
+func reachErrorDetail(err error) string {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timed out"
+	}
+	var sysErr *os.SyscallError
+	if errors.As(err, &sysErr) {
+		return sysErr.Err.Error()
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Err != nil {
+		return opErr.Err.Error()
+	}
+	return err.Error()
+}
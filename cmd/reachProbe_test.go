@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePortSpecs(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+		err  bool
+	}{
+		{spec: "22", want: "22/tcp"},
+		{spec: "8002-8000", err: true},
+		{spec: "9000/udp, 8000-8002,22,8001", want: "22/tcp 8000/tcp 8001/tcp 8002/tcp 9000/udp"},
+		{spec: "53/UDP,53/tcp,53", want: "53/tcp 53/udp"},
+		{spec: "0", err: true},
+		{spec: "65536", err: true},
+		{spec: "80/sctp", err: true},
+		{spec: "http", err: true},
+		{spec: " , ", err: true},
+	}
+	for _, tt := range tests {
+		ports, err := ParsePortSpecs(tt.spec)
+		if (err != nil) != tt.err {
+			t.Errorf("ParsePortSpecs(%q) error = %v, want error %v", tt.spec, err, tt.err)
+			continue
+		}
+		var got []string
+		for _, port := range ports {
+			got = append(got, port.String())
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("ParsePortSpecs(%q) = %v, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestCheckReachAnswer(t *testing.T) {
+	tests := []struct {
+		answer, status, detail string
+	}{
+		{"GHOST-REACH-OK abc 8000\n", ReachReachable, ""},
+		{"GHOST-REACH-OK abc 9000\n", ReachReachable, "answered by the listener on port 9000"},
+		{"GHOST-REACH-OK xyz 8000\n", ReachIntercepted, `unexpected answer "GHOST-REACH-OK xyz 8000"`},
+		{"SSH-2.0-OpenSSH_9.6 and a long banner after it\r\n", ReachIntercepted, `unexpected answer "SSH-2.0-OpenSSH_9.6 and a long banner af..."`},
+	}
+	for _, tt := range tests {
+		status, detail := checkReachAnswer(tt.answer, "abc", 8000)
+		if status != tt.status || detail != tt.detail {
+			t.Errorf("checkReachAnswer(%q) = %s %q, want %s %q", tt.answer, status, detail, tt.status, tt.detail)
+		}
+	}
+	if token, ok := parseReachChallenge("GHOST-REACH abc\n"); !ok || token != "abc" {
+		t.Errorf("parseReachChallenge = %q, %v", token, ok)
+	}
+	if _, ok := parseReachChallenge("GET / HTTP/1.1\r\n"); ok {
+		t.Error("an HTTP request was taken for a challenge")
+	}
+}
+
+func TestRunReachLoopback(t *testing.T) {
+	// Free ports, found by binding to port 0 and releasing them
+	freePort := func(network string) int {
+		if network == "udp" {
+			conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			return conn.LocalAddr().(*net.UDPAddr).Port
+		}
+		listener, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		return listener.Addr().(*net.TCPAddr).Port
+	}
+	tcpPort, udpPort := freePort("tcp"), freePort("udp")
+	closedTCP, closedUDP := freePort("tcp"), freePort("udp")
+
+	listener, errs := NewReachListener("127.0.0.1", []PortSpec{{tcpPort, "tcp"}, {udpPort, "udp"}})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	events := make(chan ReachEvent, 4)
+	listener.OnEvent = func(event ReachEvent) { events <- event }
+	go listener.Serve()
+	defer listener.Close()
+
+	// Another service that answers something else
+	banner, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer banner.Close()
+	go func() {
+		for {
+			conn, err := banner.Accept()
+			if err != nil {
+				return
+			}
+			fmt.Fprint(conn, "220 mail.example ESMTP\r\n")
+			conn.Close()
+		}
+	}()
+	bannerPort := banner.Addr().(*net.TCPAddr).Port
+
+	ports := []PortSpec{{tcpPort, "tcp"}, {udpPort, "udp"}, {closedTCP, "tcp"}, {closedUDP, "udp"}, {bannerPort, "tcp"}}
+	report, err := RunReach("127.0.0.1", ports, ReachOptions{Timeout: time.Second, Retries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Address != "127.0.0.1" || len(report.Results) != len(ports) {
+		t.Fatalf("report = %+v", report)
+	}
+	want := []string{ReachReachable, ReachReachable, ReachRefused, ReachRefused, ReachIntercepted}
+	var got []string
+	for _, result := range report.Results {
+		got = append(got, result.Status)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses = %v, want %v (results %+v)", got, want, report.Results)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-events:
+			if !event.Challenge || event.Error != "" {
+				t.Errorf("listener event = %+v, want an answered challenge", event)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("the listener reported no event")
+		}
+	}
+}
+
+func TestRunReachRejectsSeveralHosts(t *testing.T) {
+	ports := []PortSpec{{Port: 22, Protocol: "tcp"}}
+	for _, host := range []string{"127.0.0.0/30", "127.0.0.1,127.0.0.2"} {
+		if _, err := RunReach(host, ports, ReachOptions{}); err == nil || !strings.Contains(err.Error(), "one host at a time") {
+			t.Errorf("RunReach(%q) error = %v, want it rejected", host, err)
+		}
+	}
+	if _, err := RunReach("127.0.0.1/32", []PortSpec{}, ReachOptions{}); err != nil {
+		t.Errorf("a /32 block is a single host: %v", err)
+	}
+}
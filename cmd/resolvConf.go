@@ -0,0 +1,489 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Limits the C library applies to resolv.conf (see resolv.conf(5)).
+const (
+	resolvMaxNameservers = 3
+	resolvMaxNdots       = 15
+	resolvMaxTimeout     = 30
+	resolvMaxAttempts    = 5
+)
+
+// systemd-resolved's stub listener addresses and the files it maintains.
+var (
+	resolvedStubAddresses = []string{"127.0.0.53", "127.0.0.54"}
+	resolvedUplinkConf    = "/run/systemd/resolve/resolv.conf"
+)
+
+// ResolvConf is the parsed content of resolv.conf.
+type ResolvConf struct {
+	Path        string   `json:"path"`
+	Found       bool     `json:"found"`
+	Nameservers []string `json:"nameservers"` // In order; only the first three are used
+	Search      []string `json:"search"`      // Search list, from the last search or domain line
+	Ndots       int      `json:"ndots"`
+	Timeout     int      `json:"timeout"`  // Seconds to wait for each server
+	Attempts    int      `json:"attempts"` // Rounds through the server list
+	Rotate      bool     `json:"rotate"`
+	Options     []string `json:"options"` // Every option as written, including ndots, timeout and attempts
+	Warnings    []string `json:"warnings"`
+}
+
+// NSSwitchSource is one source of a nsswitch.conf database, with its status actions, e.g.
+// "dns" with "[NOTFOUND=return]".
+type NSSwitchSource struct {
+	Name    string            `json:"name"`
+	Actions map[string]string `json:"actions"` // Status (SUCCESS, NOTFOUND, UNAVAIL, TRYAGAIN) to action (return, continue)
+}
+
+// HostsEntry is one line of the hosts file.
+type HostsEntry struct {
+	Line    int      `json:"line"`
+	Address string   `json:"address"`
+	Names   []string `json:"names"` // Canonical name followed by aliases
+}
+
+// ResolvedStatus describes whether name resolution goes through systemd-resolved.
+type ResolvedStatus struct {
+	Active    bool     `json:"active"`    // resolv.conf points at resolved's stub listener
+	Mode      string   `json:"mode"`      // How resolv.conf is managed: stub, static, uplink, foreign (a copy listing the stub) or empty
+	Target    string   `json:"target"`    // Where resolv.conf links to, when it is a symbolic link
+	Upstreams []string `json:"upstreams"` // The servers resolved forwards to, from its uplink resolv.conf
+}
+
+// DNSConfig is the resolver configuration of a system, read from the files under Root.
+type DNSConfig struct {
+	Root     string           `json:"root"`
+	Resolv   ResolvConf       `json:"resolv"`
+	Hosts    []NSSwitchSource `json:"hosts"`         // The hosts line of nsswitch.conf
+	HostsSet bool             `json:"nsswitchFound"` // nsswitch.conf has a hosts line; otherwise the default is shown
+	HostFile []HostsEntry     `json:"hostsFile"`
+	Resolved ResolvedStatus   `json:"resolved"`
+}
+
+// LoadDNSConfig reads resolv.conf, the hosts line of nsswitch.conf, the hosts file and the
+// state of systemd-resolved from the files below root, so a copy of another system's /etc
+// (or a test fixture) can be inspected. Missing files are reported, not treated as errors.
+func LoadDNSConfig(root string) (DNSConfig, error) {
+	config := DNSConfig{Root: root}
+
+	resolvPath := filepath.Join(root, "etc", "resolv.conf")
+	resolv, err := ParseResolvConf(rootedPath(root, resolvPath))
+	if err != nil {
+		return config, err
+	}
+	resolv.Path = resolvPath
+	config.Resolv = resolv
+
+	config.Hosts, config.HostsSet, err = ParseNSSwitchHosts(rootedPath(root, filepath.Join(root, "etc", "nsswitch.conf")))
+	if err != nil {
+		return config, err
+	}
+	config.HostFile, err = ParseHostsFile(rootedPath(root, filepath.Join(root, "etc", "hosts")))
+	if err != nil {
+		return config, err
+	}
+	config.Resolved = detectResolved(root, resolvPath, resolv)
+	return config, nil
+}
+
+// ParseResolvConf parses a resolv.conf file the way the C library does: the last search or
+// domain line wins, at most three name servers are used, and option values are capped. A
+// missing file yields the library defaults.
+func ParseResolvConf(path string) (ResolvConf, error) {
+	conf := ResolvConf{Path: path, Ndots: 1, Timeout: 5, Attempts: 2, Nameservers: []string{}, Search: []string{}, Options: []string{}, Warnings: []string{}}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		conf.Warnings = append(conf.Warnings, fmt.Sprintf("%s does not exist; the local host (127.0.0.1) is used as name server", path))
+		return conf, nil
+	}
+	if err != nil {
+		return conf, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	defer file.Close()
+	conf.Found = true
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			if len(fields) < 2 {
+				conf.Warnings = append(conf.Warnings, fmt.Sprintf("line %d: nameserver without an address", line))
+				continue
+			}
+			conf.Nameservers = append(conf.Nameservers, fields[1])
+		case "domain":
+			if len(fields) >= 2 {
+				conf.Search = []string{strings.TrimSuffix(fields[1], ".")}
+			}
+		case "search":
+			conf.Search = []string{}
+			for _, domain := range fields[1:] {
+				conf.Search = append(conf.Search, strings.TrimSuffix(domain, "."))
+			}
+		case "options":
+			for _, option := range fields[1:] {
+				conf.Options = append(conf.Options, option)
+				name, value, _ := strings.Cut(option, ":")
+				n, _ := strconv.Atoi(value)
+				switch name {
+				case "ndots":
+					conf.Ndots = min(n, resolvMaxNdots)
+				case "timeout":
+					conf.Timeout = max(min(n, resolvMaxTimeout), 1)
+				case "attempts":
+					conf.Attempts = max(min(n, resolvMaxAttempts), 1)
+				case "rotate":
+					conf.Rotate = true
+				}
+			}
+		case "sortlist":
+			// Only reorders IPv4 answers, which does not affect how names are looked up
+		default:
+			conf.Warnings = append(conf.Warnings, fmt.Sprintf("line %d: unknown keyword %q is ignored", line, fields[0]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return conf, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	if len(conf.Nameservers) == 0 {
+		conf.Warnings = append(conf.Warnings, "no nameserver lines; the local host (127.0.0.1) is used as name server")
+	}
+	if len(conf.Nameservers) > resolvMaxNameservers {
+		conf.Warnings = append(conf.Warnings, fmt.Sprintf("only the first %d name servers are used; %s ignored",
+			resolvMaxNameservers, strings.Join(conf.Nameservers[resolvMaxNameservers:], ", ")))
+	}
+	return conf, nil
+}
+
+// ActiveNameservers returns the name servers the C library queries.
+func (conf ResolvConf) ActiveNameservers() []string {
+	if len(conf.Nameservers) == 0 {
+		return []string{"127.0.0.1"}
+	}
+	return conf.Nameservers[:min(len(conf.Nameservers), resolvMaxNameservers)]
+}
+
+// ParseNSSwitchHosts returns the sources of the hosts database in nsswitch.conf. When the file
+// or its hosts line is missing, the C library's default of "files dns" is returned and ok is false.
+func ParseNSSwitchHosts(path string) ([]NSSwitchSource, bool, error) {
+	defaults := []NSSwitchSource{{Name: "files", Actions: map[string]string{}}, {Name: "dns", Actions: map[string]string{}}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaults, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		database, spec, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(database) != "hosts" {
+			continue
+		}
+		return parseNSSwitchSpec(spec), true, nil
+	}
+	return defaults, false, nil
+}
+
+// parseNSSwitchSpec parses the sources of a nsswitch.conf line, such as
+// "files mdns4_minimal [NOTFOUND=return] dns". Actions apply to the source before them.
+func parseNSSwitchSpec(spec string) []NSSwitchSource {
+	var sources []NSSwitchSource
+	fields := strings.Fields(spec)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if !strings.HasPrefix(field, "[") {
+			sources = append(sources, NSSwitchSource{Name: field, Actions: map[string]string{}})
+			continue
+		}
+		// An action block may contain spaces: [NOTFOUND=return UNAVAIL=continue]
+		block := field
+		for !strings.HasSuffix(block, "]") && i+1 < len(fields) {
+			i++
+			block += " " + fields[i]
+		}
+		if len(sources) == 0 {
+			continue
+		}
+		for _, action := range strings.Fields(strings.Trim(block, "[]")) {
+			status, value, _ := strings.Cut(action, "=")
+			negate := strings.HasPrefix(status, "!")
+			status = strings.ToUpper(strings.TrimPrefix(status, "!"))
+			value = strings.ToLower(value)
+			if negate {
+				// [!UNAVAIL=return] applies the action to every other status
+				for _, other := range []string{"SUCCESS", "NOTFOUND", "UNAVAIL", "TRYAGAIN"} {
+					if other != status {
+						sources[len(sources)-1].Actions[other] = value
+					}
+				}
+				continue
+			}
+			sources[len(sources)-1].Actions[status] = value
+		}
+	}
+	return sources
+}
+
+// action returns what happens after the source finished with status: "return" or "continue".
+// By default only SUCCESS returns.
+func (source NSSwitchSource) action(status string) string {
+	if action, ok := source.Actions[status]; ok {
+		return action
+	}
+	if status == "SUCCESS" {
+		return "return"
+	}
+	return "continue"
+}
+
+// String formats the source the way it is written in nsswitch.conf.
+func (source NSSwitchSource) String() string {
+	if len(source.Actions) == 0 {
+		return source.Name
+	}
+	var actions []string
+	for _, status := range []string{"SUCCESS", "NOTFOUND", "UNAVAIL", "TRYAGAIN"} {
+		if action, ok := source.Actions[status]; ok {
+			actions = append(actions, status+"="+action)
+		}
+	}
+	return source.Name + " [" + strings.Join(actions, " ") + "]"
+}
+
+// ParseHostsFile parses a hosts file. A missing file yields no entries.
+func ParseHostsFile(path string) ([]HostsEntry, error) {
+	entries := []HostsEntry{}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) < 2 {
+			continue
+		}
+		entries = append(entries, HostsEntry{Line: line, Address: fields[0], Names: fields[1:]})
+	}
+	return entries, scanner.Err()
+}
+
+// detectResolved works out whether resolv.conf sends queries to systemd-resolved, either because
+// it links to one of the files resolved maintains or because it lists the stub listener.
+func detectResolved(root, resolvPath string, resolv ResolvConf) ResolvedStatus {
+	status := ResolvedStatus{Upstreams: []string{}}
+	if target, err := os.Readlink(resolvPath); err == nil {
+		status.Target = target
+		switch {
+		case strings.HasSuffix(target, "/stub-resolv.conf"):
+			status.Mode = "stub"
+		case strings.HasSuffix(target, "systemd/resolv.conf") && strings.Contains(target, "lib"):
+			status.Mode = "static"
+		case strings.HasSuffix(target, "/systemd/resolve/resolv.conf"):
+			status.Mode = "uplink"
+		}
+	}
+	for _, server := range resolv.ActiveNameservers() {
+		for _, stub := range resolvedStubAddresses {
+			if server == stub {
+				status.Active = true
+			}
+		}
+	}
+	if status.Active && status.Mode == "" {
+		status.Mode = "foreign"
+	}
+	if status.Active || status.Mode != "" {
+		if uplink, err := ParseResolvConf(rootedPath(root, filepath.Join(root, resolvedUplinkConf))); err == nil && uplink.Found {
+			status.Upstreams = uplink.Nameservers
+		}
+	}
+	return status
+}
+
+// rootedPath follows path while it is a symbolic link, the way a process chrooted to root would:
+// absolute link targets are taken relative to root rather than to this system's root directory,
+// and ".." never leads above root, so no link can point outside it. It returns the path the
+// links lead to, which may not exist.
+func rootedPath(root, path string) string {
+	// The kernel's limit on nested links
+	for i := 0; i < 40; i++ {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return path
+		}
+		if !filepath.IsAbs(target) {
+			dir, err := filepath.Rel(root, filepath.Dir(path))
+			if err != nil {
+				return path
+			}
+			target = filepath.Join(dir, target)
+		}
+		// Cleaned as an absolute path, leading ".." elements stop at root as they do at "/"
+		path = filepath.Join(root, filepath.Join(string(filepath.Separator), target))
+	}
+	return path
+}
+
+// DNSExplainStep is one step of resolving a name.
+type DNSExplainStep struct {
+	Source string `json:"source"` // The nsswitch source, e.g. files or dns
+	Detail string `json:"detail"`
+	Result string `json:"result"` // found, not found, skipped, or what the outcome depends on
+}
+
+// Explain describes, step by step, how the C library would resolve name with this configuration:
+// the hosts file and DNS in nsswitch order, the search-list candidates DNS tries and the servers
+// they are sent to. Nothing is queried; steps that depend on the network say so.
+func (config DNSConfig) Explain(name string) []DNSExplainStep {
+	var steps []DNSExplainStep
+	lookup := strings.ToLower(strings.TrimSuffix(name, "."))
+
+	determined := true // Whether every step so far had a known outcome
+	for _, source := range config.Hosts {
+		step := DNSExplainStep{Source: source.String()}
+		status := ""
+		switch source.Name {
+		case "files":
+			step.Detail = "Look up the name in " + filepath.Join(config.Root, "etc", "hosts")
+			var addresses []string
+			for _, entry := range config.HostFile {
+				for _, host := range entry.Names {
+					if strings.EqualFold(host, lookup) {
+						addresses = append(addresses, fmt.Sprintf("%s (line %d)", entry.Address, entry.Line))
+					}
+				}
+			}
+			if len(addresses) > 0 {
+				step.Result = "found: " + strings.Join(addresses, ", ")
+				status = "SUCCESS"
+			} else {
+				step.Result = "not found"
+				status = "NOTFOUND"
+			}
+		case "dns":
+			step.Detail = config.explainDNS(name)
+			step.Result = "depends on the servers' answers"
+		case "resolve":
+			step.Detail = "Ask systemd-resolved over its NSS module, which applies its own per-link DNS, LLMNR and mDNS settings"
+			if len(config.Resolved.Upstreams) > 0 {
+				step.Detail += " (upstream servers: " + strings.Join(config.Resolved.Upstreams, ", ") + ")"
+			}
+			step.Result = "depends on systemd-resolved"
+		case "myhostname":
+			step.Detail = "Resolve the local host name, localhost and _gateway"
+			if lookup == "localhost" || strings.HasSuffix(lookup, ".localhost") || lookup == "_gateway" {
+				step.Result = "found"
+				status = "SUCCESS"
+			} else {
+				step.Result = "found only if it is this machine's host name"
+			}
+		case "mdns", "mdns4", "mdns6", "mdns_minimal", "mdns4_minimal", "mdns6_minimal":
+			if strings.HasSuffix(source.Name, "_minimal") && !strings.HasSuffix(lookup, ".local") {
+				// The minimal modules report UNAVAIL for other names, so [NOTFOUND=return] does not apply
+				step.Detail = "Multicast DNS, for names in .local only"
+				step.Result = "skipped"
+				status = "UNAVAIL"
+			} else {
+				step.Detail = "Ask the local network with multicast DNS"
+				step.Result = "depends on the devices on the link"
+			}
+		default:
+			step.Detail = "Handled by the " + source.Name + " NSS module"
+			step.Result = "not evaluated"
+		}
+
+		if status == "" {
+			determined = false
+			if source.action("NOTFOUND") == "return" {
+				step.Result += "; a not-found answer ends the lookup here"
+			}
+		}
+		steps = append(steps, step)
+		if status != "" && source.action(status) == "return" {
+			if status == "NOTFOUND" {
+				steps = append(steps, DNSExplainStep{Source: "-", Detail: "[NOTFOUND=return] ends the lookup", Result: "not found"})
+			}
+			return steps
+		}
+	}
+	if determined {
+		steps = append(steps, DNSExplainStep{Source: "-", Detail: "No source found the name", Result: "not found"})
+	} else {
+		steps = append(steps, DNSExplainStep{Source: "-", Detail: "If none of the sources above finds the name", Result: "not found"})
+	}
+	return steps
+}
+
+// explainDNS describes the queries the stub resolver sends for name: which candidate names are
+// tried in which order, and the servers, timeout and attempts used for each.
+func (config DNSConfig) explainDNS(name string) string {
+	resolv := config.Resolv
+	candidates := dnsSearchCandidates(name, resolv.Search, resolv.Ndots)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Query %s", strings.Join(candidates, ", then "))
+	switch {
+	case strings.HasSuffix(name, "."):
+		b.WriteString(" (the name is absolute, so the search list is not used)")
+	case len(resolv.Search) > 0 && strings.Count(name, ".") >= resolv.Ndots:
+		fmt.Fprintf(&b, " (the name has at least ndots=%d dots, so it is tried as is first)", resolv.Ndots)
+	case len(resolv.Search) > 0:
+		fmt.Fprintf(&b, " (the name has fewer than ndots=%d dots, so the search list is tried first)", resolv.Ndots)
+	}
+
+	servers := resolv.ActiveNameservers()
+	fmt.Fprintf(&b, ", each to %s", strings.Join(servers, ", "))
+	if resolv.Rotate {
+		b.WriteString(" in rotation")
+	} else {
+		b.WriteString(" in order")
+	}
+	fmt.Fprintf(&b, ", waiting %ds per server, %d rounds", resolv.Timeout, resolv.Attempts)
+	if config.Resolved.Active {
+		b.WriteString("; the server is systemd-resolved's stub")
+		if len(config.Resolved.Upstreams) > 0 {
+			b.WriteString(", which forwards to " + strings.Join(config.Resolved.Upstreams, ", "))
+		}
+	}
+	return b.String()
+}
+
+// dnsSearchCandidates returns the names the stub resolver tries for name, in order. A name with
+// at least ndots dots is tried as is before the search list is applied; an absolute name (with a
+// trailing dot) is only tried as is.
+func dnsSearchCandidates(name string, search []string, ndots int) []string {
+	if strings.HasSuffix(name, ".") {
+		return []string{name}
+	}
+	var searched []string
+	for _, domain := range search {
+		searched = append(searched, name+"."+domain+".")
+	}
+	if strings.Count(name, ".") >= ndots {
+		return append([]string{name + "."}, searched...)
+	}
+	return append(searched, name+".")
+}
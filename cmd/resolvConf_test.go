@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadDNSConfigFixture loads the resolver configuration of a root under testdata/dnsconfig. Roots
+// whose resolv.conf is a symbolic link are skipped when the checkout did not keep the link.
+func loadDNSConfigFixture(t *testing.T, name string, symlink bool) DNSConfig {
+	t.Helper()
+	root := filepath.Join("testdata", "dnsconfig", name)
+	if _, err := os.Readlink(filepath.Join(root, "etc", "resolv.conf")); symlink && err != nil {
+		t.Skip("the fixture's symbolic link was not checked out:", err)
+	}
+	config, err := LoadDNSConfig(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestLoadDNSConfigPlain(t *testing.T) {
+	config := loadDNSConfigFixture(t, "plain", false)
+	resolv := config.Resolv
+
+	if !resolv.Found || resolv.Path != filepath.Join("testdata", "dnsconfig", "plain", "etc", "resolv.conf") {
+		t.Errorf("Found %v Path %q", resolv.Found, resolv.Path)
+	}
+	if want := []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}; !reflect.DeepEqual(resolv.ActiveNameservers(), want) {
+		t.Errorf("active name servers = %v, want %v", resolv.ActiveNameservers(), want)
+	}
+	// The search line replaces the earlier domain line
+	if want := []string{"corp.example", "lab.example"}; !reflect.DeepEqual(resolv.Search, want) {
+		t.Errorf("search = %v, want %v", resolv.Search, want)
+	}
+	if resolv.Ndots != 2 || resolv.Timeout != 3 || resolv.Attempts != resolvMaxAttempts || !resolv.Rotate {
+		t.Errorf("ndots %d timeout %d attempts %d rotate %v, want 2 3 %d true", resolv.Ndots, resolv.Timeout, resolv.Attempts, resolv.Rotate, resolvMaxAttempts)
+	}
+	if len(resolv.Warnings) != 2 || !strings.Contains(resolv.Warnings[0], `unknown keyword "lookup"`) || !strings.Contains(resolv.Warnings[1], "192.0.2.4 ignored") {
+		t.Errorf("warnings = %q", resolv.Warnings)
+	}
+
+	if !config.HostsSet {
+		t.Error("the hosts line of nsswitch.conf was not found")
+	}
+	var sources []string
+	for _, source := range config.Hosts {
+		sources = append(sources, source.String())
+	}
+	if want := []string{"files", "mdns4_minimal [NOTFOUND=return]", "dns"}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("nsswitch hosts = %v, want %v", sources, want)
+	}
+	if len(config.HostFile) != 4 || config.HostFile[2].Line != 4 || !reflect.DeepEqual(config.HostFile[2].Names, []string{"router.lan", "router"}) {
+		t.Errorf("hosts file = %+v", config.HostFile)
+	}
+	if config.Resolved.Active || config.Resolved.Mode != "" || config.Resolved.Target != "" {
+		t.Errorf("resolved = %+v, want not used", config.Resolved)
+	}
+}
+
+func TestLoadDNSConfigResolved(t *testing.T) {
+	tests := []struct {
+		root      string
+		target    string
+		mode      string
+		active    bool
+		servers   []string
+		upstreams []string
+	}{
+		// An absolute link, which must be followed below the root rather than on this system
+		{root: "stub", target: "/run/systemd/resolve/stub-resolv.conf", mode: "stub", active: true,
+			servers: []string{"127.0.0.53"}, upstreams: []string{"192.0.2.53", "2001:db8::53"}},
+		{root: "uplink", target: "../run/systemd/resolve/resolv.conf", mode: "uplink",
+			servers: []string{"192.0.2.53", "2001:db8::53"}, upstreams: []string{"192.0.2.53", "2001:db8::53"}},
+		// A relative link with more ".." than directories, which stops at the root
+		{root: "escape", target: "../../../../../../../../run/systemd/resolve/stub-resolv.conf", mode: "stub", active: true,
+			servers: []string{"127.0.0.53"}, upstreams: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.root, func(t *testing.T) {
+			config := loadDNSConfigFixture(t, tt.root, true)
+			resolved := config.Resolved
+			if resolved.Target != tt.target || resolved.Mode != tt.mode || resolved.Active != tt.active {
+				t.Errorf("target %q mode %q active %v, want %q %q %v", resolved.Target, resolved.Mode, resolved.Active, tt.target, tt.mode, tt.active)
+			}
+			if !config.Resolv.Found || !reflect.DeepEqual(config.Resolv.Nameservers, tt.servers) {
+				t.Errorf("found %v name servers %v, want %v", config.Resolv.Found, config.Resolv.Nameservers, tt.servers)
+			}
+			if !reflect.DeepEqual(resolved.Upstreams, tt.upstreams) {
+				t.Errorf("upstreams = %v, want %v", resolved.Upstreams, tt.upstreams)
+			}
+		})
+	}
+
+	// Without an nsswitch.conf the C library's default applies
+	config := loadDNSConfigFixture(t, "stub", true)
+	if config.HostsSet || len(config.Hosts) != 2 || config.Hosts[0].Name != "files" || config.Hosts[1].Name != "dns" {
+		t.Errorf("hosts = %+v set %v, want the files dns default", config.Hosts, config.HostsSet)
+	}
+}
+
+func TestRootedPath(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"etc", "run/a/b"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"etc/relative": "../run/a/b/file",
+		"etc/escape":   "../../../../run/a/b/file",
+		"etc/absolute": "/../../run/a/b/file",
+		"etc/chain":    "/run/a/b/next",
+		"run/a/b/next": "../../../../../etc/relative",
+		"etc/loop":     "loop",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skip("symbolic links are not supported:", err)
+		}
+	}
+
+	file := filepath.Join(root, "run", "a", "b", "file")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"etc/relative", file},
+		{"etc/escape", file},
+		{"etc/absolute", file},
+		{"etc/chain", file},
+		{"etc/loop", filepath.Join(root, "etc", "loop")},
+		{"etc/missing", filepath.Join(root, "etc", "missing")},
+	}
+	for _, tt := range tests {
+		if got := rootedPath(root, filepath.Join(root, tt.path)); got != tt.want {
+			t.Errorf("rootedPath(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDNSConfigExplain(t *testing.T) {
+	plain := loadDNSConfigFixture(t, "plain", false)
+	tests := []struct {
+		config DNSConfig
+		name   string
+		want   []string // Source and result of each step
+	}{
+		{plain, "router.lan", []string{"files: found: 192.168.1.1 (line 4)"}},
+		{plain, "db", []string{
+			"files: not found",
+			"mdns4_minimal [NOTFOUND=return]: skipped",
+			"dns: depends on the servers' answers",
+			"-: not found",
+		}},
+		{plain, "printer.local", []string{
+			"files: not found",
+			"mdns4_minimal [NOTFOUND=return]: depends on the devices on the link; a not-found answer ends the lookup here",
+			"dns: depends on the servers' answers",
+			"-: not found",
+		}},
+	}
+	if _, err := os.Readlink(filepath.Join("testdata", "dnsconfig", "uplink", "etc", "resolv.conf")); err == nil {
+		uplink := loadDNSConfigFixture(t, "uplink", true)
+		tests = append(tests, struct {
+			config DNSConfig
+			name   string
+			want   []string
+		}{uplink, "missing", []string{"files [NOTFOUND=return]: not found", "-: not found"}})
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, step := range tt.config.Explain(tt.name) {
+			got = append(got, step.Source+": "+step.Result)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Explain(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	detail := plain.explainDNS("db")
+	if want := "Query db.corp.example., then db.lab.example., then db. (the name has fewer than ndots=2 dots, so the search list is tried first), each to 192.0.2.1, 192.0.2.2, 192.0.2.3 in rotation, waiting 3s per server, 5 rounds"; detail != want {
+		t.Errorf("explainDNS(db) = %q\nwant %q", detail, want)
+	}
+}
+
+func TestDNSSearchCandidates(t *testing.T) {
+	search := []string{"corp.example", "lab.example"}
+	tests := []struct {
+		name  string
+		ndots int
+		want  []string
+	}{
+		{"db", 1, []string{"db.corp.example.", "db.lab.example.", "db."}},
+		{"db.eu", 1, []string{"db.eu.", "db.eu.corp.example.", "db.eu.lab.example."}},
+		{"db.eu", 2, []string{"db.eu.corp.example.", "db.eu.lab.example.", "db.eu."}},
+		{"db.eu.", 5, []string{"db.eu."}},
+		{"db", 0, []string{"db.", "db.corp.example.", "db.lab.example."}},
+	}
+	for _, tt := range tests {
+		if got := dnsSearchCandidates(tt.name, search, tt.ndots); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("dnsSearchCandidates(%q, ndots=%d) = %v, want %v", tt.name, tt.ndots, got, tt.want)
+		}
+	}
+	if got := dnsSearchCandidates("db", nil, 1); !reflect.DeepEqual(got, []string{"db."}) {
+		t.Errorf("without a search list got %v, want [db.]", got)
+	}
+}
+
+func TestParseNSSwitchSpec(t *testing.T) {
+	sources := parseNSSwitchSpec("files [NOTFOUND=return UNAVAIL=continue] resolve [!UNAVAIL=return] dns")
+	var got []string
+	for _, source := range sources {
+		got = append(got, source.String())
+	}
+	want := []string{
+		"files [NOTFOUND=return UNAVAIL=continue]",
+		"resolve [SUCCESS=return NOTFOUND=return TRYAGAIN=return]",
+		"dns",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNSSwitchSpec = %q, want %q", got, want)
+	}
+	if sources[2].action("SUCCESS") != "return" || sources[2].action("NOTFOUND") != "continue" {
+		t.Error("a source without actions must only return on SUCCESS")
+	}
+}
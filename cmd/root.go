@@ -3,7 +3,9 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"runtime/debug"
 
+	"github.com/mwiater/ghost/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -11,12 +13,41 @@ import (
 // This variable is set in the main.go file.
 var IsGoRun bool
 
+// Version is the version of ghost. Release builds set it with
+// -ldflags "-X github.com/mwiater/ghost/cmd.Version=v1.2.3"; otherwise GhostVersion falls back
+// to the module version recorded in the binary.
+var Version string
+
+// GhostVersion returns the version of the running ghost binary, or "devel" when it is unknown.
+func GhostVersion() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
 // RootCmd represents the base command when called without any subcommands.
 // It serves as the entry point for all utilities and tools available within the application.
 var RootCmd = &cobra.Command{
 	Use:   "ghost",
 	Short: "Network diagnostics and system info toolkit.",
 	Long:  `A versatile toolkit for network diagnostics and system information gathering, offering developers a suite of commands to scan networks, retrieve system details, and perform IP and port analyses.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Only check an override vendor database eagerly, so a bad path is reported up front
+		ouiFile, _ := cmd.Flags().GetString("oui-file")
+		if ouiFile == "" {
+			ouiFile = os.Getenv(utils.OUIFileEnv)
+		}
+		if ouiFile != "" {
+			utils.SetOUIFile(ouiFile)
+			if err := utils.OUIError(); err != nil {
+				utils.TerminalColor(fmt.Sprintf("Warning: %v; using the built-in vendor database.", err), utils.Warn)
+			}
+		}
+	},
 }
 
 // Execute adds all child commands to the root command and sets the flags appropriately.
@@ -33,6 +64,7 @@ func Execute() {
 func init() {
 	// Example of defining a persistent flag:
 	// RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.golangutils.yaml)")
+	RootCmd.PersistentFlags().String("oui-file", "", "MAC vendor database to use instead of the built-in one (also $"+utils.OUIFileEnv+")")
 
 	// Example of defining a local flag:
 	// RootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
@@ -2,10 +2,18 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
-	"os/exec"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/mwiater/ghost/utils"
@@ -16,228 +24,534 @@ import (
 var RouteCmd = &cobra.Command{
 	Use:   "routeinfo",
 	Short: "Displays the IP routing table and network routes.",
-	Long:  `Retrieves and displays the system's IP routing table and network routes in a formatted table.`,
+	Long: `Retrieves and displays the system's IP routing table and network routes in a formatted table.
+
+On Linux the IPv4 and IPv6 routes of the main table are read from the kernel over netlink (or
+from /proc/net/route and /proc/net/ipv6_route when netlink is unavailable), with the destination
+in CIDR form, the gateway, interface, metric, preferred source address, the protocol that
+installed the route (kernel, boot, static, dhcp, ra, ...), its scope and its flags. --route-file
+reads saved copies of the /proc files instead, e.g. from another machine.
+
+--lookup reports the path traffic to one or more destinations would take: the longest matching
+route of the table, the outgoing interface, gateway and the source address the kernel would
+choose. A CIDR block is looked up once, by its network address. On Linux the kernel is asked as
+well (like 'ip route get'), so local addresses and policy routing are accounted for, and
+destinations where it takes a different path than the matched route are flagged.
+
+--table shows another routing table than main, by name (as in /etc/iproute2/rt_tables) or
+number, or every table (local, main, default and custom tables) with "all". The policy rules
+that select between the tables are listed by 'routeinfo rules'.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		routes, err := RunRoute()
+		routeFiles, _ := cmd.Flags().GetStringSlice("route-file")
+		lookups, _ := cmd.Flags().GetStringSlice("lookup")
+		tableName, _ := cmd.Flags().GetString("table")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		family, err := addressFamilyFlag(cmd)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		table, err := ParseRouteTable(tableName)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		var routes []RouteEntry
+		if len(routeFiles) > 0 {
+			routes, err = ReadRouteFiles(routeFiles)
+		} else {
+			routes, err = GetRouteTable(table)
+		}
 		if err != nil {
 			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		routes = filterRouteFamily(routes, family)
+
+		if len(lookups) > 0 {
+			// The kernel only knows the routes of this machine, not those of a route file
+			results, err := RunRouteLookup(lookups, routes, family, len(routeFiles) == 0)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			if jsonOutput {
+				utils.PrintJSON(results)
+				return
+			}
+			PrintRouteLookups(results)
+			return
+		}
+
+		if jsonOutput {
+			utils.PrintJSON(routes)
 			return
 		}
 		PrintRoutes(routes)
 	},
 }
 
+// Address families of RouteEntry.Family.
+const (
+	RouteFamilyIPv4 = "inet"
+	RouteFamilyIPv6 = "inet6"
+)
+
 // RouteEntry holds details about a single route.
 type RouteEntry struct {
-	Destination string
-	Genmask     string
-	Gateway     string
-	Flags       string
-	Metric      string
-	Ref         string
-	Use         string
-	Iface       string
+	Family      string `json:"family,omitempty"`   // RouteFamilyIPv4 or RouteFamilyIPv6 (Linux)
+	Destination string `json:"destination"`        // CIDR on Linux, e.g. 10.0.0.0/8 or ::/0
+	Genmask     string `json:"genmask,omitempty"`  // Netmask (Windows)
+	Gateway     string `json:"gateway"`            // Empty for directly connected networks
+	Flags       string `json:"flags"`              // Flags decoded into words on Linux, e.g. "up, gateway"
+	Metric      string `json:"metric"`             // Route priority; lower is preferred
+	Ref         string `json:"ref,omitempty"`      // Reference count (from /proc)
+	Use         string `json:"use,omitempty"`      // Lookup count (from /proc)
+	Iface       string `json:"iface"`              // Outgoing interface
+	Source      string `json:"source,omitempty"`   // Preferred source address (netlink)
+	Protocol    string `json:"protocol,omitempty"` // What installed the route: kernel, boot, static, dhcp, ra, ...
+	Scope       string `json:"scope,omitempty"`    // universe, site, link, host or nowhere
+	Type        string `json:"type,omitempty"`     // unicast, local, broadcast, blackhole, unreachable, ...
+	Table       string `json:"table,omitempty"`    // Routing table; always main when read from /proc
 }
 
-// RunRoute retrieves the routing table without printing.
-func RunRoute() ([]RouteEntry, error) {
-	return GetRoute()
+// Route flags (RTF_*, linux/route.h and linux/ipv6_route.h) as reported in /proc/net/route and
+// /proc/net/ipv6_route.
+const (
+	rtfUp        = 0x0001
+	rtfGateway   = 0x0002
+	rtfHost      = 0x0004
+	rtfDynamic   = 0x0010
+	rtfModified  = 0x0020
+	rtfReject    = 0x0200
+	rtfDefault   = 0x00010000
+	rtfAddrconf  = 0x00040000
+	rtfAnycast   = 0x00100000
+	rtfNoNexthop = 0x00200000
+	rtfExpires   = 0x00400000
+	rtfCache     = 0x01000000
+	rtfLocal     = 0x80000000
+)
+
+// routeFlagNames lists the words used for route flags, in display order.
+var routeFlagNames = []struct {
+	flag uint32
+	name string
+}{
+	{rtfUp, "up"},
+	{rtfGateway, "gateway"},
+	{rtfHost, "host"},
+	{0x0008, "reinstate"},
+	{rtfDynamic, "dynamic"},
+	{rtfModified, "modified"},
+	{0x0040, "mtu"},
+	{0x0080, "window"},
+	{0x0100, "irtt"},
+	{rtfReject, "reject"},
+	{rtfDefault, "default"},
+	{0x00020000, "allonlink"},
+	{rtfAddrconf, "addrconf"},
+	{0x00080000, "prefix"},
+	{rtfAnycast, "anycast"},
+	{rtfNoNexthop, "nonexthop"},
+	{rtfExpires, "expires"},
+	{0x00800000, "routeinfo"},
+	{rtfCache, "cache"},
+	{0x02000000, "flow"},
+	{0x04000000, "policy"},
+	{rtfLocal, "local"},
 }
 
-// PrintRoutes displays the routing entries in a formatted table.
-func PrintRoutes(routes []RouteEntry) {
-	t := utils.Table("DarkSimple", "routeCmd")
-	// Define table headers based on the operating system
-	if runtime.GOOS == "windows" {
-		t.AppendHeader(table.Row{"Network Destination", "Netmask", "Gateway", "Interface", "Metric"})
-		for _, route := range routes {
-			t.AppendRow(table.Row{
-				route.Destination,
-				route.Genmask,
-				route.Gateway,
-				route.Iface,
-				route.Metric,
-			})
-		}
-	} else {
-		t.AppendHeader(table.Row{"Destination", "Genmask", "Gateway", "Flags", "Metric", "Ref", "Use", "Iface"})
-		for _, route := range routes {
-			t.AppendRow(table.Row{
-				route.Destination,
-				route.Genmask,
-				route.Gateway,
-				route.Flags,
-				route.Metric,
-				route.Ref,
-				route.Use,
-				route.Iface,
-			})
+// routeFlagWords decodes RTF_* flags into words, e.g. "up, gateway".
+func routeFlagWords(flags uint32) string {
+	var words []string
+	for _, f := range routeFlagNames {
+		if flags&f.flag != 0 {
+			words = append(words, f.name)
 		}
 	}
+	return strings.Join(words, ", ")
+}
 
-	fmt.Println()
-	t.Render()
-	fmt.Println()
+// routeProtocolNames maps route protocols (RTPROT_*, linux/rtnetlink.h) to the names used by
+// /etc/iproute2/rt_protos.
+var routeProtocolNames = map[uint8]string{
+	0: "unspec", 1: "redirect", 2: "kernel", 3: "boot", 4: "static",
+	8: "gated", 9: "ra", 10: "mrt", 11: "zebra", 12: "bird", 13: "dnrouted", 14: "xorp",
+	15: "ntk", 16: "dhcp", 17: "mrouted", 18: "keepalived", 42: "babel", 99: "openr",
+	186: "bgp", 187: "isis", 188: "ospf", 189: "rip", 192: "eigrp",
 }
 
-func init() {
-	RootCmd.AddCommand(RouteCmd)
+// routeScopeNames maps route scopes (RT_SCOPE_*) to their names.
+var routeScopeNames = map[uint8]string{0: "universe", 200: "site", 253: "link", 254: "host", 255: "nowhere"}
 
-	// Define flags with default values (if any)
-	// Currently, no additional flags are necessary for the 'route' command
-	// However, you can add flags here if future enhancements are needed
+// routeTypeNames maps route types (RTN_*) to their names.
+var routeTypeNames = map[uint8]string{
+	0: "unspec", 1: "unicast", 2: "local", 3: "broadcast", 4: "anycast", 5: "multicast",
+	6: "blackhole", 7: "unreachable", 8: "prohibit", 9: "throw", 10: "nat", 11: "xresolve",
+}
 
-	// Example:
-	// RouteCmd.PersistentFlags().BoolP("json", "j", false, "Output in JSON format")
-	// viper.BindPFlag("json", RouteCmd.PersistentFlags().Lookup("json"))
+// Routing tables accepted by GetRouteTable.
+const (
+	RouteTableAll  = 0   // Every table
+	RouteTableMain = 254 // The table 'ip route' shows
+)
+
+// routeTableFiles lists where iproute2 keeps routing table names: the packaged defaults first,
+// then the local configuration that overrides them.
+var routeTableFiles = []string{"/usr/lib/iproute2/rt_tables", "/usr/share/iproute2/rt_tables", "/etc/iproute2/rt_tables"}
+
+var (
+	// routeTableNames maps routing table numbers to their names; the reserved tables are
+	// always known, others are read from routeTableFiles on first use.
+	routeTableNames     = map[uint32]string{253: "default", 254: "main", 255: "local"}
+	routeTableNamesOnce sync.Once
+)
+
+// loadRouteTableNames adds the table names of routeTableFiles and their .d/*.conf directories
+// to routeTableNames. Missing files are skipped.
+func loadRouteTableNames() {
+	routeTableNamesOnce.Do(func() {
+		for _, path := range routeTableFiles {
+			conf, _ := filepath.Glob(path + ".d/*.conf")
+			for _, file := range append([]string{path}, conf...) {
+				f, err := os.Open(file)
+				if err != nil {
+					continue
+				}
+				for id, name := range parseRouteTableNames(f) {
+					routeTableNames[id] = name
+				}
+				f.Close()
+			}
+		}
+	})
 }
 
-// GetRoute retrieves routing information based on the operating system.
-func GetRoute() ([]RouteEntry, error) {
-	if runtime.GOOS == "windows" {
-		return getRouteWindows()
+// parseRouteTableNames parses an rt_tables file: one "number name" pair per line, with #
+// comments. Numbers may be decimal or hexadecimal (0x...).
+func parseRouteTableNames(r io.Reader) map[uint32]string {
+	names := make(map[uint32]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		id, err := strconv.ParseUint(fields[0], 0, 32)
+		if err != nil {
+			continue
+		}
+		names[uint32(id)] = fields[1]
 	}
-	return getRouteUnix()
+	return names
 }
 
-// getRouteUnix retrieves routing information on Unix-based systems (Linux, macOS).
-func getRouteUnix() ([]RouteEntry, error) {
-	var routes []RouteEntry
+// routeTableName returns the name of a routing table, or its number when it has no name.
+func routeTableName(id uint32) string {
+	loadRouteTableNames()
+	if name, ok := routeTableNames[id]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
 
-	// Use 'route -n' for Linux and 'netstat -rn' for macOS
-	var cmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		// macOS
-		cmd = exec.Command("netstat", "-rn")
-	} else {
-		// Assume Linux
-		cmd = exec.Command("route", "-n")
+// ParseRouteTable resolves a routing table given by name (as in /etc/iproute2/rt_tables) or
+// number; "all" selects every table.
+func ParseRouteTable(table string) (uint32, error) {
+	if table == "all" {
+		return RouteTableAll, nil
+	}
+	if id, err := strconv.ParseUint(table, 0, 32); err == nil {
+		return uint32(id), nil
+	}
+	loadRouteTableNames()
+	for id, name := range routeTableNames {
+		if name == table {
+			return id, nil
+		}
 	}
+	return 0, fmt.Errorf("unknown routing table %q", table)
+}
 
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute routing command: %v", err)
+// routeNumberName returns the name of a numeric value, or the number when it has no name.
+func routeNumberName(names map[uint8]string, value uint8) string {
+	if name, ok := names[value]; ok {
+		return name
 	}
+	return strconv.Itoa(int(value))
+}
 
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	lineNumber := 0
-	for scanner.Scan() {
-		line := scanner.Text()
-		lineNumber++
+// RunRoute retrieves the routing table without printing.
+func RunRoute() ([]RouteEntry, error) {
+	return GetRoute()
+}
 
-		// Skip header lines
-		if runtime.GOOS == "darwin" {
-			if lineNumber < 3 {
-				continue
-			}
-		} else {
-			if lineNumber < 3 {
-				continue
-			}
-		}
+// GetRoute retrieves the routing table of the system: the main table over netlink on Linux and
+// the IPv4 routes of 'route print' on Windows.
+func GetRoute() ([]RouteEntry, error) {
+	return GetRouteTable(RouteTableMain)
+}
 
-		// Split the line into fields
-		fields := strings.Fields(line)
-		if runtime.GOOS == "darwin" {
-			// macOS netstat -rn output has columns:
-			// Destination, Gateway, Flags, Refs, Use, Netif, Expire
-			if len(fields) < 7 {
-				continue
-			}
-			route := RouteEntry{
-				Destination: fields[0],
-				Genmask:     "N/A", // Not provided directly
-				Gateway:     fields[1],
-				Flags:       fields[2],
-				Metric:      "N/A", // Not provided directly
-				Ref:         fields[3],
-				Use:         fields[4],
-				Iface:       fields[5],
-			}
-			routes = append(routes, route)
-		} else {
-			// Linux route -n output has columns:
-			// Destination, Gateway, Genmask, Flags, Metric, Ref, Use, Iface
-			if len(fields) < 8 {
-				continue
-			}
-			route := RouteEntry{
-				Destination: fields[0],
-				Genmask:     fields[2],
-				Gateway:     fields[1],
-				Flags:       fields[3],
-				Metric:      fields[4],
-				Ref:         fields[5],
-				Use:         fields[6],
-				Iface:       fields[7],
-			}
-			routes = append(routes, route)
+// GetRouteTable retrieves the routes of one routing table, or of every table with
+// RouteTableAll. Tables other than main are only available on Linux.
+func GetRouteTable(table uint32) ([]RouteEntry, error) {
+	return readRoutes(table)
+}
+
+// ReadRouteFiles reads routes from saved copies of /proc/net/route or /proc/net/ipv6_route; the
+// format of each file is recognized by its content.
+func ReadRouteFiles(paths []string) ([]RouteEntry, error) {
+	var routes []RouteEntry
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := parseRouteFile(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
 		}
+		routes = append(routes, entries...)
 	}
+	return routes, nil
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading routing command output: %v", err)
+// parseRouteFile parses /proc/net/route, which starts with a header line, or
+// /proc/net/ipv6_route, which has none.
+func parseRouteFile(r io.Reader) ([]RouteEntry, error) {
+	reader := bufio.NewReader(r)
+	start, err := reader.Peek(5)
+	if err != nil && len(start) == 0 {
+		return nil, nil
+	}
+	if string(start) == "Iface" {
+		return parseProcNetRoute(reader)
 	}
+	return parseProcIPv6Route(reader)
+}
 
-	return routes, nil
+// parseProcNetRoute parses /proc/net/route:
+//
+//	Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+//	eth0	00000000	0100A8C0	0003	0	0	100	00000000	0	0	0
+//
+// Addresses are the raw network-order words printed in host byte order.
+func parseProcNetRoute(r io.Reader) ([]RouteEntry, error) {
+	var routes []RouteEntry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] == "Iface" {
+			continue
+		}
+		dst, err1 := procRouteIPv4(fields[1])
+		gateway, err2 := procRouteIPv4(fields[2])
+		mask, err3 := procRouteIPv4(fields[7])
+		flags, err4 := strconv.ParseUint(fields[3], 16, 32)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			return nil, fmt.Errorf("invalid route line %q", scanner.Text())
+		}
+		bits, err := netmaskBits(mask.String())
+		if err != nil {
+			return nil, err
+		}
+		route := RouteEntry{
+			Family:      RouteFamilyIPv4,
+			Destination: netip.PrefixFrom(dst, bits).String(),
+			Genmask:     mask.String(),
+			Flags:       routeFlagWords(uint32(flags)),
+			Metric:      fields[6],
+			Ref:         fields[4],
+			Use:         fields[5],
+			Iface:       fields[0],
+			Scope:       procRouteScope(uint32(flags)),
+			Type:        "unicast",
+			Table:       "main",
+		}
+		if flags&rtfGateway != 0 {
+			route.Gateway = gateway.String()
+		}
+		if flags&rtfReject != 0 {
+			route.Type = "unreachable"
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
 }
 
-// getRouteWindows retrieves routing information on Windows systems.
-func getRouteWindows() ([]RouteEntry, error) {
+// parseProcIPv6Route parses /proc/net/ipv6_route, one route per line: destination, prefix
+// length, source, source prefix length, next hop, metric, reference count, use count and flags
+// in hex, then the interface. The file lists the routes of every table without naming it; the
+// local table's routes are recognized and skipped, so what remains is reported as main, as
+// 'ip -6 route' shows it on hosts without policy routing.
+//
+//	fe800000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+func parseProcIPv6Route(r io.Reader) ([]RouteEntry, error) {
 	var routes []RouteEntry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		dst, err1 := procRouteIPv6(fields[0])
+		bits, err2 := strconv.ParseUint(fields[1], 16, 8)
+		gateway, err3 := procRouteIPv6(fields[4])
+		metric, err4 := strconv.ParseUint(fields[5], 16, 32)
+		ref, err5 := strconv.ParseUint(fields[6], 16, 32)
+		use, err6 := strconv.ParseUint(fields[7], 16, 32)
+		flags, err7 := strconv.ParseUint(fields[8], 16, 32)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || err6 != nil || err7 != nil || bits > 128 {
+			return nil, fmt.Errorf("invalid IPv6 route line %q", scanner.Text())
+		}
+		// Cached clones are not routes and the null entry (an unreachable ::/0 with the highest
+		// metric) only terminates lookups
+		if flags&rtfCache != 0 || (flags&rtfReject != 0 && metric == 0xffffffff) {
+			continue
+		}
+		// The local table holds the host's own and anycast addresses and the multicast routes
+		if flags&(rtfLocal|rtfAnycast) != 0 || dst.IsMulticast() {
+			continue
+		}
+		route := RouteEntry{
+			Family:      RouteFamilyIPv6,
+			Destination: netip.PrefixFrom(dst, int(bits)).String(),
+			Flags:       routeFlagWords(uint32(flags)),
+			Metric:      strconv.FormatUint(metric, 10),
+			Ref:         strconv.FormatUint(ref, 10),
+			Use:         strconv.FormatUint(use, 10),
+			Iface:       fields[9],
+			Scope:       procRouteScope(uint32(flags)),
+			Type:        "unicast",
+			Table:       "main",
+		}
+		if flags&rtfGateway != 0 || !gateway.IsUnspecified() {
+			route.Gateway = gateway.String()
+		}
+		if flags&rtfReject != 0 {
+			route.Type = "unreachable"
+		}
+		if flags&rtfAddrconf != 0 {
+			route.Protocol = "ra"
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
 
-	// Use 'route print' command
-	cmd := exec.Command("route", "print")
-	output, err := cmd.Output()
+// procRouteScope infers the scope of a route from /proc, which does not record it: routes via
+// a gateway reach the universe, local routes the host itself, and others the link.
+func procRouteScope(flags uint32) string {
+	switch {
+	case flags&rtfGateway != 0:
+		return "universe"
+	case flags&rtfLocal != 0:
+		return "host"
+	case flags&rtfReject != 0:
+		return "nowhere"
+	}
+	return "link"
+}
+
+// procRouteIPv4 decodes an IPv4 address of /proc/net/route.
+func procRouteIPv4(field string) (netip.Addr, error) {
+	value, err := strconv.ParseUint(field, 16, 32)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute 'route print' command: %v", err)
+		return netip.Addr{}, err
 	}
+	var b [4]byte
+	binary.NativeEndian.PutUint32(b[:], uint32(value))
+	return netip.AddrFrom4(b), nil
+}
 
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	inIPv4Section := false
-	for scanner.Scan() {
-		line := scanner.Text()
+// procRouteIPv6 decodes an IPv6 address of /proc/net/ipv6_route.
+func procRouteIPv6(field string) (netip.Addr, error) {
+	b, err := hex.DecodeString(field)
+	if err != nil || len(b) != net.IPv6len {
+		return netip.Addr{}, fmt.Errorf("invalid IPv6 address %q", field)
+	}
+	return netip.AddrFrom16([16]byte(b)), nil
+}
 
-		// Detect the IPv4 Route Table section
-		if strings.Contains(line, "IPv4 Route Table") {
-			inIPv4Section = true
-			continue
+// filterRouteFamily keeps the routes of an address family (FamilyIPv4 or FamilyIPv6); routes
+// without a family (Windows) are kept for IPv4.
+func filterRouteFamily(routes []RouteEntry, family string) []RouteEntry {
+	if family == FamilyAny {
+		return routes
+	}
+	var filtered []RouteEntry
+	for _, route := range routes {
+		if (route.Family == RouteFamilyIPv6) == (family == FamilyIPv6) {
+			filtered = append(filtered, route)
 		}
+	}
+	return filtered
+}
 
-		if inIPv4Section {
-			// Skip until headers are found
-			if strings.HasPrefix(line, "===") || strings.HasPrefix(line, "Network Destination") {
-				continue
-			}
-
-			// An empty line signifies the end of the IPv4 section
-			if strings.TrimSpace(line) == "" {
-				break
-			}
+// PrintRoutes displays the routing entries in a formatted table.
+func PrintRoutes(routes []RouteEntry) {
+	t := utils.Table("DarkSimple", "routeCmd")
+	// Define table headers based on the operating system
+	if runtime.GOOS == "windows" {
+		t.AppendHeader(table.Row{"Network Destination", "Netmask", "Gateway", "Interface", "Metric"})
+		for _, route := range routes {
+			t.AppendRow(table.Row{
+				route.Destination,
+				route.Genmask,
+				route.Gateway,
+				route.Iface,
+				route.Metric,
+			})
+		}
+	} else {
+		// Name the table of each route when they come from several tables
+		tables := make(map[string]bool)
+		for _, route := range routes {
+			tables[route.Table] = true
+		}
+		showTable := len(tables) > 1
 
-			// Split the line into fields based on whitespace
-			fields := strings.Fields(line)
-			if len(fields) < 5 {
-				continue
+		header := table.Row{"Destination", "Gateway", "Iface", "Metric", "Source", "Protocol", "Scope", "Type", "Flags"}
+		if showTable {
+			header = append(table.Row{"Table"}, header...)
+		}
+		t.AppendHeader(header)
+		for _, route := range routes {
+			row := table.Row{
+				route.Destination,
+				valueOrDash(route.Gateway),
+				valueOrDash(route.Iface),
+				route.Metric,
+				valueOrDash(route.Source),
+				valueOrDash(route.Protocol),
+				valueOrDash(route.Scope),
+				valueOrDash(route.Type),
+				route.Flags,
 			}
-
-			route := RouteEntry{
-				Destination: fields[0],
-				Genmask:     fields[1],
-				Gateway:     fields[2],
-				Iface:       fields[3],
-				Metric:      fields[4],
-				Flags:       "N/A", // Flags are not directly available
-				Ref:         "N/A", // Not available
-				Use:         "N/A", // Not available
+			if showTable {
+				row = append(table.Row{valueOrDash(route.Table)}, row...)
 			}
-			routes = append(routes, route)
+			t.AppendRow(row)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading 'route print' output: %v", err)
-	}
+	fmt.Println()
+	t.Render()
+	fmt.Println()
+}
 
-	return routes, nil
+// init registers the RouteCmd with the root command and defines its flags.
+func init() {
+	RootCmd.AddCommand(RouteCmd)
+	RouteCmd.PersistentFlags().BoolP("ipv4", "4", false, "Show IPv4 routes and rules only")
+	RouteCmd.PersistentFlags().BoolP("ipv6", "6", false, "Show IPv6 routes and rules only")
+	RouteCmd.PersistentFlags().Bool("json", false, "Print the results as JSON")
+	RouteCmd.Flags().String("table", "main", "Routing table to show, by name or number, or \"all\" (Linux)")
+	RouteCmd.Flags().StringSlice("route-file", nil, "Read routes from saved copies of /proc/net/route or /proc/net/ipv6_route instead of the kernel")
+	RouteCmd.Flags().StringSlice("lookup", nil, "Show the route to these destinations (addresses, host names or CIDR blocks, looked up by their network address) instead of the table")
 }
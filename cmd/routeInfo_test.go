@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadRouteFiles(t *testing.T) {
+	// The fixtures are copies of /proc/net/route and /proc/net/ipv6_route, whose IPv4 words are
+	// printed in the byte order of a little-endian host
+	routes, err := ReadRouteFiles([]string{
+		filepath.Join("testdata", "route", "route"),
+		filepath.Join("testdata", "route", "ipv6_route"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v4 := func(dst, mask, gateway, flags, metric, use, iface, scope, kind string) RouteEntry {
+		return RouteEntry{Family: RouteFamilyIPv4, Destination: dst, Genmask: mask, Gateway: gateway, Flags: flags,
+			Metric: metric, Ref: "0", Use: use, Iface: iface, Scope: scope, Type: kind, Table: "main"}
+	}
+	want := []RouteEntry{
+		v4("0.0.0.0/0", "0.0.0.0", "192.168.0.1", "up, gateway", "100", "0", "eth0", "universe", "unicast"),
+		v4("192.168.0.0/24", "255.255.255.0", "", "up", "100", "0", "eth0", "link", "unicast"),
+		v4("10.0.0.0/8", "255.0.0.0", "", "up", "0", "0", "wg0", "link", "unicast"),
+		v4("10.0.2.1/32", "255.255.255.255", "192.168.0.1", "up, gateway, host", "0", "3", "eth0", "universe", "unicast"),
+		v4("172.18.0.0/16", "255.255.0.0", "", "up, reject", "0", "0", "lo", "nowhere", "unreachable"),
+		{Family: RouteFamilyIPv6, Destination: "2001:db8:1::/64", Flags: "up", Metric: "256", Ref: "1", Use: "0",
+			Iface: "eth0", Scope: "link", Type: "unicast", Table: "main"},
+		{Family: RouteFamilyIPv6, Destination: "fe80::/64", Flags: "up", Metric: "256", Ref: "1", Use: "0",
+			Iface: "eth0", Scope: "link", Type: "unicast", Table: "main"},
+		// Learned from a router advertisement; the local, anycast, multicast and null entries that
+		// follow in the file are left out
+		{Family: RouteFamilyIPv6, Destination: "::/0", Gateway: "fe80::1", Flags: "up, gateway, default, addrconf, expires",
+			Metric: "1024", Ref: "2", Use: "7", Iface: "eth0", Protocol: "ra", Scope: "universe", Type: "unicast", Table: "main"},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("got %d routes, want %d: %+v", len(routes), len(want), routes)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(routes[i], want[i]) {
+			t.Errorf("route %d = %+v\nwant %+v", i, routes[i], want[i])
+		}
+	}
+}
+
+func TestParseRouteFileErrors(t *testing.T) {
+	tests := []string{
+		"Iface\tDestination\tGateway\tFlags\tRefCnt\tUse\tMetric\tMask\neth0\tzz000000\t00000000\t0001\t0\t0\t0\t00000000\n",
+		"Iface\tDestination\tGateway\tFlags\tRefCnt\tUse\tMetric\tMask\neth0\t00000000\t00000000\t0001\t0\t0\t0\t00FF00FF\n",
+		"20010db8 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001 eth0\n",
+		"20010db8000100000000000000000000 81 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001 eth0\n",
+	}
+	for _, input := range tests {
+		if routes, err := parseRouteFile(strings.NewReader(input)); err == nil {
+			t.Errorf("parseRouteFile(%q) = %+v, want an error", input, routes)
+		}
+	}
+	if routes, err := parseRouteFile(strings.NewReader("")); err != nil || len(routes) != 0 {
+		t.Errorf("empty file = %+v, %v", routes, err)
+	}
+}
+
+func TestFilterRouteFamily(t *testing.T) {
+	routes := []RouteEntry{{Family: RouteFamilyIPv4}, {Family: RouteFamilyIPv6}, {}}
+	if got := filterRouteFamily(routes, FamilyIPv4); len(got) != 2 || got[1].Family != "" {
+		t.Errorf("IPv4 routes = %+v, want the Windows route kept", got)
+	}
+	if got := filterRouteFamily(routes, FamilyIPv6); len(got) != 1 || got[0].Family != RouteFamilyIPv6 {
+		t.Errorf("IPv6 routes = %+v", got)
+	}
+}
+
+func TestGetRouteTable(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only Linux has several routing tables")
+	}
+	main, err := GetRouteTable(RouteTableMain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range main {
+		if route.Table != "main" {
+			t.Errorf("route %s of table %s listed in main", route.Destination, route.Table)
+		}
+	}
+	all, err := GetRouteTable(RouteTableAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The local table holds at least the loopback addresses
+	local := 0
+	for _, route := range all {
+		if route.Table == "local" {
+			local++
+		}
+	}
+	if local == 0 || len(all) < len(main)+local {
+		t.Errorf("%d routes in every table with %d local, %d in main", len(all), local, len(main))
+	}
+}
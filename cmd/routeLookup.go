@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+)
+
+// RouteLookup is the path traffic to one destination would take.
+type RouteLookup struct {
+	Destination string      `json:"destination"`       // Host name or address as requested
+	Address     string      `json:"address"`           // Address looked up
+	Prefix      string      `json:"prefix,omitempty"`  // Longest matching prefix of the routing table
+	Route       *RouteEntry `json:"route,omitempty"`   // Route of the routing table the prefix belongs to
+	Iface       string      `json:"iface,omitempty"`   // Outgoing interface
+	Gateway     string      `json:"gateway,omitempty"` // Next hop; empty when the destination is on-link
+	Source      string      `json:"source,omitempty"`  // Source address the kernel would choose
+	Table       string      `json:"table,omitempty"`   // Table the kernel found the route in (Linux)
+	Type        string      `json:"type,omitempty"`    // unicast, local, unreachable, ...
+	Kernel      bool        `json:"kernel"`            // Whether the kernel answered the lookup itself
+	Mismatch    bool        `json:"mismatch"`          // The kernel uses a different path than the matched route, e.g. through policy routing
+	Error       string      `json:"error,omitempty"`
+}
+
+// RunRouteLookup looks up the route to each destination, given as host names or addresses as
+// accepted by ParseScanTargets, or CIDR blocks, by longest-prefix match against routes. A CIDR
+// block is looked up once, by its network address, rather than address by address. With
+// useKernel, the kernel is also asked which route, interface and source address it would use,
+// which accounts for local addresses and policy rules that the table alone does not show.
+func RunRouteLookup(destinations []string, routes []RouteEntry, family string, useKernel bool) ([]RouteLookup, error) {
+	var lookups []RouteLookup
+	for _, entry := range strings.Split(strings.Join(destinations, ","), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			addr, err := routeLookupNetwork(entry, family)
+			if err != nil {
+				return nil, err
+			}
+			lookups = append(lookups, LookupRoute(routes, entry, addr, useKernel))
+			continue
+		}
+		targets, err := ParseScanTargets(entry, family)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range targets {
+			addr, err := netip.ParseAddr(target.Address)
+			if err != nil {
+				return nil, err
+			}
+			lookups = append(lookups, LookupRoute(routes, target.Name, addr, useKernel))
+		}
+	}
+	if len(lookups) == 0 {
+		return nil, fmt.Errorf("no destinations specified")
+	}
+	return lookups, nil
+}
+
+// routeLookupNetwork returns the network address of a CIDR entry (optionally followed by %zone).
+func routeLookupNetwork(entry string, family string) (netip.Addr, error) {
+	cidr, zone, _ := strings.Cut(entry, "%")
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+	}
+	addr := prefix.Masked().Addr()
+	if !familyMatches(addr, family) {
+		return netip.Addr{}, fmt.Errorf("CIDR %s does not match the requested address family", entry)
+	}
+	if zone != "" {
+		addr = addr.WithZone(zone)
+	}
+	return addr, nil
+}
+
+// LookupRoute finds the route to a single address.
+func LookupRoute(routes []RouteEntry, name string, addr netip.Addr, useKernel bool) RouteLookup {
+	lookup := RouteLookup{Destination: name, Address: addr.String()}
+	if route, prefix, ok := matchRoute(routes, addr.WithZone("")); ok {
+		lookup.Prefix = prefix.String()
+		lookup.Route = &route
+		lookup.Iface = route.Iface
+		lookup.Gateway = routeGateway(route)
+		lookup.Type = route.Type
+	}
+	if !useKernel {
+		if lookup.Route == nil {
+			lookup.Error = "no matching route"
+		}
+		return lookup
+	}
+
+	answer, err := kernelRouteLookup(addr.WithZone(""))
+	// The kernel answers a lookup it cannot route with a bare errno, e.g. "network is
+	// unreachable"; any other error means it could not be asked
+	errno, refused := err.(syscall.Errno)
+	switch {
+	case err == nil:
+		lookup.Kernel = true
+		if answer.Type == "local" {
+			// Addresses of this host are routed by the local table, not the matched route
+			lookup.Prefix, lookup.Route = "", nil
+		}
+		lookup.Mismatch = lookup.Route != nil && (answer.Iface != lookup.Iface || answer.Gateway != lookup.Gateway)
+		lookup.Iface = answer.Iface
+		lookup.Gateway = answer.Gateway
+		lookup.Source = answer.Source
+		lookup.Table = answer.Table
+		lookup.Type = answer.Type
+	case refused:
+		lookup.Kernel = true
+		lookup.Error = errno.Error()
+		return lookup
+	default:
+		// Not supported on this platform, or netlink is unavailable: keep the table match
+		if lookup.Route == nil {
+			lookup.Error = "no matching route"
+			return lookup
+		}
+	}
+
+	if lookup.Source == "" {
+		lookup.Source = routeSourceAddress(addr)
+	}
+	return lookup
+}
+
+// matchRoute returns the route with the longest prefix containing addr, preferring the lowest
+// metric among routes with the same prefix length.
+func matchRoute(routes []RouteEntry, addr netip.Addr) (RouteEntry, netip.Prefix, bool) {
+	var best RouteEntry
+	var bestPrefix netip.Prefix
+	var bestMetric uint64
+	found := false
+	for _, route := range routes {
+		prefix, ok := routePrefix(route)
+		if !ok || prefix.Addr().Is4() != addr.Is4() || !prefix.Contains(addr) {
+			continue
+		}
+		metric, _ := strconv.ParseUint(route.Metric, 10, 64)
+		if !found || prefix.Bits() > bestPrefix.Bits() || (prefix.Bits() == bestPrefix.Bits() && metric < bestMetric) {
+			best, bestPrefix, bestMetric, found = route, prefix, metric, true
+		}
+	}
+	return best, bestPrefix, found
+}
+
+// routePrefix returns the destination of a route as a prefix: CIDR on Linux, or an address
+// and netmask on Windows.
+func routePrefix(route RouteEntry) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(route.Destination); err == nil {
+		return prefix.Masked(), true
+	}
+	addr, err := netip.ParseAddr(route.Destination)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	bits, err := netmaskBits(route.Genmask)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	prefix, err := addr.Prefix(bits)
+	return prefix, err == nil
+}
+
+// routeGateway returns the next hop of a route, or "" for directly connected networks.
+func routeGateway(route RouteEntry) string {
+	if route.Gateway == "On-link" {
+		return ""
+	}
+	return route.Gateway
+}
+
+// routeSourceAddress returns the source address the kernel selects for traffic to addr, read
+// from a connected UDP socket; connecting sends nothing.
+func routeSourceAddress(addr netip.Addr) string {
+	conn, err := net.Dial("udp", net.JoinHostPort(addr.String(), "9"))
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	if local, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		return local.IP.String()
+	}
+	return ""
+}
+
+// PrintRouteLookups displays the route to each destination in a formatted table.
+func PrintRouteLookups(lookups []RouteLookup) {
+	t := utils.Table("DarkSimple", "Route Lookup")
+	t.AppendHeader(table.Row{"Destination", "Matched Route", "Gateway", "Iface", "Source", "Table", "Note"})
+	mismatches := 0
+	for _, lookup := range lookups {
+		destination := lookup.Destination
+		if destination != lookup.Address {
+			destination = fmt.Sprintf("%s (%s)", lookup.Destination, lookup.Address)
+		}
+		note := ""
+		switch {
+		case lookup.Error != "":
+			note = lookup.Error
+		case lookup.Mismatch:
+			note = "kernel path differs from matched route"
+			mismatches++
+		case lookup.Type != "" && lookup.Type != "unicast":
+			note = lookup.Type
+		}
+		t.AppendRow(table.Row{
+			destination,
+			valueOrDash(lookup.Prefix),
+			valueOrDash(lookup.Gateway),
+			valueOrDash(lookup.Iface),
+			valueOrDash(lookup.Source),
+			valueOrDash(lookup.Table),
+			note,
+		})
+	}
+	fmt.Println()
+	t.Render()
+	if mismatches > 0 {
+		fmt.Printf("%d destination(s) take a different path than the matched route, e.g. through policy routing.\n", mismatches)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"net/netip"
+	"runtime"
+	"testing"
+)
+
+// testRoutes is a routing table with a default route, overlapping prefixes and two routes to
+// the same prefix with different metrics.
+var testRoutes = []RouteEntry{
+	{Destination: "0.0.0.0/0", Gateway: "192.168.0.1", Iface: "eth0", Metric: "100", Type: "unicast"},
+	{Destination: "192.168.0.0/24", Iface: "eth0", Metric: "100", Type: "unicast"},
+	{Destination: "10.0.0.0/8", Iface: "wg0", Metric: "50", Type: "unicast"},
+	{Destination: "10.0.0.0/8", Gateway: "192.168.0.254", Iface: "eth0", Metric: "20", Type: "unicast"},
+	{Destination: "10.2.0.0/16", Iface: "wg1", Metric: "0", Type: "unicast"},
+	{Destination: "2001:db8::/32", Gateway: "fe80::1", Iface: "eth0", Metric: "1024", Type: "unicast"},
+	// As 'route print' lists them on Windows
+	{Destination: "172.16.0.0", Genmask: "255.240.0.0", Gateway: "On-link", Iface: "192.168.0.10", Metric: "25"},
+	{Destination: "not a route"},
+}
+
+func TestMatchRoute(t *testing.T) {
+	tests := []struct {
+		addr   string
+		prefix string
+		iface  string
+	}{
+		{"10.2.3.4", "10.2.0.0/16", "wg1"},
+		{"10.3.0.1", "10.0.0.0/8", "eth0"}, // The lower metric wins
+		{"192.168.0.7", "192.168.0.0/24", "eth0"},
+		{"8.8.8.8", "0.0.0.0/0", "eth0"},
+		{"172.20.1.1", "172.16.0.0/12", "192.168.0.10"},
+		{"2001:db8:5::1", "2001:db8::/32", "eth0"},
+		{"2001:4860::8888", "", ""}, // No IPv6 default route
+	}
+	for _, tt := range tests {
+		route, prefix, ok := matchRoute(testRoutes, netip.MustParseAddr(tt.addr))
+		if tt.prefix == "" {
+			if ok {
+				t.Errorf("matchRoute(%s) = %s, want no match", tt.addr, prefix)
+			}
+			continue
+		}
+		if !ok || prefix.String() != tt.prefix || route.Iface != tt.iface {
+			t.Errorf("matchRoute(%s) = %s via %s, want %s via %s", tt.addr, prefix, route.Iface, tt.prefix, tt.iface)
+		}
+	}
+}
+
+func TestLookupRouteTable(t *testing.T) {
+	lookup := LookupRoute(testRoutes, "vpn.example", netip.MustParseAddr("10.9.9.9"), false)
+	want := RouteLookup{Destination: "vpn.example", Address: "10.9.9.9", Prefix: "10.0.0.0/8", Route: &testRoutes[3],
+		Iface: "eth0", Gateway: "192.168.0.254", Type: "unicast"}
+	if lookup.Route == nil || *lookup.Route != *want.Route {
+		t.Fatalf("route = %+v, want %+v", lookup.Route, want.Route)
+	}
+	lookup.Route = want.Route
+	if lookup != want {
+		t.Errorf("lookup = %+v, want %+v", lookup, want)
+	}
+
+	if lookup := LookupRoute(testRoutes, "172.31.0.1", netip.MustParseAddr("172.31.0.1"), false); lookup.Gateway != "" {
+		t.Errorf("an on-link route has gateway %q", lookup.Gateway)
+	}
+	if lookup := LookupRoute(testRoutes, "::1", netip.MustParseAddr("::1"), false); lookup.Route != nil || lookup.Error != "no matching route" {
+		t.Errorf("lookup = %+v, want no matching route", lookup)
+	}
+}
+
+func TestRunRouteLookup(t *testing.T) {
+	// CIDR blocks of any size are looked up once, by their network address
+	lookups, err := RunRouteLookup([]string{"10.2.0.0/16, 10.0.0.0/8", "192.168.0.77/24,2001:db8::1"}, testRoutes, FamilyAny, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ destination, address, prefix string }{
+		{"10.2.0.0/16", "10.2.0.0", "10.2.0.0/16"},
+		{"10.0.0.0/8", "10.0.0.0", "10.0.0.0/8"},
+		{"192.168.0.77/24", "192.168.0.0", "192.168.0.0/24"},
+		{"2001:db8::1", "2001:db8::1", "2001:db8::/32"},
+	}
+	if len(lookups) != len(want) {
+		t.Fatalf("got %d lookups, want %d: %+v", len(lookups), len(want), lookups)
+	}
+	for i, w := range want {
+		if lookups[i].Destination != w.destination || lookups[i].Address != w.address || lookups[i].Prefix != w.prefix {
+			t.Errorf("lookup %d = %s (%s) via %s, want %s (%s) via %s", i, lookups[i].Destination, lookups[i].Address, lookups[i].Prefix,
+				w.destination, w.address, w.prefix)
+		}
+	}
+
+	for _, tt := range []struct {
+		destinations []string
+		family       string
+	}{
+		{[]string{"10.0.0.0/33"}, FamilyAny},
+		{[]string{"2001:db8::/48"}, FamilyIPv4},
+		{[]string{"10.0.0.1"}, FamilyIPv6},
+		{[]string{" , "}, FamilyAny},
+	} {
+		if lookups, err := RunRouteLookup(tt.destinations, testRoutes, tt.family, false); err == nil {
+			t.Errorf("RunRouteLookup(%q, %q) = %+v, want an error", tt.destinations, tt.family, lookups)
+		}
+	}
+	// Destinations go to --lookup; an argument would otherwise be dropped silently
+	if err := RouteCmd.Args(RouteCmd, []string{"::1"}); err == nil {
+		t.Error("routeinfo accepted a positional argument")
+	}
+}
+
+func TestLookupRouteKernel(t *testing.T) {
+	// The table says to use the default route, but the kernel routes the host's own address
+	// through the local table
+	routes := []RouteEntry{{Destination: "0.0.0.0/0", Gateway: "192.0.2.1", Iface: "eth9", Metric: "0", Type: "unicast"}}
+	lookup := LookupRoute(routes, "127.0.0.1", netip.MustParseAddr("127.0.0.1"), true)
+	if lookup.Error != "" {
+		t.Fatal(lookup.Error)
+	}
+	if runtime.GOOS != "linux" {
+		// Only Linux answers the lookup itself; elsewhere the table match is kept
+		if lookup.Kernel || lookup.Iface != "eth9" {
+			t.Errorf("lookup = %+v, want the table match", lookup)
+		}
+		return
+	}
+	if !lookup.Kernel || lookup.Type != "local" || lookup.Route != nil || lookup.Table != "local" || lookup.Mismatch {
+		t.Errorf("lookup = %+v, want the kernel's local route", lookup)
+	}
+	if lookup.Source != "127.0.0.1" || lookup.Gateway != "" {
+		t.Errorf("source %q gateway %q, want 127.0.0.1 and none", lookup.Source, lookup.Gateway)
+	}
+}
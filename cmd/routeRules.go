@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+	"github.com/spf13/cobra"
+)
+
+// RouteRulesCmd lists the routing policy rules.
+var RouteRulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Displays the routing policy rules (Linux)",
+	Long: `Lists the routing policy rules that select the routing table for each packet, like 'ip rule',
+read from the kernel over netlink: the priority, the selector (source and destination prefix,
+incoming and outgoing interface, TOS, IP protocol, ports and user range), the firewall mark
+and the action, usually the table to look up. Rules are evaluated in priority order.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		family, err := addressFamilyFlag(cmd)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		rules, err := GetRouteRules()
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if family != FamilyAny {
+			var filtered []RouteRule
+			for _, rule := range rules {
+				if (rule.Family == RouteFamilyIPv6) == (family == FamilyIPv6) {
+					filtered = append(filtered, rule)
+				}
+			}
+			rules = filtered
+		}
+
+		if jsonOutput {
+			utils.PrintJSON(rules)
+			return
+		}
+		PrintRouteRules(rules)
+	},
+}
+
+// RouteRule is one routing policy rule.
+type RouteRule struct {
+	Family     string `json:"family"`               // RouteFamilyIPv4 or RouteFamilyIPv6
+	Priority   uint32 `json:"priority"`             // Lower priorities are evaluated first
+	Invert     bool   `json:"invert"`               // The rule applies to packets the selector does not match
+	From       string `json:"from,omitempty"`       // Source prefix; empty matches all
+	To         string `json:"to,omitempty"`         // Destination prefix; empty matches all
+	IIF        string `json:"iif,omitempty"`        // Incoming interface
+	OIF        string `json:"oif,omitempty"`        // Outgoing interface
+	TOS        uint8  `json:"tos,omitempty"`        // Type of service
+	IPProto    string `json:"ipProto,omitempty"`    // IP protocol, e.g. tcp
+	SPort      string `json:"sport,omitempty"`      // Source port range
+	DPort      string `json:"dport,omitempty"`      // Destination port range
+	UIDRange   string `json:"uidRange,omitempty"`   // Range of socket owner UIDs
+	FwMark     string `json:"fwmark,omitempty"`     // Firewall mark and mask, e.g. 0x1/0xff
+	Action     string `json:"action"`               // lookup, goto, nop, blackhole, unreachable or prohibit
+	Table      string `json:"table,omitempty"`      // Table looked up by the lookup action
+	Goto       uint32 `json:"goto,omitempty"`       // Priority jumped to by the goto action
+	Protocol   string `json:"protocol,omitempty"`   // What installed the rule: kernel, boot, static, ...
+	Suppress   string `json:"suppress,omitempty"`   // Suppression of the lookup result, e.g. "prefixlength 0"
+	Unresolved bool   `json:"unresolved,omitempty"` // A goto rule whose target does not exist
+}
+
+// Selector describes the packets a rule applies to, in the words of 'ip rule', excluding the
+// firewall mark.
+func (r RouteRule) Selector() string {
+	from := r.From
+	if from == "" {
+		from = "all"
+	}
+	parts := []string{"from", from}
+	if r.To != "" {
+		parts = append(parts, "to", r.To)
+	}
+	if r.TOS != 0 {
+		parts = append(parts, "tos", fmt.Sprintf("0x%02x", r.TOS))
+	}
+	for _, option := range []struct{ name, value string }{
+		{"iif", r.IIF}, {"oif", r.OIF}, {"ipproto", r.IPProto}, {"sport", r.SPort}, {"dport", r.DPort}, {"uidrange", r.UIDRange},
+	} {
+		if option.value != "" {
+			parts = append(parts, option.name, option.value)
+		}
+	}
+	selector := strings.Join(parts, " ")
+	if r.Invert {
+		selector = "not " + selector
+	}
+	return selector
+}
+
+// ActionString describes the action of a rule, e.g. "lookup main" or "goto 32000".
+func (r RouteRule) ActionString() string {
+	action := r.Action
+	switch r.Action {
+	case "lookup":
+		action += " " + r.Table
+	case "goto":
+		action += " " + strconv.FormatUint(uint64(r.Goto), 10)
+		if r.Unresolved {
+			action += " (unresolved)"
+		}
+	}
+	if r.Suppress != "" {
+		action += " suppress_" + r.Suppress
+	}
+	return action
+}
+
+// routeRuleActionNames maps rule actions (FR_ACT_*, linux/fib_rules.h) to the words of 'ip rule'.
+var routeRuleActionNames = map[uint8]string{
+	0: "unspec", 1: "lookup", 2: "goto", 3: "nop", 6: "blackhole", 7: "unreachable", 8: "prohibit",
+}
+
+// GetRouteRules retrieves the routing policy rules of both address families, in priority order.
+// Policy rules are only available on Linux.
+func GetRouteRules() ([]RouteRule, error) {
+	return readRouteRules()
+}
+
+// PrintRouteRules displays the routing policy rules in a formatted table.
+func PrintRouteRules(rules []RouteRule) {
+	t := utils.Table("DarkSimple", "Policy Rules")
+	t.AppendHeader(table.Row{"Priority", "Family", "Selector", "FwMark", "Action", "Protocol"})
+	for _, rule := range rules {
+		t.AppendRow(table.Row{
+			rule.Priority,
+			rule.Family,
+			rule.Selector(),
+			valueOrDash(rule.FwMark),
+			rule.ActionString(),
+			valueOrDash(rule.Protocol),
+		})
+	}
+	fmt.Println()
+	t.Render()
+	fmt.Println()
+}
+
+// init registers the RouteRulesCmd with the routeinfo command when this package is imported.
+func init() {
+	RouteCmd.AddCommand(RouteRulesCmd)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParseRouteTableNames(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "route", "rt_tables"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want := map[uint32]string{255: "local", 254: "main", 253: "default", 0: "unspec", 100: "vpn", 128: "backup"}
+	if got := parseRouteTableNames(f); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+}
+
+func TestParseRouteTable(t *testing.T) {
+	// A custom table, as if read from rt_tables
+	loadRouteTableNames()
+	routeTableNames[1234] = "ghost-test"
+	t.Cleanup(func() { delete(routeTableNames, 1234) })
+
+	tests := []struct {
+		table string
+		id    uint32
+		err   bool
+	}{
+		{table: "all", id: RouteTableAll},
+		{table: "main", id: RouteTableMain},
+		{table: "local", id: 255},
+		{table: "ghost-test", id: 1234},
+		{table: "100", id: 100},
+		{table: "0x80", id: 128},
+		{table: "no-such-table", err: true},
+		{table: "4294967296", err: true},
+	}
+	for _, tt := range tests {
+		id, err := ParseRouteTable(tt.table)
+		if (err != nil) != tt.err || id != tt.id {
+			t.Errorf("ParseRouteTable(%q) = %d, %v; want %d, error %v", tt.table, id, err, tt.id, tt.err)
+		}
+	}
+	if name := routeTableName(1234); name != "ghost-test" {
+		t.Errorf("routeTableName(1234) = %q", name)
+	}
+	if name := routeTableName(4321); name != "4321" {
+		t.Errorf("an unnamed table is called %q, want its number", name)
+	}
+}
+
+func TestRouteRuleStrings(t *testing.T) {
+	tests := []struct {
+		rule     RouteRule
+		selector string
+		action   string
+	}{
+		{RouteRule{Action: "lookup", Table: "main"}, "from all", "lookup main"},
+		{
+			RouteRule{From: "10.0.0.0/8", To: "192.168.0.0/16", TOS: 0x10, IIF: "eth0", IPProto: "tcp", DPort: "443", Action: "lookup", Table: "vpn"},
+			"from 10.0.0.0/8 to 192.168.0.0/16 tos 0x10 iif eth0 ipproto tcp dport 443", "lookup vpn",
+		},
+		{RouteRule{Invert: true, OIF: "wg0", UIDRange: "1000-1999", Action: "prohibit"}, "not from all oif wg0 uidrange 1000-1999", "prohibit"},
+		{RouteRule{Action: "goto", Goto: 32000, Unresolved: true}, "from all", "goto 32000 (unresolved)"},
+		{RouteRule{Action: "lookup", Table: "main", Suppress: "prefixlength 0"}, "from all", "lookup main suppress_prefixlength 0"},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Selector(); got != tt.selector {
+			t.Errorf("Selector() = %q, want %q", got, tt.selector)
+		}
+		if got := tt.rule.ActionString(); got != tt.action {
+			t.Errorf("ActionString() = %q, want %q", got, tt.action)
+		}
+	}
+}
+
+func TestGetRouteRules(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		if _, err := GetRouteRules(); err == nil {
+			t.Error("policy rules were read outside Linux")
+		}
+		return
+	}
+	rules, err := GetRouteRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every network namespace starts with rules that look up the local and main tables
+	found := map[string]bool{}
+	for i, rule := range rules {
+		if i > 0 && rule.Family == rules[i-1].Family && rule.Priority < rules[i-1].Priority {
+			t.Errorf("rule %d (priority %d) follows priority %d", i, rule.Priority, rules[i-1].Priority)
+		}
+		if rule.Family == RouteFamilyIPv4 && rule.Action == "lookup" {
+			found[rule.Table] = true
+		}
+	}
+	if !found["local"] || !found["main"] {
+		t.Errorf("rules = %+v, want lookups of the local and main tables", rules)
+	}
+}
@@ -0,0 +1,395 @@
+//go:build linux
+// +build linux
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// Netlink route attributes (linux/rtnetlink.h).
+const (
+	rtaDst       = 1
+	rtaOif       = 4
+	rtaGateway   = 5
+	rtaPriority  = 6
+	rtaPrefSrc   = 7
+	rtaMultipath = 9
+	rtaTable     = 15
+	rtaVia       = 18
+)
+
+// Route header and next-hop flags.
+const (
+	rtnhFlagDead     = 0x01
+	rtnhFlagOnlink   = 0x04
+	rtnhFlagLinkdown = 0x10
+	rtmFlagCloned    = 0x200
+)
+
+// RTM_F_LOOKUP_TABLE asks a route lookup to report the table the matching route was found in.
+const rtmFlagLookupTable = 0x1000
+
+// Netlink policy rule attributes (linux/fib_rules.h).
+const (
+	fraDst               = 1
+	fraSrc               = 2
+	fraIIFName           = 3
+	fraGoto              = 4
+	fraPriority          = 6
+	fraFwMark            = 10
+	fraSuppressIfgroup   = 13
+	fraSuppressPrefixlen = 14
+	fraTable             = 15
+	fraFwMask            = 16
+	fraOIFName           = 17
+	fraUIDRange          = 20
+	fraProtocol          = 21
+	fraIPProto           = 22
+	fraSportRange        = 23
+	fraDportRange        = 24
+)
+
+// Policy rule flags.
+const (
+	fibRuleInvert     = 0x02
+	fibRuleUnresolved = 0x04
+)
+
+// fibRuleHdrLen is the size of struct fib_rule_hdr, the fixed header of rule messages.
+const fibRuleHdrLen = 12
+
+// ipProtocolNames names the IP protocols policy rules commonly select.
+var ipProtocolNames = map[uint8]string{1: "icmp", 6: "tcp", 17: "udp", 58: "ipv6-icmp", 132: "sctp"}
+
+// rtmsgLen is the size of struct rtmsg, the fixed header of route messages.
+const rtmsgLen = 12
+
+// readRoutes reads the IPv4 and IPv6 routes of a routing table (RouteTableAll for every table)
+// over netlink, falling back to /proc/net/route and /proc/net/ipv6_route for the main table;
+// those lack the protocol and preferred source.
+func readRoutes(table uint32) ([]RouteEntry, error) {
+	msgs, err := netlinkDump(syscall.RTM_GETROUTE, syscall.AF_UNSPEC, syscall.RTM_NEWROUTE, rtmsgLen)
+	if err == nil {
+		return netlinkRoutes(msgs, table), nil
+	}
+	if table != RouteTableMain {
+		return nil, fmt.Errorf("reading routing table %s: %w", routeTableName(table), err)
+	}
+
+	var routes []RouteEntry
+	for _, path := range []string{"/proc/net/route", "/proc/net/ipv6_route"} {
+		f, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // IPv6 disabled
+			}
+			return nil, fmt.Errorf("reading routes: %w", err)
+		}
+		entries, err := parseRouteFile(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		routes = append(routes, entries...)
+	}
+	return routes, nil
+}
+
+// netlinkRoutes converts the messages of an RTM_GETROUTE dump into routes of a table
+// (RouteTableAll for every table), one per next hop for multipath routes.
+func netlinkRoutes(msgs []netlinkMessage, table uint32) []RouteEntry {
+	var routes []RouteEntry
+	for _, msg := range msgs {
+		flags := binary.NativeEndian.Uint32(msg.Header[8:12])
+		if (table != RouteTableAll && netlinkRouteTable(msg) != table) || flags&rtmFlagCloned != 0 {
+			continue
+		}
+		routes = append(routes, netlinkRouteEntries(msg)...)
+	}
+	return routes
+}
+
+// netlinkRouteTable returns the table of a route message: RTA_TABLE, or the 8-bit rtm_table for
+// kernels that do not send it.
+func netlinkRouteTable(msg netlinkMessage) uint32 {
+	if table := netlinkUint32(msg.Attrs, rtaTable); table != 0 {
+		return table
+	}
+	return uint32(msg.Header[4])
+}
+
+// netlinkRouteEntries converts one route message into entries, one per next hop.
+func netlinkRouteEntries(msg netlinkMessage) []RouteEntry {
+	// struct rtmsg: family, dst_len, src_len, tos, table, protocol, scope, type, flags (u32)
+	h := msg.Header
+	family, dstLen := h[0], int(h[1])
+	flags := binary.NativeEndian.Uint32(h[8:12])
+	route := RouteEntry{
+		Protocol: routeNumberName(routeProtocolNames, h[5]),
+		Scope:    routeNumberName(routeScopeNames, h[6]),
+		Type:     routeNumberName(routeTypeNames, h[7]),
+		Metric:   strconv.FormatUint(uint64(netlinkUint32(msg.Attrs, rtaPriority)), 10),
+		Table:    routeTableName(netlinkRouteTable(msg)),
+	}
+	var unspecified netip.Addr
+	switch family {
+	case syscall.AF_INET:
+		route.Family, unspecified = RouteFamilyIPv4, netip.IPv4Unspecified()
+	case syscall.AF_INET6:
+		route.Family, unspecified = RouteFamilyIPv6, netip.IPv6Unspecified()
+	default:
+		return nil
+	}
+	dst := unspecified
+	if addr, ok := netip.AddrFromSlice(msg.Attrs[rtaDst]); ok {
+		dst = addr
+	}
+	route.Destination = netip.PrefixFrom(dst, dstLen).String()
+	if addr, ok := netip.AddrFromSlice(msg.Attrs[rtaPrefSrc]); ok {
+		route.Source = addr.String()
+	}
+
+	var rtf uint32 = rtfUp
+	if dstLen == unspecified.BitLen() {
+		rtf |= rtfHost
+	}
+	switch h[7] {
+	case syscall.RTN_BLACKHOLE, syscall.RTN_UNREACHABLE, syscall.RTN_PROHIBIT:
+		rtf |= rtfReject
+	}
+
+	hops := netlinkNexthops(msg.Attrs[rtaMultipath])
+	if len(hops) == 0 {
+		hops = []routeNexthop{{
+			ifindex: int(int32(netlinkUint32(msg.Attrs, rtaOif))),
+			gateway: netlinkGateway(msg.Attrs),
+			flags:   uint8(flags),
+		}}
+	}
+	var routes []RouteEntry
+	for _, hop := range hops {
+		entry := route
+		entry.Iface = netlinkInterfaceName(hop.ifindex)
+		hopFlags := rtf
+		if hop.gateway.IsValid() {
+			entry.Gateway = hop.gateway.String()
+			hopFlags |= rtfGateway
+		}
+		entry.Flags = routeFlagWords(hopFlags)
+		for _, f := range []struct {
+			flag uint8
+			name string
+		}{{rtnhFlagOnlink, "onlink"}, {rtnhFlagLinkdown, "linkdown"}, {rtnhFlagDead, "dead"}} {
+			if hop.flags&f.flag != 0 {
+				entry.Flags += ", " + f.name
+			}
+		}
+		routes = append(routes, entry)
+	}
+	return routes
+}
+
+// routeNexthop is one next hop of a route.
+type routeNexthop struct {
+	ifindex int
+	gateway netip.Addr
+	flags   uint8
+}
+
+// netlinkNexthops decodes RTA_MULTIPATH: struct rtnexthop entries (length u16, flags, hops,
+// ifindex int32), each followed by its own attributes.
+func netlinkNexthops(b []byte) []routeNexthop {
+	var hops []routeNexthop
+	for len(b) >= 8 {
+		length := int(binary.NativeEndian.Uint16(b[0:2]))
+		if length < 8 || length > len(b) {
+			break
+		}
+		hops = append(hops, routeNexthop{
+			ifindex: int(int32(binary.NativeEndian.Uint32(b[4:8]))),
+			gateway: netlinkGateway(parseNetlinkAttrs(b[8:length])),
+			flags:   b[2],
+		})
+		b = b[min(netlinkAlign(length), len(b)):]
+	}
+	return hops
+}
+
+// netlinkGateway returns the gateway of RTA_GATEWAY or, for IPv4 routes via an IPv6 next hop,
+// RTA_VIA (2-byte family followed by the address).
+func netlinkGateway(attrs map[uint16][]byte) netip.Addr {
+	if addr, ok := netip.AddrFromSlice(attrs[rtaGateway]); ok {
+		return addr
+	}
+	if via := attrs[rtaVia]; len(via) > 2 {
+		if addr, ok := netip.AddrFromSlice(via[2:]); ok {
+			return addr
+		}
+	}
+	return netip.Addr{}
+}
+
+// kernelRouteLookup asks the kernel which route it would use for traffic to addr (like
+// 'ip route get'), policy rules included. The answer carries the outgoing interface, gateway,
+// chosen source address and the table the route was found in. When the kernel has no route,
+// its answer is returned as a bare syscall.Errno; failures to ask it are wrapped.
+func kernelRouteLookup(addr netip.Addr) (*RouteEntry, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	defer syscall.Close(fd)
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("netlink bind: %w", err)
+	}
+
+	family := syscall.AF_INET
+	if addr.Is6() {
+		family = syscall.AF_INET6
+	}
+	dst := addr.AsSlice()
+	attrLen := syscall.SizeofRtAttr + len(dst)
+	req := make([]byte, syscall.NLMSG_HDRLEN+rtmsgLen+netlinkAlign(attrLen))
+	// struct nlmsghdr: length, type, flags, sequence, port id
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], syscall.RTM_GETROUTE)
+	binary.NativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST)
+	binary.NativeEndian.PutUint32(req[8:12], 1)
+	rtm := req[syscall.NLMSG_HDRLEN:]
+	rtm[0], rtm[1] = byte(family), byte(addr.BitLen())
+	binary.NativeEndian.PutUint32(rtm[8:12], rtmFlagLookupTable)
+	attr := rtm[rtmsgLen:]
+	binary.NativeEndian.PutUint16(attr[0:2], uint16(attrLen))
+	binary.NativeEndian.PutUint16(attr[2:4], rtaDst)
+	copy(attr[4:], dst)
+
+	if err := syscall.Sendto(fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("netlink send: %w", err)
+	}
+	buf := make([]byte, 8192)
+	n, _, err := syscall.Recvfrom(fd, buf, 0)
+	if err != nil {
+		return nil, fmt.Errorf("netlink receive: %w", err)
+	}
+	msgs, err := parseNetlinkDump(buf[:n], syscall.RTM_NEWROUTE, rtmsgLen)
+	if err != nil {
+		// The kernel's answer, e.g. "network is unreachable"
+		var errno syscall.Errno
+		if errors.As(err, &errno) {
+			return nil, errno
+		}
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("no route returned")
+	}
+	entries := netlinkRouteEntries(msgs[0])
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no route returned")
+	}
+	return &entries[0], nil
+}
+
+// readRouteRules dumps the routing policy rules of both address families with RTM_GETRULE. The
+// kernel returns them in priority order.
+func readRouteRules() ([]RouteRule, error) {
+	msgs, err := netlinkDump(syscall.RTM_GETRULE, syscall.AF_UNSPEC, syscall.RTM_NEWRULE, fibRuleHdrLen)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy rules: %w", err)
+	}
+
+	var rules []RouteRule
+	for _, msg := range msgs {
+		// struct fib_rule_hdr: family, dst_len, src_len, tos, table, res1, res2, action, flags (u32)
+		h := msg.Header
+		rule := RouteRule{
+			Priority: netlinkUint32(msg.Attrs, fraPriority),
+			TOS:      h[3],
+			Action:   routeNumberName(routeRuleActionNames, h[7]),
+			Goto:     netlinkUint32(msg.Attrs, fraGoto),
+		}
+		switch h[0] {
+		case syscall.AF_INET:
+			rule.Family = RouteFamilyIPv4
+		case syscall.AF_INET6:
+			rule.Family = RouteFamilyIPv6
+		default:
+			continue
+		}
+		flags := binary.NativeEndian.Uint32(h[8:12])
+		rule.Invert = flags&fibRuleInvert != 0
+		rule.Unresolved = flags&fibRuleUnresolved != 0
+
+		if addr, ok := netip.AddrFromSlice(msg.Attrs[fraSrc]); ok {
+			rule.From = netip.PrefixFrom(addr, int(h[2])).String()
+		}
+		if addr, ok := netip.AddrFromSlice(msg.Attrs[fraDst]); ok {
+			rule.To = netip.PrefixFrom(addr, int(h[1])).String()
+		}
+		rule.IIF = netlinkString(msg.Attrs[fraIIFName])
+		rule.OIF = netlinkString(msg.Attrs[fraOIFName])
+		if proto := msg.Attrs[fraIPProto]; len(proto) >= 1 {
+			rule.IPProto = routeNumberName(ipProtocolNames, proto[0])
+		}
+		if b := msg.Attrs[fraProtocol]; len(b) >= 1 && b[0] != 0 {
+			rule.Protocol = routeNumberName(routeProtocolNames, b[0])
+		}
+		rule.SPort = netlinkPortRange(msg.Attrs[fraSportRange])
+		rule.DPort = netlinkPortRange(msg.Attrs[fraDportRange])
+		if b := msg.Attrs[fraUIDRange]; len(b) >= 8 {
+			rule.UIDRange = fmt.Sprintf("%d-%d", binary.NativeEndian.Uint32(b[0:4]), binary.NativeEndian.Uint32(b[4:8]))
+		}
+
+		if mark, ok := msg.Attrs[fraFwMark]; ok && len(mark) >= 4 {
+			rule.FwMark = fmt.Sprintf("0x%x", binary.NativeEndian.Uint32(mark))
+			if mask, ok := msg.Attrs[fraFwMask]; ok && len(mask) >= 4 && binary.NativeEndian.Uint32(mask) != 0xffffffff {
+				rule.FwMark += fmt.Sprintf("/0x%x", binary.NativeEndian.Uint32(mask))
+			}
+		}
+
+		if rule.Action == "lookup" {
+			table := uint32(h[4])
+			if t := netlinkUint32(msg.Attrs, fraTable); t != 0 {
+				table = t
+			}
+			rule.Table = routeTableName(table)
+		}
+		// The kernel reports unset suppressors as -1
+		if b := msg.Attrs[fraSuppressPrefixlen]; len(b) >= 4 && int32(binary.NativeEndian.Uint32(b)) >= 0 {
+			rule.Suppress = fmt.Sprintf("prefixlength %d", int32(binary.NativeEndian.Uint32(b)))
+		}
+		if b := msg.Attrs[fraSuppressIfgroup]; len(b) >= 4 && int32(binary.NativeEndian.Uint32(b)) >= 0 {
+			rule.Suppress = fmt.Sprintf("ifgroup %d", int32(binary.NativeEndian.Uint32(b)))
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// netlinkString decodes a NUL-terminated string attribute.
+func netlinkString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// netlinkPortRange decodes a struct fib_rule_port_range (start and end, u16 each).
+func netlinkPortRange(b []byte) string {
+	if len(b) < 4 {
+		return ""
+	}
+	start, end := binary.NativeEndian.Uint16(b[0:2]), binary.NativeEndian.Uint16(b[2:4])
+	if start == end {
+		return strconv.Itoa(int(start))
+	}
+	return fmt.Sprintf("%d-%d", start, end)
+}
@@ -0,0 +1,88 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os/exec"
+	"strings"
+)
+
+// readRoutes reads the IPv4 routes listed by 'route print'. Windows has a single routing table.
+func readRoutes(table uint32) ([]RouteEntry, error) {
+	if table != RouteTableMain && table != RouteTableAll {
+		return nil, fmt.Errorf("routing table %s: Windows has only the main table", routeTableName(table))
+	}
+
+	var routes []RouteEntry
+
+	// Use 'route print' command
+	cmd := exec.Command("route", "print")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute 'route print' command: %v", err)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	inIPv4Section := false
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Detect the IPv4 Route Table section
+		if strings.Contains(line, "IPv4 Route Table") {
+			inIPv4Section = true
+			continue
+		}
+
+		if inIPv4Section {
+			// Skip until headers are found
+			if strings.HasPrefix(line, "===") || strings.HasPrefix(line, "Network Destination") {
+				continue
+			}
+
+			// An empty line signifies the end of the IPv4 section
+			if strings.TrimSpace(line) == "" {
+				break
+			}
+
+			// Split the line into fields based on whitespace
+			fields := strings.Fields(line)
+			if len(fields) < 5 {
+				continue
+			}
+
+			route := RouteEntry{
+				Destination: fields[0],
+				Genmask:     fields[1],
+				Gateway:     fields[2],
+				Iface:       fields[3],
+				Metric:      fields[4],
+				Flags:       "N/A", // Flags are not directly available
+				Ref:         "N/A", // Not available
+				Use:         "N/A", // Not available
+			}
+			routes = append(routes, route)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading 'route print' output: %v", err)
+	}
+
+	return routes, nil
+}
+
+// kernelRouteLookup is not available on Windows; lookups use the routing table and a connected
+// UDP socket instead.
+func kernelRouteLookup(addr netip.Addr) (*RouteEntry, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// readRouteRules is not available on Windows, which has no routing policy rules.
+func readRouteRules() ([]RouteRule, error) {
+	return nil, fmt.Errorf("routing policy rules are only available on Linux")
+}
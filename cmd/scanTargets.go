@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// maxScanTargets limits how many addresses a single target specification may expand to,
+// so that a mistyped IPv6 prefix does not try to enumerate billions of hosts.
+const maxScanTargets = 65536
+
+// Address families accepted by ParseScanTargets.
+const (
+	FamilyAny  = ""
+	FamilyIPv4 = "ip4"
+	FamilyIPv6 = "ip6"
+)
+
+// ScanTarget is a single address to probe together with the name it was requested as.
+type ScanTarget struct {
+	Name    string // Host name, address or CIDR entry the target came from
+	Address string // IP address, including the zone for scoped IPv6 addresses (fe80::1%eth0)
+}
+
+// IsIPv6 reports whether the target address is an IPv6 address.
+func (t ScanTarget) IsIPv6() bool {
+	addr, err := netip.ParseAddr(t.Address)
+	return err == nil && addr.Is6() && !addr.Is4In6()
+}
+
+// ParseScanTargets expands a comma-separated target specification into individual addresses.
+// Each entry may be a host name, an IPv4 or IPv6 address (optionally bracketed and with a zone,
+// e.g. [fe80::1%eth0]) or a CIDR block (e.g. 192.168.1.0/24, fd00::/120, fe80::/120%eth0).
+// family restricts name resolution and CIDR entries to FamilyIPv4 or FamilyIPv6; FamilyAny accepts both.
+// For IPv4 blocks larger than /31 the network and broadcast addresses are skipped.
+func ParseScanTargets(spec string, family string) ([]ScanTarget, error) {
+	var targets []ScanTarget
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		expanded, err := parseScanTarget(entry, family)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, expanded...)
+		if len(targets) > maxScanTargets {
+			return nil, fmt.Errorf("target specification %q expands to more than %d addresses", spec, maxScanTargets)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets specified")
+	}
+	return targets, nil
+}
+
+// parseScanTarget expands a single target entry.
+func parseScanTarget(entry string, family string) ([]ScanTarget, error) {
+	if strings.Contains(entry, "/") {
+		return expandCIDRTarget(entry, family)
+	}
+
+	literal := strings.TrimSuffix(strings.TrimPrefix(entry, "["), "]")
+	if addr, err := netip.ParseAddr(literal); err == nil {
+		addr = addr.Unmap()
+		if !familyMatches(addr, family) {
+			return nil, fmt.Errorf("address %s does not match the requested address family", entry)
+		}
+		return []ScanTarget{{Name: literal, Address: addr.String()}}, nil
+	}
+
+	network := "ip"
+	if family != FamilyAny {
+		network = family
+	}
+	ips, err := net.DefaultResolver.LookupIP(context.Background(), network, entry)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving %s: %w", entry, err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", entry)
+	}
+	return []ScanTarget{{Name: entry, Address: ips[0].String()}}, nil
+}
+
+// expandCIDRTarget expands a CIDR entry (optionally followed by %zone) into its addresses.
+func expandCIDRTarget(entry string, family string) ([]ScanTarget, error) {
+	cidr, zone, _ := strings.Cut(entry, "%")
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+	}
+	prefix = prefix.Masked()
+	if !familyMatches(prefix.Addr(), family) {
+		return nil, fmt.Errorf("CIDR %s does not match the requested address family", entry)
+	}
+
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("CIDR %s is too large to scan (at most %d addresses)", entry, maxScanTargets)
+	}
+
+	var targets []ScanTarget
+	skipEnds := prefix.Addr().Is4() && prefix.Bits() < 31
+	count := 1 << hostBits
+	addr := prefix.Addr()
+	for i := 0; i < count; i++ {
+		if !(skipEnds && (i == 0 || i == count-1)) {
+			target := addr
+			if zone != "" {
+				target = target.WithZone(zone)
+			}
+			targets = append(targets, ScanTarget{Name: target.String(), Address: target.String()})
+		}
+		addr = addr.Next()
+	}
+	return targets, nil
+}
+
+// familyMatches reports whether addr belongs to the requested address family.
+func familyMatches(addr netip.Addr, family string) bool {
+	switch family {
+	case FamilyIPv4:
+		return addr.Is4()
+	case FamilyIPv6:
+		return addr.Is6()
+	}
+	return true
+}
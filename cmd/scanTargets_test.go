@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseScanTargets(t *testing.T) {
+	tests := []struct {
+		spec    string
+		family  string
+		want    []string // Addresses; a single entry ending in "..." only checks the first and the count
+		count   int
+		wantErr string
+	}{
+		{spec: "192.168.1.10", want: []string{"192.168.1.10"}},
+		{spec: "[2001:db8::1]", want: []string{"2001:db8::1"}},
+		{spec: "::ffff:10.0.0.1", want: []string{"10.0.0.1"}},
+		{spec: "10.0.0.1, 10.0.0.2,,", want: []string{"10.0.0.1", "10.0.0.2"}},
+		{spec: "192.168.1.0/30", want: []string{"192.168.1.1", "192.168.1.2"}},
+		{spec: "192.168.1.0/31", want: []string{"192.168.1.0", "192.168.1.1"}},
+		{spec: "192.168.1.7/32", want: []string{"192.168.1.7"}},
+		{spec: "192.168.1.5/30", want: []string{"192.168.1.5", "192.168.1.6"}},
+		{spec: "fd00::/126", want: []string{"fd00::", "fd00::1", "fd00::2", "fd00::3"}},
+		{spec: "fe80::/127%eth0", want: []string{"fe80::%eth0", "fe80::1%eth0"}},
+		{spec: "10.0.0.0/16", want: []string{"10.0.0.1..."}, count: 65534},
+		{spec: "10.0.0.0/15", wantErr: "too large"},
+		{spec: "fd00::/64", wantErr: "too large"},
+		{spec: "10.0.0.0/16,10.1.0.0/24", wantErr: "more than"},
+		{spec: "10.0.0.1", family: FamilyIPv6, wantErr: "address family"},
+		{spec: "fd00::/120", family: FamilyIPv4, wantErr: "address family"},
+		{spec: "10.0.0.0/33", wantErr: "invalid CIDR"},
+		{spec: " , ", wantErr: "no targets"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			targets, err := ParseScanTargets(tt.spec, tt.family)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.want) == 1 && strings.HasSuffix(tt.want[0], "...") {
+				if len(targets) != tt.count || targets[0].Address != strings.TrimSuffix(tt.want[0], "...") {
+					t.Fatalf("got %d targets starting at %s, want %d starting at %s", len(targets), targets[0].Address, tt.count, tt.want[0])
+				}
+				return
+			}
+			var got []string
+			for _, target := range targets {
+				got = append(got, target.Address)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanTargetIsIPv6(t *testing.T) {
+	for address, want := range map[string]bool{
+		"10.0.0.1":        false,
+		"::ffff:10.0.0.1": false,
+		"2001:db8::1":     true,
+		"fe80::1%eth0":    true,
+		"not-an-address":  false,
+	} {
+		if got := (ScanTarget{Address: address}).IsIPv6(); got != want {
+			t.Errorf("IsIPv6(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
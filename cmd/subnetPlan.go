@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxSplitSubnets limits how many subnets a split may produce.
+const maxSplitSubnets = 65536
+
+// Relations between two overlapping networks reported by FindSubnetOverlaps.
+const (
+	SubnetRelationIdentical = "identical"
+	SubnetRelationContains  = "contains" // The first network contains the second
+)
+
+// SubnetAllocation is one subnet produced by a split.
+type SubnetAllocation struct {
+	Subnet      string   `json:"subnet"`
+	Requested   int      `json:"requested,omitempty"` // Hosts asked for, when splitting by host counts
+	UsableHosts *big.Int `json:"usableHosts"`
+	FirstUsable string   `json:"firstUsable"`
+	LastUsable  string   `json:"lastUsable"`
+}
+
+// SubnetPlan is the result of splitting a network.
+type SubnetPlan struct {
+	Network string             `json:"network"`
+	Subnets []SubnetAllocation `json:"subnets"`
+	Free    []string           `json:"free"` // Address space left over, as the fewest CIDR blocks
+}
+
+// NamedSubnet is a network with an optional name, as listed in an overlap file.
+type NamedSubnet struct {
+	Prefix netip.Prefix `json:"prefix"`
+	Name   string       `json:"name,omitempty"`
+	Line   int          `json:"line,omitempty"`
+}
+
+// SubnetOverlap is a pair of networks that share addresses.
+type SubnetOverlap struct {
+	First    NamedSubnet `json:"first"`
+	Second   NamedSubnet `json:"second"`
+	Relation string      `json:"relation"`
+}
+
+// SubnetMembership tells whether an address or network lies within a network.
+type SubnetMembership struct {
+	Item      string `json:"item"`
+	Contained bool   `json:"contained"`
+}
+
+// newAllocation describes a subnet of a plan.
+func newAllocation(prefix netip.Prefix, requested int) SubnetAllocation {
+	details := RunSubnetCalculator(prefix)
+	return SubnetAllocation{
+		Subnet:      details.CIDR,
+		Requested:   requested,
+		UsableHosts: details.UsableHosts,
+		FirstUsable: details.FirstUsable,
+		LastUsable:  details.LastUsable,
+	}
+}
+
+// SplitSubnet divides a network into subnets of the given prefix length.
+func SplitSubnet(network netip.Prefix, bits int) (SubnetPlan, error) {
+	network = network.Masked()
+	plan := SubnetPlan{Network: network.String(), Free: []string{}}
+	if bits < network.Bits() || bits > network.Addr().BitLen() {
+		return plan, fmt.Errorf("cannot split %s into /%d subnets", network, bits)
+	}
+	if bits-network.Bits() > 16 || 1<<(bits-network.Bits()) > maxSplitSubnets {
+		return plan, fmt.Errorf("splitting %s into /%d subnets gives more than %d subnets", network, bits, maxSplitSubnets)
+	}
+
+	size := new(big.Int).Lsh(big.NewInt(1), uint(network.Addr().BitLen()-bits))
+	start := addrToInt(network.Addr())
+	for i := 0; i < 1<<(bits-network.Bits()); i++ {
+		prefix := netip.PrefixFrom(intToAddr(start, network.Addr().Is4()), bits)
+		plan.Subnets = append(plan.Subnets, newAllocation(prefix, 0))
+		start.Add(start, size)
+	}
+	return plan, nil
+}
+
+// SplitSubnetByHosts allocates a subnet for each host count (VLSM): the largest requests are
+// placed first, each in the smallest aligned block with enough usable hosts, so that no
+// space is wasted on alignment. The subnets are returned in the order they were allocated.
+func SplitSubnetByHosts(network netip.Prefix, hosts []int) (SubnetPlan, error) {
+	network = network.Masked()
+	plan := SubnetPlan{Network: network.String(), Free: []string{}}
+	width := network.Addr().BitLen()
+	is4 := network.Addr().Is4()
+
+	requests := append([]int(nil), hosts...)
+	sort.Sort(sort.Reverse(sort.IntSlice(requests)))
+
+	next := addrToInt(network.Addr())
+	end := addrToInt(lastAddress(network))
+	for _, count := range requests {
+		if count < 1 {
+			return plan, fmt.Errorf("invalid host count %d", count)
+		}
+		bits := prefixForHosts(count, is4)
+		if bits < network.Bits() {
+			return plan, fmt.Errorf("%d hosts do not fit in %s", count, network)
+		}
+		// Requests are placed in decreasing size, so next is always aligned for this one
+		size := new(big.Int).Lsh(big.NewInt(1), uint(width-bits))
+		last := new(big.Int).Add(next, size)
+		last.Sub(last, big.NewInt(1))
+		if last.Cmp(end) > 0 {
+			return plan, fmt.Errorf("%s is too small: no room left for %d hosts", network, count)
+		}
+		plan.Subnets = append(plan.Subnets, newAllocation(netip.PrefixFrom(intToAddr(next, is4), bits), count))
+		next.Add(last, big.NewInt(1))
+	}
+
+	if next.Cmp(end) <= 0 {
+		for _, prefix := range rangeToPrefixes(next, end, is4) {
+			plan.Free = append(plan.Free, prefix.String())
+		}
+	}
+	return plan, nil
+}
+
+// prefixForHosts returns the longest prefix with at least count usable hosts, following the
+// rules of RunSubnetCalculator for /31, /32, /127 and /128.
+func prefixForHosts(count int, is4 bool) int {
+	width, reserved := 128, 1
+	if is4 {
+		width, reserved = 32, 2
+	}
+	for hostBits := 0; hostBits <= width; hostBits++ {
+		total := new(big.Int).Lsh(big.NewInt(1), uint(hostBits))
+		usable := new(big.Int).Set(total)
+		if hostBits > 1 {
+			usable.Sub(usable, big.NewInt(int64(reserved)))
+		}
+		if usable.Cmp(big.NewInt(int64(count))) >= 0 {
+			return width - hostBits
+		}
+	}
+	return -1
+}
+
+// SummarizeSubnets merges networks into the fewest CIDR blocks covering exactly the same
+// addresses. IPv4 blocks are listed before IPv6 blocks.
+func SummarizeSubnets(networks []netip.Prefix) []netip.Prefix {
+	type span struct {
+		start, end *big.Int
+		is4        bool
+	}
+	var spans []span
+	for _, network := range networks {
+		network = network.Masked()
+		spans = append(spans, span{addrToInt(network.Addr()), addrToInt(lastAddress(network)), network.Addr().Is4()})
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].is4 != spans[j].is4 {
+			return spans[i].is4
+		}
+		return spans[i].start.Cmp(spans[j].start) < 0
+	})
+
+	var summary []netip.Prefix
+	for i := 0; i < len(spans); {
+		current := spans[i]
+		end := new(big.Int).Set(current.end)
+		j := i + 1
+		// Merge spans that overlap or directly follow each other
+		for ; j < len(spans) && spans[j].is4 == current.is4; j++ {
+			adjacent := new(big.Int).Add(end, big.NewInt(1))
+			if spans[j].start.Cmp(adjacent) > 0 {
+				break
+			}
+			if spans[j].end.Cmp(end) > 0 {
+				end.Set(spans[j].end)
+			}
+		}
+		summary = append(summary, rangeToPrefixes(current.start, end, current.is4)...)
+		i = j
+	}
+	return summary
+}
+
+// FindSubnetOverlaps returns every pair of networks that share addresses. Two CIDR blocks that
+// overlap are either identical or one contains the other; the containing network comes first.
+func FindSubnetOverlaps(networks []NamedSubnet) []SubnetOverlap {
+	sorted := append([]NamedSubnet(nil), networks...)
+	for i := range sorted {
+		sorted[i].Prefix = sorted[i].Prefix.Masked()
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if c := sorted[i].Prefix.Addr().Compare(sorted[j].Prefix.Addr()); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Prefix.Bits() < sorted[j].Prefix.Bits()
+	})
+
+	overlaps := []SubnetOverlap{}
+	for i, outer := range sorted {
+		for _, inner := range sorted[i+1:] {
+			// Later networks start at or after outer; once one starts beyond it, none overlap
+			if !outer.Prefix.Contains(inner.Prefix.Addr()) {
+				break
+			}
+			relation := SubnetRelationContains
+			if outer.Prefix == inner.Prefix {
+				relation = SubnetRelationIdentical
+			}
+			overlaps = append(overlaps, SubnetOverlap{First: outer, Second: inner, Relation: relation})
+		}
+	}
+	return overlaps
+}
+
+// SubnetContains checks whether each item, an address or a network, lies within network.
+func SubnetContains(network netip.Prefix, items []string) ([]SubnetMembership, error) {
+	network = network.Masked()
+	var results []SubnetMembership
+	for _, item := range items {
+		prefix, err := ParseSubnet(item)
+		if err != nil {
+			return nil, err
+		}
+		contained := prefix.Addr().BitLen() == network.Addr().BitLen() &&
+			prefix.Bits() >= network.Bits() && network.Contains(prefix.Masked().Addr())
+		results = append(results, SubnetMembership{Item: item, Contained: contained})
+	}
+	return results, nil
+}
+
+// rangeToPrefixes returns the fewest CIDR blocks covering the addresses from start to end.
+func rangeToPrefixes(start, end *big.Int, is4 bool) []netip.Prefix {
+	width := 128
+	if is4 {
+		width = 32
+	}
+	var prefixes []netip.Prefix
+	current := new(big.Int).Set(start)
+	one := big.NewInt(1)
+	for current.Cmp(end) <= 0 {
+		// The block may be as large as the alignment of current allows and must end by end
+		hostBits := width
+		if current.Sign() != 0 {
+			hostBits = int(current.TrailingZeroBits())
+		}
+		remaining := new(big.Int).Sub(end, current)
+		remaining.Add(remaining, one)
+		for hostBits > 0 && new(big.Int).Lsh(one, uint(hostBits)).Cmp(remaining) > 0 {
+			hostBits--
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(intToAddr(current, is4), width-hostBits))
+		current.Add(current, new(big.Int).Lsh(one, uint(hostBits)))
+	}
+	return prefixes
+}
+
+// addrToInt returns an address as an integer.
+func addrToInt(addr netip.Addr) *big.Int {
+	return new(big.Int).SetBytes(addr.AsSlice())
+}
+
+// intToAddr returns the IPv4 or IPv6 address with the given integer value.
+func intToAddr(n *big.Int, is4 bool) netip.Addr {
+	size := 16
+	if is4 {
+		size = 4
+	}
+	b := make([]byte, size)
+	n.FillBytes(b)
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// ParseHostCounts parses a comma-separated list of host counts, e.g. "500,200,50".
+func ParseHostCounts(list string) ([]int, error) {
+	var counts []int
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid host count %q", field)
+		}
+		counts = append(counts, n)
+	}
+	if len(counts) == 0 {
+		return nil, fmt.Errorf("no host counts given")
+	}
+	return counts, nil
+}
+
+// ReadSubnetList reads networks, one per line, each optionally followed by a name. Blank
+// lines and text after # are ignored; commas may also separate networks on a line.
+func ReadSubnetList(r io.Reader) ([]NamedSubnet, error) {
+	var networks []NamedSubnet
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if strings.Contains(text, ",") {
+			for _, field := range strings.Split(text, ",") {
+				if field = strings.TrimSpace(field); field == "" {
+					continue
+				}
+				prefix, err := ParseSubnet(field)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+				networks = append(networks, NamedSubnet{Prefix: prefix, Line: line})
+			}
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) > 1 && strings.Count(fields[1], ".") == 3 {
+			// An address followed by a dotted netmask
+			if _, err := netmaskBits(fields[1]); err == nil {
+				fields = append([]string{fields[0] + "/" + fields[1]}, fields[2:]...)
+			}
+		}
+		prefix, err := ParseSubnet(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		networks = append(networks, NamedSubnet{Prefix: prefix, Name: strings.Join(fields[1:], " "), Line: line})
+	}
+	return networks, scanner.Err()
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSubnet(t *testing.T) {
+	plan, err := SplitSubnet(netip.MustParsePrefix("10.0.1.7/22"), 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subnets []string
+	for _, subnet := range plan.Subnets {
+		subnets = append(subnets, subnet.Subnet)
+	}
+	want := []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"}
+	if plan.Network != "10.0.0.0/22" || !reflect.DeepEqual(subnets, want) || len(plan.Free) != 0 {
+		t.Errorf("plan = %+v, want subnets %v", plan, want)
+	}
+	if first := plan.Subnets[0]; first.UsableHosts.Int64() != 254 || first.FirstUsable != "10.0.0.1" || first.LastUsable != "10.0.0.254" {
+		t.Errorf("first subnet = %+v", first)
+	}
+
+	plan, err = SplitSubnet(netip.MustParsePrefix("2001:db8::/62"), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Subnets) != 4 || plan.Subnets[3].Subnet != "2001:db8:0:3::/64" {
+		t.Errorf("IPv6 plan = %+v", plan)
+	}
+
+	for _, tt := range []struct {
+		network string
+		bits    int
+	}{
+		{"10.0.0.0/16", 8},
+		{"10.0.0.0/24", 33},
+		{"10.0.0.0/8", 30},
+		{"2001:db8::/32", 64},
+	} {
+		if _, err := SplitSubnet(netip.MustParsePrefix(tt.network), tt.bits); err == nil {
+			t.Errorf("SplitSubnet(%s, /%d) succeeded", tt.network, tt.bits)
+		}
+	}
+}
+
+func TestSplitSubnetByHosts(t *testing.T) {
+	plan, err := SplitSubnetByHosts(netip.MustParsePrefix("192.168.0.0/24"), []int{20, 100, 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, subnet := range plan.Subnets {
+		got = append(got, subnet.Subnet)
+	}
+	// Largest request first, each in the smallest block that holds it
+	want := []string{"192.168.0.0/25", "192.168.0.128/26", "192.168.0.192/27"}
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(plan.Free, []string{"192.168.0.224/27"}) {
+		t.Errorf("subnets %v free %v, want %v and 192.168.0.224/27", got, plan.Free, want)
+	}
+	if plan.Subnets[0].Requested != 100 || plan.Subnets[0].UsableHosts.Int64() != 126 {
+		t.Errorf("first allocation = %+v", plan.Subnets[0])
+	}
+
+	tests := []struct {
+		network string
+		hosts   []int
+		err     string
+	}{
+		{"192.168.0.0/24", []int{200, 100}, "no room left for 100 hosts"},
+		{"192.168.0.0/24", []int{300}, "300 hosts do not fit"},
+		{"192.168.0.0/24", []int{0}, "invalid host count 0"},
+	}
+	for _, tt := range tests {
+		_, err := SplitSubnetByHosts(netip.MustParsePrefix(tt.network), tt.hosts)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("SplitSubnetByHosts(%s, %v) error = %v, want %q", tt.network, tt.hosts, err, tt.err)
+		}
+	}
+
+	// /31 and /32 need no network or broadcast address; IPv6 only sets aside the anycast address
+	sizes := []struct {
+		count int
+		is4   bool
+		bits  int
+	}{
+		{1, true, 32}, {2, true, 31}, {3, true, 29}, {6, true, 29}, {7, true, 28},
+		{1, false, 128}, {2, false, 127}, {3, false, 126}, {4, false, 125},
+	}
+	for _, tt := range sizes {
+		if got := prefixForHosts(tt.count, tt.is4); got != tt.bits {
+			t.Errorf("prefixForHosts(%d, %v) = /%d, want /%d", tt.count, tt.is4, got, tt.bits)
+		}
+	}
+}
+
+func TestSummarizeSubnets(t *testing.T) {
+	tests := []struct {
+		networks []string
+		want     []string
+	}{
+		{
+			networks: []string{"2001:db8:8000::/33", "10.0.1.0/24", "10.0.0.0/24", "10.0.3.128/25", "10.0.2.0/23", "192.168.0.0/24", "2001:db8::/33", "10.0.5.0/24"},
+			want:     []string{"10.0.0.0/22", "10.0.5.0/24", "192.168.0.0/24", "2001:db8::/32"},
+		},
+		{
+			// Contiguous, but not aligned on a single block
+			networks: []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"},
+			want:     []string{"10.0.1.0/24", "10.0.2.0/23"},
+		},
+		{
+			networks: []string{"10.0.0.0/8", "10.20.30.0/24", "10.0.0.7/32"},
+			want:     []string{"10.0.0.0/8"},
+		},
+	}
+	for _, tt := range tests {
+		var networks []netip.Prefix
+		for _, network := range tt.networks {
+			networks = append(networks, netip.MustParsePrefix(network))
+		}
+		var got []string
+		for _, prefix := range SummarizeSubnets(networks) {
+			got = append(got, prefix.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SummarizeSubnets(%v) = %v, want %v", tt.networks, got, tt.want)
+		}
+	}
+}
+
+func TestFindSubnetOverlaps(t *testing.T) {
+	networks := []NamedSubnet{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/16"), Name: "office"},
+		{Prefix: netip.MustParsePrefix("10.0.1.0/24"), Name: "lab"},
+		{Prefix: netip.MustParsePrefix("192.168.0.0/24")},
+		{Prefix: netip.MustParsePrefix("10.0.1.5/24"), Name: "copy"},
+		{Prefix: netip.MustParsePrefix("172.16.0.0/12")},
+		{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Name: "next door"},
+	}
+	var got []string
+	for _, overlap := range FindSubnetOverlaps(networks) {
+		got = append(got, overlap.First.Name+" "+overlap.Relation+" "+overlap.Second.Name+" "+overlap.Second.Prefix.String())
+	}
+	want := []string{
+		"office contains lab 10.0.1.0/24",
+		"office contains copy 10.0.1.0/24",
+		"lab identical copy 10.0.1.0/24",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("overlaps = %q, want %q", got, want)
+	}
+	if overlaps := FindSubnetOverlaps(networks[4:]); len(overlaps) != 0 {
+		t.Errorf("disjoint networks overlap: %+v", overlaps)
+	}
+}
+
+func TestSubnetContains(t *testing.T) {
+	network := netip.MustParsePrefix("10.0.5.5/16")
+	items := []string{"10.0.3.4", "10.0.8.0/24", "10.0.0.0/16", "10.1.0.0", "10.0.0.0/8", "::ffff:10.0.0.1", "2001:db8::1"}
+	results, err := SubnetContains(network, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bool{true, true, true, false, false, false, false}
+	for i, result := range results {
+		if result.Item != items[i] || result.Contained != want[i] {
+			t.Errorf("%s contained = %v, want %v", result.Item, result.Contained, want[i])
+		}
+	}
+	if _, err := SubnetContains(network, []string{"10.0.0.1", "not-an-address"}); err == nil {
+		t.Error("an invalid item was accepted")
+	}
+}
+
+func TestParseHostCounts(t *testing.T) {
+	counts, err := ParseHostCounts("500, 200,,50")
+	if err != nil || !reflect.DeepEqual(counts, []int{500, 200, 50}) {
+		t.Errorf("ParseHostCounts = %v, %v", counts, err)
+	}
+	for _, list := range []string{"0", "12,x", " , "} {
+		if _, err := ParseHostCounts(list); err == nil {
+			t.Errorf("ParseHostCounts(%q) succeeded", list)
+		}
+	}
+}
+
+func TestReadSubnetList(t *testing.T) {
+	input := `# office networks
+10.0.0.0/16 Head office  # main site
+10.1.0.0 255.255.0.0 Branch
+192.168.0.0/24, 192.168.1.0/24
+
+2001:db8::/32
+`
+	networks, err := ReadSubnetList(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []NamedSubnet{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/16"), Name: "Head office", Line: 2},
+		{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Name: "Branch", Line: 3},
+		{Prefix: netip.MustParsePrefix("192.168.0.0/24"), Line: 4},
+		{Prefix: netip.MustParsePrefix("192.168.1.0/24"), Line: 4},
+		{Prefix: netip.MustParsePrefix("2001:db8::/32"), Line: 6},
+	}
+	if !reflect.DeepEqual(networks, want) {
+		t.Errorf("networks = %+v, want %+v", networks, want)
+	}
+
+	_, err = ReadSubnetList(strings.NewReader("10.0.0.0/8\n10.0.0.0/40\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("error = %v, want it on line 2", err)
+	}
+}
@@ -2,7 +2,11 @@ package cmd
 
 import (
 	"fmt"
-	"net"
+	"math/big"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/mwiater/ghost/utils"
@@ -10,48 +14,387 @@ import (
 )
 
 // SubnetCalcCmd defines the Cobra command for calculating network details from a given IP address and subnet (CIDR).
-// It calculates and displays the network address, broadcast address, and IP range for the specified subnet.
+// It calculates and displays the network address, masks, host counts, usable range and address type of the subnet.
 var SubnetCalcCmd = &cobra.Command{
-	Use:   "subnetcalc",
+	Use:   "subnetcalc [address[/prefix] [netmask]]",
 	Short: "Calculates network details for a given IP address and subnet (CIDR)",
+	Long: `Calculates the details of an IPv4 or IPv6 subnet: network and broadcast addresses, netmask and
+wildcard mask, total and usable host counts, the first and last usable host, and the address class
+and type (private, reserved, documentation, ...). The subnet may be given as CIDR (10.0.0.5/24,
+2001:db8::/48), as an address followed by a dotted netmask (10.0.0.5 255.255.255.0), or with
+--cidr. A bare address is treated as a single host (/32 or /128).
+
+IPv4 /31 networks are point-to-point links (RFC 3021) where both addresses are usable hosts and
+there is no broadcast address. In IPv6 the first address of a subnet is the Subnet-Router anycast
+address (RFC 4291) and is not counted as usable, except in /127 (RFC 6164) and /128.`,
+	Args: cobra.MaximumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
-		cidr, _ := cmd.Flags().GetString("cidr")
+		input, _ := cmd.Flags().GetString("cidr")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		if len(args) > 0 {
+			input = strings.Join(args, " ")
+		}
 
-		_, ipNet, err := net.ParseCIDR(cidr)
+		prefix, err := ParseSubnet(input)
 		if err != nil {
 			fmt.Println("Error:", err)
-			return
+			os.Exit(1)
 		}
 
-		subnetDetails := RunSubnetCalculator(ipNet)
+		subnetDetails := RunSubnetCalculator(prefix)
+		if jsonOutput {
+			utils.PrintJSON(subnetDetails)
+			return
+		}
 		PrintSubnetDetails(subnetDetails)
 	},
 }
 
-// init registers the SubnetCalcCmd with the root command and defines the --cidr flag.
+// SubnetSplitCmd divides a network into equal subnets or into subnets sized for host counts.
+var SubnetSplitCmd = &cobra.Command{
+	Use:   "split <network>",
+	Short: "Splits a network into subnets of a prefix length or sized for host counts (VLSM)",
+	Long: `Splits a network into subnets, either all of the prefix length given with --into, or one per host
+count given with --hosts (VLSM). Host counts are allocated largest first, each in the smallest
+subnet with enough usable hosts, and the address space left over is listed. The network may be
+given as an argument or on standard input.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		into, _ := cmd.Flags().GetString("into")
+		hosts, _ := cmd.Flags().GetString("hosts")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+
+		inputs, err := subnetInputs(args)
+		if err == nil && len(inputs) != 1 {
+			err = fmt.Errorf("split needs exactly one network")
+		}
+		if err == nil && (into == "") == (hosts == "") {
+			err = fmt.Errorf("use either --into or --hosts")
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		var plan SubnetPlan
+		if into != "" {
+			var bits int
+			bits, err = strconv.Atoi(strings.TrimPrefix(into, "/"))
+			if err != nil {
+				fmt.Println("Error: invalid prefix length", into)
+				os.Exit(1)
+			}
+			plan, err = SplitSubnet(inputs[0].Prefix, bits)
+		} else {
+			var counts []int
+			if counts, err = ParseHostCounts(hosts); err == nil {
+				plan, err = SplitSubnetByHosts(inputs[0].Prefix, counts)
+			}
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if jsonOutput {
+			utils.PrintJSON(plan)
+			return
+		}
+		PrintSubnetPlan(plan)
+	},
+}
+
+// SubnetSummarizeCmd aggregates networks into the fewest CIDR blocks.
+var SubnetSummarizeCmd = &cobra.Command{
+	Use:   "summarize [network...]",
+	Short: "Aggregates networks into the fewest CIDR blocks covering the same addresses",
+	Long: `Merges overlapping and adjacent networks into the fewest CIDR blocks that cover exactly the same
+addresses, e.g. for route summarization or firewall rules. Networks are read from the arguments
+or, without arguments, from standard input (one per line or comma-separated).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		inputs, err := subnetInputs(args)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		var networks []netip.Prefix
+		for _, input := range inputs {
+			networks = append(networks, input.Prefix)
+		}
+		summary := SummarizeSubnets(networks)
+		if jsonOutput {
+			utils.PrintJSON(summary)
+			return
+		}
+
+		t := utils.Table("DarkSimple", "Summary")
+		t.AppendHeader(table.Row{"Network", "Addresses"})
+		for _, prefix := range summary {
+			t.AppendRow(table.Row{prefix, formatAddressCount(RunSubnetCalculator(prefix).TotalAddresses)})
+		}
+		fmt.Println()
+		t.Render()
+		fmt.Printf("%d networks summarized into %d.\n", len(networks), len(summary))
+	},
+}
+
+// SubnetOverlapCmd reports networks in a list that overlap.
+var SubnetOverlapCmd = &cobra.Command{
+	Use:   "overlap [file | network...]",
+	Short: "Finds overlapping networks in a list, e.g. of VPC ranges",
+	Long: `Reads a list of networks, one per line and each optionally followed by a name (e.g.
+"10.0.0.0/16 vpc-prod"), and reports every pair that shares addresses: identical networks and
+networks containing others. The list is read from a file, from the networks given as arguments,
+or from standard input when no argument (or -) is given. Exits with status 2 when overlaps are found.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		inputs, err := subnetInputs(args)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		overlaps := FindSubnetOverlaps(inputs)
+		if jsonOutput {
+			utils.PrintJSON(overlaps)
+		} else {
+			PrintSubnetOverlaps(overlaps, len(inputs))
+		}
+		if len(overlaps) > 0 {
+			os.Exit(utils.FindingsExitCode)
+		}
+	},
+}
+
+// SubnetContainsCmd checks whether addresses or networks lie within a network.
+var SubnetContainsCmd = &cobra.Command{
+	Use:   "contains <network> [address...]",
+	Short: "Checks whether addresses or networks lie within a network",
+	Long: `Checks whether each address or network lies within the given network. Items are read from the
+arguments or, when only the network is given, from standard input. Exits with status 2 when any
+item lies outside the network.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		network, err := ParseSubnet(args[0])
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		items := args[1:]
+		if len(items) == 0 {
+			inputs, err := ReadSubnetList(os.Stdin)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			for _, input := range inputs {
+				if input.Prefix.IsSingleIP() {
+					items = append(items, input.Prefix.Addr().String())
+				} else {
+					items = append(items, input.Prefix.String())
+				}
+			}
+		}
+
+		results, err := SubnetContains(network, items)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if jsonOutput {
+			utils.PrintJSON(results)
+		} else {
+			PrintSubnetMembership(network, results)
+		}
+		for _, result := range results {
+			if !result.Contained {
+				os.Exit(utils.FindingsExitCode)
+			}
+		}
+	},
+}
+
+// init registers the subnet commands with the root command and defines their flags.
 func init() {
 	RootCmd.AddCommand(SubnetCalcCmd)
-	SubnetCalcCmd.Flags().StringP("cidr", "c", "192.168.1.0/24", "CIDR notation for subnet (e.g., 192.168.1.0/24)")
+	SubnetCalcCmd.AddCommand(SubnetSplitCmd)
+	SubnetCalcCmd.AddCommand(SubnetSummarizeCmd)
+	SubnetCalcCmd.AddCommand(SubnetOverlapCmd)
+	SubnetCalcCmd.AddCommand(SubnetContainsCmd)
+
+	SubnetCalcCmd.Flags().StringP("cidr", "c", "192.168.1.0/24", "CIDR notation for subnet (e.g., 192.168.1.0/24 or 2001:db8::/64), used when no address is given as an argument")
+	SubnetCalcCmd.PersistentFlags().Bool("json", false, "Print the results as JSON")
+	SubnetSplitCmd.Flags().String("into", "", "Prefix length of the subnets, e.g. /24")
+	SubnetSplitCmd.Flags().String("hosts", "", "Comma-separated host counts to allocate subnets for (VLSM), e.g. 500,120,20")
+}
+
+// subnetInputs returns the networks given as arguments, read from the file named by a single
+// argument, or read from standard input when there are no arguments or
+// the argument is "-".
+func subnetInputs(args []string) ([]NamedSubnet, error) {
+	var networks []NamedSubnet
+	switch {
+	case len(args) == 0 || (len(args) == 1 && args[0] == "-"):
+		var err error
+		if networks, err = ReadSubnetList(os.Stdin); err != nil {
+			return nil, err
+		}
+	case len(args) == 1 && isRegularFile(args[0]):
+		file, err := os.Open(args[0])
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		if networks, err = ReadSubnetList(file); err != nil {
+			return nil, fmt.Errorf("%s: %w", args[0], err)
+		}
+	default:
+		var err error
+		if networks, err = ReadSubnetList(strings.NewReader(strings.Join(args, "\n"))); err != nil {
+			return nil, err
+		}
+	}
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("no networks given")
+	}
+	return networks, nil
 }
 
 // SubnetDetails holds details about the calculated subnet information.
 type SubnetDetails struct {
-	NetworkAddress   string
-	BroadcastAddress string
-	IPRange          string
+	Address          string   `json:"address"` // Address as given, which may be a host inside the network
+	CIDR             string   `json:"cidr"`
+	Version          int      `json:"version"`
+	PrefixLength     int      `json:"prefixLength"`
+	NetworkAddress   string   `json:"networkAddress"`
+	BroadcastAddress string   `json:"broadcastAddress"`
+	Netmask          string   `json:"netmask"`
+	WildcardMask     string   `json:"wildcardMask"`
+	IPRange          string   `json:"ipRange"` // Every address in the network, including network and broadcast
+	TotalAddresses   *big.Int `json:"totalAddresses"`
+	UsableHosts      *big.Int `json:"usableHosts"`
+	FirstUsable      string   `json:"firstUsable"`
+	LastUsable       string   `json:"lastUsable"`
+	Class            string   `json:"class,omitempty"` // IPv4 address class (A to E)
+	Type             string   `json:"type"`            // e.g. "private (RFC 1918)", "loopback", "public"
+	Private          bool     `json:"private"`
+	Reserved         bool     `json:"reserved"`
 }
 
-// RunSubnetCalculator calculates the network address, broadcast address, and IP range for the specified subnet.
-func RunSubnetCalculator(ipNet *net.IPNet) *SubnetDetails {
-	networkAddress := ipNet.IP.String()
-	broadcastAddress := calculateBroadcastAddress(ipNet)
-	ipRange := fmt.Sprintf("%s - %s", networkAddress, calculateLastIP(ipNet))
+// ParseSubnet parses a subnet given as CIDR (10.0.0.5/24, 2001:db8::1/64), as an IPv4 address
+// with a dotted netmask separated by a space or a slash (10.0.0.5 255.255.255.0), or as a bare
+// address, which is a single host. The returned prefix keeps the address as given; use
+// Masked() for the network.
+func ParseSubnet(input string) (netip.Prefix, error) {
+	input = strings.TrimSpace(input)
+	address, mask, hasMask := strings.Cut(input, "/")
+	if !hasMask {
+		fields := strings.Fields(input)
+		switch len(fields) {
+		case 1:
+			address = fields[0]
+		case 2:
+			address, mask, hasMask = fields[0], fields[1], true
+		default:
+			return netip.Prefix{}, fmt.Errorf("invalid subnet %q", input)
+		}
+	}
 
-	return &SubnetDetails{
-		NetworkAddress:   networkAddress,
-		BroadcastAddress: broadcastAddress,
-		IPRange:          ipRange,
+	addr, err := netip.ParseAddr(strings.TrimSpace(address))
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid subnet %q: %w", input, err)
 	}
+	if addr.Zone() != "" {
+		return netip.Prefix{}, fmt.Errorf("invalid subnet %q: zoned addresses are not supported", input)
+	}
+	bits := addr.BitLen()
+	if hasMask {
+		mask = strings.TrimSpace(mask)
+		if strings.Contains(mask, ".") {
+			if bits, err = netmaskBits(mask); err != nil {
+				return netip.Prefix{}, err
+			}
+			if !addr.Is4() {
+				return netip.Prefix{}, fmt.Errorf("a dotted netmask needs an IPv4 address")
+			}
+		} else if bits, err = strconv.Atoi(mask); err != nil || bits < 0 || bits > addr.BitLen() {
+			return netip.Prefix{}, fmt.Errorf("invalid prefix length %q", mask)
+		}
+	}
+	return netip.PrefixFrom(addr, bits), nil
+}
+
+// netmaskBits returns the prefix length of a dotted IPv4 netmask, which must be contiguous.
+func netmaskBits(mask string) (int, error) {
+	addr, err := netip.ParseAddr(mask)
+	if err != nil || !addr.Is4() {
+		return 0, fmt.Errorf("invalid netmask %q", mask)
+	}
+	b := addr.As4()
+	value := uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+	ones := 0
+	for value&(1<<31) != 0 {
+		ones++
+		value <<= 1
+	}
+	if value != 0 {
+		return 0, fmt.Errorf("netmask %s is not contiguous", mask)
+	}
+	return ones, nil
+}
+
+// RunSubnetCalculator calculates the network address, masks, host counts, usable range and
+// address type of the subnet. IPv6 networks have no broadcast address, so it is reported as
+// not applicable, as it is for IPv4 /31 and /32 networks.
+func RunSubnetCalculator(prefix netip.Prefix) *SubnetDetails {
+	network := prefix.Masked()
+	first := network.Addr()
+	last := lastAddress(network)
+	bits := prefix.Bits()
+	hostBits := first.BitLen() - bits
+	total := new(big.Int).Lsh(big.NewInt(1), uint(hostBits))
+
+	details := &SubnetDetails{
+		Address:          prefix.Addr().String(),
+		CIDR:             network.String(),
+		Version:          4,
+		PrefixLength:     bits,
+		NetworkAddress:   first.String(),
+		BroadcastAddress: last.String(),
+		Netmask:          prefixMask(bits, first.BitLen()).String(),
+		WildcardMask:     invertAddr(prefixMask(bits, first.BitLen())).String(),
+		IPRange:          fmt.Sprintf("%s - %s", first, last),
+		TotalAddresses:   total,
+		UsableHosts:      new(big.Int).Set(total),
+		FirstUsable:      first.String(),
+		LastUsable:       last.String(),
+	}
+
+	if first.Is4() {
+		switch hostBits {
+		case 0:
+			details.BroadcastAddress = "N/A (single host)"
+		case 1:
+			details.BroadcastAddress = "N/A (point-to-point link, RFC 3021)"
+		default:
+			// The network and broadcast addresses are not usable hosts
+			details.UsableHosts.Sub(total, big.NewInt(2))
+			details.FirstUsable = first.Next().String()
+			details.LastUsable = last.Prev().String()
+		}
+		details.Class = ipv4Class(first)
+	} else {
+		details.Version = 6
+		details.BroadcastAddress = "N/A (IPv6)"
+		if hostBits > 1 {
+			// The Subnet-Router anycast address is not assigned to hosts
+			details.UsableHosts.Sub(total, big.NewInt(1))
+			details.FirstUsable = first.Next().String()
+		}
+	}
+
+	details.Type, details.Private, details.Reserved = classifySubnet(network)
+	return details
 }
 
 // PrintSubnetDetails displays the subnet details in a formatted table.
@@ -62,10 +405,22 @@ func PrintSubnetDetails(details *SubnetDetails) {
 
 	// Prepare the data for the table
 	data := [][]string{
+		{"Address", details.Address},
+		{"Network", details.CIDR},
 		{"Network Address", details.NetworkAddress},
 		{"Broadcast Address", details.BroadcastAddress},
+		{"Netmask", details.Netmask},
+		{"Wildcard Mask", details.WildcardMask},
+		{"Prefix Length", fmt.Sprintf("/%d", details.PrefixLength)},
 		{"IP Range", details.IPRange},
+		{"Usable Range", fmt.Sprintf("%s - %s", details.FirstUsable, details.LastUsable)},
+		{"Total Addresses", formatAddressCount(details.TotalAddresses)},
+		{"Usable Hosts", formatAddressCount(details.UsableHosts)},
+	}
+	if details.Class != "" {
+		data = append(data, []string{"Class", details.Class})
 	}
+	data = append(data, []string{"Type", details.Type})
 
 	// Add the data to the table
 	for _, v := range data {
@@ -78,26 +433,197 @@ func PrintSubnetDetails(details *SubnetDetails) {
 	fmt.Println("Subnet calculation complete.")
 }
 
-// calculateBroadcastAddress calculates the broadcast address for the given subnet.
-// It performs bitwise operations using the IP address and subnet mask to derive the broadcast address.
-func calculateBroadcastAddress(ipNet *net.IPNet) string {
-	ip := ipNet.IP.To4()
-	mask := ipNet.Mask
-	broadcast := make(net.IP, len(ip))
-	for i := 0; i < len(ip); i++ {
-		broadcast[i] = ip[i] | ^mask[i]
+// formatAddressCount formats a number of addresses, adding the power of two for large counts
+// that are one.
+func formatAddressCount(n *big.Int) string {
+	if n.BitLen() > 32 && new(big.Int).Lsh(big.NewInt(1), uint(n.BitLen()-1)).Cmp(n) == 0 {
+		return fmt.Sprintf("%s (2^%d)", n, n.BitLen()-1)
+	}
+	return n.String()
+}
+
+// lastAddress returns the highest address of an IPv4 or IPv6 network by setting every host bit.
+func lastAddress(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Masked().Addr()
+	b := addr.AsSlice()
+	wildcard := invertAddr(prefixMask(prefix.Bits(), addr.BitLen())).AsSlice()
+	for i := range b {
+		b[i] |= wildcard[i]
+	}
+	last, _ := netip.AddrFromSlice(b)
+	return last
+}
+
+// prefixMask returns the mask of a prefix length as an address of the given size in bits.
+func prefixMask(bits, size int) netip.Addr {
+	b := make([]byte, size/8)
+	for i := range b {
+		switch {
+		case bits >= 8:
+			b[i] = 0xff
+			bits -= 8
+		case bits > 0:
+			b[i] = ^byte(0xff >> bits)
+			bits = 0
+		}
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// invertAddr flips every bit of an address.
+func invertAddr(addr netip.Addr) netip.Addr {
+	b := addr.AsSlice()
+	for i := range b {
+		b[i] = ^b[i]
+	}
+	inverted, _ := netip.AddrFromSlice(b)
+	return inverted
+}
+
+// ipv4Class returns the historical class of an IPv4 address.
+func ipv4Class(addr netip.Addr) string {
+	first := addr.As4()[0]
+	switch {
+	case first < 128:
+		return "A"
+	case first < 192:
+		return "B"
+	case first < 224:
+		return "C"
+	case first < 240:
+		return "D (multicast)"
 	}
-	return broadcast.String()
+	return "E (reserved)"
+}
+
+// specialRange is an entry of the IANA special-purpose address registries.
+type specialRange struct {
+	prefix   netip.Prefix
+	name     string
+	private  bool
+	reserved bool
+}
+
+// specialRanges lists special-purpose IPv4 and IPv6 ranges, more specific ranges first.
+var specialRanges = []specialRange{
+	{netip.MustParsePrefix("0.0.0.0/8"), "\"this network\" (RFC 791)", false, true},
+	{netip.MustParsePrefix("10.0.0.0/8"), "private (RFC 1918)", true, false},
+	{netip.MustParsePrefix("100.64.0.0/10"), "shared address space, carrier-grade NAT (RFC 6598)", false, true},
+	{netip.MustParsePrefix("127.0.0.0/8"), "loopback", false, true},
+	{netip.MustParsePrefix("169.254.0.0/16"), "link-local", false, true},
+	{netip.MustParsePrefix("172.16.0.0/12"), "private (RFC 1918)", true, false},
+	{netip.MustParsePrefix("192.0.0.0/24"), "IETF protocol assignments", false, true},
+	{netip.MustParsePrefix("192.0.2.0/24"), "documentation, TEST-NET-1 (RFC 5737)", false, true},
+	{netip.MustParsePrefix("192.88.99.0/24"), "6to4 relay anycast (deprecated)", false, true},
+	{netip.MustParsePrefix("192.168.0.0/16"), "private (RFC 1918)", true, false},
+	{netip.MustParsePrefix("198.18.0.0/15"), "benchmarking (RFC 2544)", false, true},
+	{netip.MustParsePrefix("198.51.100.0/24"), "documentation, TEST-NET-2 (RFC 5737)", false, true},
+	{netip.MustParsePrefix("203.0.113.0/24"), "documentation, TEST-NET-3 (RFC 5737)", false, true},
+	{netip.MustParsePrefix("224.0.0.0/4"), "multicast", false, true},
+	{netip.MustParsePrefix("255.255.255.255/32"), "limited broadcast", false, true},
+	{netip.MustParsePrefix("240.0.0.0/4"), "reserved for future use", false, true},
+	{netip.MustParsePrefix("::/128"), "unspecified", false, true},
+	{netip.MustParsePrefix("::1/128"), "loopback", false, true},
+	{netip.MustParsePrefix("::ffff:0:0/96"), "IPv4-mapped", false, true},
+	{netip.MustParsePrefix("64:ff9b::/96"), "IPv4/IPv6 translation, NAT64 (RFC 6052)", false, true},
+	{netip.MustParsePrefix("100::/64"), "discard-only (RFC 6666)", false, true},
+	{netip.MustParsePrefix("2001::/32"), "Teredo", false, true},
+	{netip.MustParsePrefix("2001:db8::/32"), "documentation (RFC 3849)", false, true},
+	{netip.MustParsePrefix("2002::/16"), "6to4", false, true},
+	{netip.MustParsePrefix("fc00::/7"), "unique local (RFC 4193)", true, false},
+	{netip.MustParsePrefix("fe80::/10"), "link-local", false, true},
+	{netip.MustParsePrefix("ff00::/8"), "multicast", false, true},
+	{netip.MustParsePrefix("2000::/3"), "global unicast", false, false},
 }
 
-// calculateLastIP calculates the last IP address in the given subnet's IP range.
-// It uses the network address and subnet mask to determine the highest IP in the range.
-func calculateLastIP(ipNet *net.IPNet) string {
-	ip := ipNet.IP.To4()
-	mask := ipNet.Mask
-	lastIP := make(net.IP, len(ip))
-	for i := 0; i < len(ip); i++ {
-		lastIP[i] = ip[i] | ^mask[i]
+// classifySubnet returns the type of a network from the special-purpose registries and whether
+// it is private or reserved. A network larger than the ranges it overlaps is reported as mixed.
+func classifySubnet(network netip.Prefix) (string, bool, bool) {
+	for _, special := range specialRanges {
+		if special.prefix.Addr().Is4() != network.Addr().Is4() {
+			continue
+		}
+		if special.prefix.Bits() <= network.Bits() && special.prefix.Contains(network.Addr()) {
+			return special.name, special.private, special.reserved
+		}
+		if network.Bits() < special.prefix.Bits() && network.Contains(special.prefix.Addr()) {
+			return "mixed (spans special-purpose ranges)", false, false
+		}
+	}
+	if network.Addr().Is4() {
+		return "public", false, false
+	}
+	return "reserved by IETF", false, true
+}
+
+// PrintSubnetPlan displays the subnets of a split and the address space left over.
+func PrintSubnetPlan(plan SubnetPlan) {
+	byHosts := len(plan.Subnets) > 0 && plan.Subnets[0].Requested > 0
+	t := utils.Table("DarkSimple", "Subnets")
+	header := table.Row{"#", "Subnet", "Usable Range", "Usable Hosts"}
+	if byHosts {
+		header = table.Row{"#", "Hosts Needed", "Subnet", "Usable Range", "Usable Hosts"}
+	}
+	t.AppendHeader(header)
+	for i, subnet := range plan.Subnets {
+		row := table.Row{i + 1, subnet.Subnet, subnet.FirstUsable + " - " + subnet.LastUsable, formatAddressCount(subnet.UsableHosts)}
+		if byHosts {
+			row = append(table.Row{i + 1, subnet.Requested}, row[1:]...)
+		}
+		t.AppendRow(row)
+	}
+	fmt.Println()
+	t.Render()
+	fmt.Printf("%s split into %d subnets.\n", plan.Network, len(plan.Subnets))
+	if len(plan.Free) > 0 {
+		fmt.Printf("Unallocated: %s\n", strings.Join(plan.Free, ", "))
 	}
-	return lastIP.String()
+}
+
+// PrintSubnetOverlaps displays the pairs of overlapping networks.
+func PrintSubnetOverlaps(overlaps []SubnetOverlap, count int) {
+	if len(overlaps) == 0 {
+		fmt.Printf("No overlaps among %d networks.\n", count)
+		return
+	}
+	describe := func(network NamedSubnet) string {
+		if network.Name == "" {
+			return network.Prefix.String()
+		}
+		return fmt.Sprintf("%s (%s)", network.Prefix, network.Name)
+	}
+	t := utils.Table("DarkSimple", "Overlaps")
+	t.AppendHeader(table.Row{"Network", "Relation", "Network", "Lines"})
+	for _, overlap := range overlaps {
+		lines := fmt.Sprintf("%d, %d", overlap.First.Line, overlap.Second.Line)
+		t.AppendRow(table.Row{describe(overlap.First), overlap.Relation, describe(overlap.Second), lines})
+	}
+	fmt.Println()
+	t.Render()
+	utils.TerminalColor(fmt.Sprintf("%d overlapping pair(s) among %d networks.", len(overlaps), count), utils.Warn)
+}
+
+// PrintSubnetMembership displays which items lie within a network.
+func PrintSubnetMembership(network netip.Prefix, results []SubnetMembership) {
+	inside := 0
+	t := utils.Table("DarkSimple", network.Masked().String())
+	t.AppendHeader(table.Row{"Item", "Contained"})
+	for _, result := range results {
+		contained := "no"
+		if result.Contained {
+			contained = "yes"
+			inside++
+		}
+		t.AppendRow(table.Row{result.Item, contained})
+	}
+	fmt.Println()
+	t.Render()
+	fmt.Printf("%d of %d inside %s.\n", inside, len(results), network.Masked())
+}
+
+// isRegularFile reports whether path names an existing regular file.
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
 }
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestParseSubnet(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   bool
+	}{
+		{input: "10.0.0.5/24", want: "10.0.0.5/24"},
+		{input: " 10.0.0.5 255.255.255.0 ", want: "10.0.0.5/24"},
+		{input: "10.0.0.5/255.255.252.0", want: "10.0.0.5/22"},
+		{input: "192.168.1.1", want: "192.168.1.1/32"},
+		{input: "2001:db8::1/64", want: "2001:db8::1/64"},
+		{input: "2001:db8::1", want: "2001:db8::1/128"},
+		{input: "10.0.0.5 255.0.255.0", err: true},
+		{input: "2001:db8::1 255.255.255.0", err: true},
+		{input: "10.0.0.5/33", err: true},
+		{input: "10.0.0.5/-1", err: true},
+		{input: "fe80::1%eth0/64", err: true},
+		{input: "10.0.0.5 255.255.255.0 extra", err: true},
+		{input: "example.com/24", err: true},
+	}
+	for _, tt := range tests {
+		prefix, err := ParseSubnet(tt.input)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseSubnet(%q) error = %v, want error %v", tt.input, err, tt.err)
+			continue
+		}
+		if err == nil && prefix.String() != tt.want {
+			t.Errorf("ParseSubnet(%q) = %s, want %s", tt.input, prefix, tt.want)
+		}
+	}
+}
+
+func TestRunSubnetCalculator(t *testing.T) {
+	tests := []struct {
+		input                            string
+		cidr, broadcast, netmask, wild   string
+		total, usable, first, last, kind string
+	}{
+		{"192.168.1.77/24", "192.168.1.0/24", "192.168.1.255", "255.255.255.0", "0.0.0.255", "256", "254", "192.168.1.1", "192.168.1.254", "private (RFC 1918)"},
+		{"203.0.113.8/31", "203.0.113.8/31", "N/A (point-to-point link, RFC 3021)", "255.255.255.254", "0.0.0.1", "2", "2", "203.0.113.8", "203.0.113.9", "documentation, TEST-NET-3 (RFC 5737)"},
+		{"8.8.8.8/32", "8.8.8.8/32", "N/A (single host)", "255.255.255.255", "0.0.0.0", "1", "1", "8.8.8.8", "8.8.8.8", "public"},
+		{"2001:db8:1::9/64", "2001:db8:1::/64", "N/A (IPv6)", "ffff:ffff:ffff:ffff::", "::ffff:ffff:ffff:ffff", "18446744073709551616", "18446744073709551615", "2001:db8:1::1", "2001:db8:1:0:ffff:ffff:ffff:ffff", "documentation (RFC 3849)"},
+		{"2001:db8::/127", "2001:db8::/127", "N/A (IPv6)", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe", "::1", "2", "2", "2001:db8::", "2001:db8::1", "documentation (RFC 3849)"},
+	}
+	for _, tt := range tests {
+		details := RunSubnetCalculator(netip.MustParsePrefix(tt.input))
+		got := []string{details.CIDR, details.BroadcastAddress, details.Netmask, details.WildcardMask,
+			details.TotalAddresses.String(), details.UsableHosts.String(), details.FirstUsable, details.LastUsable, details.Type}
+		want := []string{tt.cidr, tt.broadcast, tt.netmask, tt.wild, tt.total, tt.usable, tt.first, tt.last, tt.kind}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("RunSubnetCalculator(%s) = %q, want %q", tt.input, got, want)
+				break
+			}
+		}
+		if prefix := netip.MustParsePrefix(tt.input); details.Address != prefix.Addr().String() || details.PrefixLength != prefix.Bits() {
+			t.Errorf("RunSubnetCalculator(%s) address %s /%d", tt.input, details.Address, details.PrefixLength)
+		}
+	}
+
+	v4 := RunSubnetCalculator(netip.MustParsePrefix("10.1.2.3/8"))
+	if v4.Version != 4 || v4.Class != "A" || v4.IPRange != "10.0.0.0 - 10.255.255.255" || !v4.Private || v4.Reserved {
+		t.Errorf("10.1.2.3/8 = %+v", v4)
+	}
+	v6 := RunSubnetCalculator(netip.MustParsePrefix("fd00::/8"))
+	if v6.Version != 6 || v6.Class != "" || !v6.Private {
+		t.Errorf("fd00::/8 = %+v", v6)
+	}
+}
+
+func TestClassifySubnet(t *testing.T) {
+	tests := []struct {
+		network           string
+		kind              string
+		private, reserved bool
+	}{
+		{"172.20.0.0/16", "private (RFC 1918)", true, false},
+		{"100.100.0.0/16", "shared address space, carrier-grade NAT (RFC 6598)", false, true},
+		{"127.0.0.1/32", "loopback", false, true},
+		{"255.255.255.255/32", "limited broadcast", false, true},
+		{"240.0.0.0/8", "reserved for future use", false, true},
+		{"192.0.0.0/16", "mixed (spans special-purpose ranges)", false, false},
+		{"0.0.0.0/0", "mixed (spans special-purpose ranges)", false, false},
+		{"1.1.1.0/24", "public", false, false},
+		{"::1/128", "loopback", false, true},
+		{"fe80::/64", "link-local", false, true},
+		{"2606:4700::/32", "global unicast", false, false},
+		{"4000::/2", "reserved by IETF", false, true},
+	}
+	for _, tt := range tests {
+		kind, private, reserved := classifySubnet(netip.MustParsePrefix(tt.network))
+		if kind != tt.kind || private != tt.private || reserved != tt.reserved {
+			t.Errorf("classifySubnet(%s) = %q %v %v, want %q %v %v", tt.network, kind, private, reserved, tt.kind, tt.private, tt.reserved)
+		}
+	}
+
+	classes := map[string]string{"9.0.0.1": "A", "172.16.0.1": "B", "200.1.1.1": "C", "239.1.1.1": "D (multicast)", "250.0.0.1": "E (reserved)"}
+	for addr, want := range classes {
+		if got := ipv4Class(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("ipv4Class(%s) = %s, want %s", addr, got, want)
+		}
+	}
+}
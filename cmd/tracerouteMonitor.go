@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+)
+
+// TracerouteHopStats accumulates the results of repeatedly probing one hop, the way mtr reports them.
+type TracerouteHopStats struct {
+	HopNumber   int           `json:"hop"`
+	Hostname    string        `json:"hostname"`
+	IP          string        `json:"ip"` // Address of the most recent reply
+	Sent        int           `json:"sent"`
+	Received    int           `json:"received"`
+	Loss        float64       `json:"loss"` // Percentage of probes that got no reply
+	Last        time.Duration `json:"last"`
+	Avg         time.Duration `json:"avg"`
+	Best        time.Duration `json:"best"`
+	Worst       time.Duration `json:"worst"`
+	StDev       time.Duration `json:"stdev"`
+	Jitter      time.Duration `json:"jitter"` // Mean difference between consecutive round-trip times
+	Reached     bool          `json:"reached"`
+	Unreachable bool          `json:"unreachable"` // A router reported the destination unreachable
+	Note        string        `json:"note"`
+
+	sum, sumSquares float64
+	jitterSum       float64
+}
+
+// TracerouteReport is the state of a continuous traceroute after a number of cycles.
+type TracerouteReport struct {
+	Destination string               `json:"destination"`
+	Address     string               `json:"address"`
+	Protocol    string               `json:"protocol"`
+	Cycles      int                  `json:"cycles"`
+	Start       time.Time            `json:"start"`
+	End         time.Time            `json:"end"`
+	Hops        []TracerouteHopStats `json:"hops"`
+}
+
+// TracerouteMonitorOptions controls a continuous traceroute. The probing itself is configured by
+// the embedded TracerouteOptions; ProbesPerHop and Timeout are not used.
+type TracerouteMonitorOptions struct {
+	TracerouteOptions
+	Cycles   int                    // Number of cycles to run; 0 runs until the context is canceled
+	Interval time.Duration          // Minimum time between the start of two cycles
+	OnCycle  func(TracerouteReport) // Called after every cycle, e.g. to refresh a live display
+}
+
+// record adds the outcome of one probe to the hop's statistics.
+func (s *TracerouteHopStats) record(sent time.Time, reply *probeReply) {
+	s.Sent++
+	if reply != nil {
+		rtt := reply.at.Sub(sent)
+		if s.Received == 0 || rtt < s.Best {
+			s.Best = rtt
+		}
+		if rtt > s.Worst {
+			s.Worst = rtt
+		}
+		if s.Received > 0 {
+			s.jitterSum += math.Abs(float64(rtt - s.Last))
+			s.Jitter = time.Duration(s.jitterSum / float64(s.Received))
+		}
+		s.Received++
+		s.Last = rtt
+		s.sum += float64(rtt)
+		s.sumSquares += float64(rtt) * float64(rtt)
+		mean := s.sum / float64(s.Received)
+		s.Avg = time.Duration(mean)
+		s.StDev = time.Duration(math.Sqrt(math.Max(s.sumSquares/float64(s.Received)-mean*mean, 0)))
+
+		s.IP = reply.from.String()
+		s.Reached = s.Reached || reply.reached
+		s.Unreachable = s.Unreachable || reply.unreachable
+		if reply.note != "" {
+			s.Note = reply.note
+		}
+	}
+	s.Loss = float64(s.Sent-s.Received) / float64(s.Sent) * 100
+}
+
+// RunTracerouteMonitor probes every hop on the path to destination once per cycle, like mtr, and
+// accumulates per-hop loss and latency statistics. It runs opts.Cycles cycles, or until ctx is
+// canceled, and returns the final report. It needs raw-socket privileges; unlike RunTraceroute
+// there is no fallback to the system's tools.
+func RunTracerouteMonitor(ctx context.Context, destination string, opts TracerouteMonitorOptions) (TracerouteReport, error) {
+	opts.TracerouteOptions = opts.TracerouteOptions.withDefaults()
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	report := TracerouteReport{Destination: destination, Protocol: opts.Protocol, Start: time.Now()}
+	switch opts.Protocol {
+	case TracerouteUDP, TracerouteICMP, TracerouteTCP:
+	default:
+		return report, fmt.Errorf("unknown probe protocol %q (use udp, icmp or tcp)", opts.Protocol)
+	}
+	if opts.FirstHop > opts.MaxHops {
+		return report, fmt.Errorf("first hop %d is beyond the maximum of %d hops", opts.FirstHop, opts.MaxHops)
+	}
+
+	dst, err := tracerouteAddress(destination, opts.Family)
+	if err != nil {
+		return report, err
+	}
+	report.Address = dst.String()
+	t, err := newTracer(dst, opts.TracerouteOptions)
+	if err != nil {
+		return report, err
+	}
+	defer t.close()
+
+	stats := make([]TracerouteHopStats, opts.MaxHops-opts.FirstHop+1)
+	for i := range stats {
+		stats[i].HopNumber = opts.FirstHop + i
+	}
+	names := make(map[string]string)
+	last := opts.MaxHops // Probing stops at the destination once it has been found
+
+	for {
+		cycleStart := time.Now()
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		slots := make(chan struct{}, opts.Parallel)
+		for ttl := opts.FirstHop; ttl <= last && ctx.Err() == nil; ttl++ {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(ttl int) {
+				defer func() { <-slots; wg.Done() }()
+				sent, reply, err := t.probe(ctx, ttl)
+				if ctx.Err() != nil {
+					// Interrupted, not lost
+					return
+				}
+				if err != nil {
+					reply = nil
+				}
+				mu.Lock()
+				stats[ttl-opts.FirstHop].record(sent, reply)
+				mu.Unlock()
+			}(ttl)
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			// The report keeps the statistics of the last complete cycle
+			return report, nil
+		}
+		report.Cycles++
+
+		for i := range stats[:last-opts.FirstHop+1] {
+			if stats[i].Reached || stats[i].Unreachable {
+				last = stats[i].HopNumber
+				break
+			}
+		}
+		if !opts.NoDNS {
+			resolveStatsNames(stats[:last-opts.FirstHop+1], names)
+		}
+
+		report.End = time.Now()
+		report.Hops = append([]TracerouteHopStats(nil), stats[:last-opts.FirstHop+1]...)
+		if opts.OnCycle != nil {
+			opts.OnCycle(report)
+		}
+		if opts.Cycles != 0 && report.Cycles >= opts.Cycles {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return report, nil
+		case <-time.After(time.Until(cycleStart.Add(opts.Interval))):
+		}
+	}
+	return report, nil
+}
+
+// resolveStatsNames fills in the host names of hops, looking up each address only once.
+// names caches the results between cycles.
+func resolveStatsNames(stats []TracerouteHopStats, names map[string]string) {
+	var lookup []TracerouteHop
+	for _, hop := range stats {
+		if _, known := names[hop.IP]; hop.IP != "" && !known {
+			lookup = append(lookup, TracerouteHop{IP: hop.IP})
+		}
+	}
+	resolveHopNames(lookup)
+	for _, hop := range lookup {
+		names[hop.IP] = hop.Hostname
+	}
+	for i := range stats {
+		stats[i].Hostname = names[stats[i].IP]
+	}
+}
+
+// PrintTracerouteReport displays the per-hop statistics of a continuous traceroute.
+func PrintTracerouteReport(report TracerouteReport) {
+	t := utils.Table("DarkSimple", fmt.Sprintf("Path to %s (%s)", report.Destination, report.Address))
+	t.AppendHeader(table.Row{"Hop", "Hostname", "IP Address", "Loss%", "Sent", "Last", "Avg", "Best", "Worst", "StDev", "Jitter", "Note"})
+	for _, hop := range report.Hops {
+		row := table.Row{hop.HopNumber, valueOrDash(hop.Hostname), valueOrDash(hop.IP), fmt.Sprintf("%.1f", hop.Loss), hop.Sent}
+		if hop.Received == 0 {
+			row = append(row, "*", "*", "*", "*", "*", "*")
+		} else {
+			for _, d := range []time.Duration{hop.Last, hop.Avg, hop.Best, hop.Worst, hop.StDev, hop.Jitter} {
+				row = append(row, fmt.Sprintf("%.1f", float64(d)/float64(time.Millisecond)))
+			}
+		}
+		row = append(row, hop.Note)
+		t.AppendRow(row)
+	}
+
+	fmt.Println()
+	t.Render()
+	fmt.Println()
+	fmt.Printf("%d cycles over %s; times in ms.\n", report.Cycles, report.End.Sub(report.Start).Round(time.Second))
+}
+
+// runTracerouteMonitor runs a continuous traceroute for the command line. On a terminal the
+// table is redrawn after every cycle; the final report is printed when the cycles are done or
+// the user presses Ctrl-C.
+func runTracerouteMonitor(destination string, opts TracerouteMonitorOptions, jsonOutput bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	live := !jsonOutput && utils.IsTerminal()
+	if live {
+		opts.OnCycle = func(report TracerouteReport) {
+			utils.ClearTerminal()
+			PrintTracerouteReport(report)
+			fmt.Println("Press Ctrl-C to stop.")
+		}
+	}
+	report, err := RunTracerouteMonitor(ctx, destination, opts)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	switch {
+	case jsonOutput:
+		utils.PrintJSON(report)
+	case live:
+		utils.ClearTerminal()
+		PrintTracerouteReport(report)
+	default:
+		PrintTracerouteReport(report)
+	}
+}
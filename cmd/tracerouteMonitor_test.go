@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTracerouteHopStatsRecord(t *testing.T) {
+	sent := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	reply := func(rtt time.Duration) *probeReply {
+		return &probeReply{from: net.ParseIP("10.0.0.1"), at: sent.Add(rtt)}
+	}
+
+	var s TracerouteHopStats
+	for _, r := range []*probeReply{reply(10 * time.Millisecond), nil, reply(30 * time.Millisecond), reply(20 * time.Millisecond)} {
+		s.record(sent, r)
+	}
+	if s.Sent != 4 || s.Received != 3 || s.Loss != 25 {
+		t.Errorf("sent %d received %d loss %v, want 4 3 25", s.Sent, s.Received, s.Loss)
+	}
+	if s.Last != 20*time.Millisecond || s.Avg != 20*time.Millisecond || s.Best != 10*time.Millisecond || s.Worst != 30*time.Millisecond {
+		t.Errorf("last %v avg %v best %v worst %v, want 20ms 20ms 10ms 30ms", s.Last, s.Avg, s.Best, s.Worst)
+	}
+	// Consecutive differences of 20ms and 10ms
+	if s.Jitter != 15*time.Millisecond {
+		t.Errorf("jitter = %v, want 15ms", s.Jitter)
+	}
+	if d := s.StDev - 8164965*time.Nanosecond; d < -time.Microsecond || d > time.Microsecond {
+		t.Errorf("stdev = %v, want about 8.16ms", s.StDev)
+	}
+	if s.IP != "10.0.0.1" || s.Reached || s.Unreachable {
+		t.Errorf("ip %q reached %v unreachable %v", s.IP, s.Reached, s.Unreachable)
+	}
+
+	s.record(sent, &probeReply{from: net.ParseIP("10.0.0.1"), at: sent, note: "!H", unreachable: true})
+	s.record(sent, reply(time.Millisecond))
+	if !s.Unreachable || s.Note != "!H" {
+		t.Errorf("unreachable %v note %q, want the !H reply to stick", s.Unreachable, s.Note)
+	}
+}
+
+func TestTracerouteMonitorCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	opts := TracerouteMonitorOptions{
+		TracerouteOptions: TracerouteOptions{Protocol: TracerouteICMP, MaxHops: 3, Wait: 10 * time.Second, NoDNS: true},
+		Interval:          time.Second,
+	}
+	start := time.Now()
+	// TEST-NET-1 is never routed, so the probes wait for opts.Wait unless canceled
+	_, err := RunTracerouteMonitor(ctx, "192.0.2.1", opts)
+	if errors.Is(err, os.ErrPermission) {
+		t.Skip("raw sockets are not permitted:", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("returned %v after the start, want soon after the cancel", elapsed)
+	}
+}
@@ -0,0 +1,630 @@
+package cmd
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Probe protocols supported by the built-in traceroute.
+const (
+	TracerouteUDP  = "udp"
+	TracerouteICMP = "icmp"
+	TracerouteTCP  = "tcp"
+)
+
+// tracerouteSegment keys the TCP answers captured from the destination, by their source (the
+// probed port) and destination port.
+const tracerouteSegment = "segment"
+
+// TracerouteNoReply is recorded in TracerouteHop.RTTs for a probe that got no answer.
+const TracerouteNoReply time.Duration = -1
+
+// probeReply is the answer to a single probe: an ICMP error from a router on the way, or a
+// reply from the destination itself.
+type probeReply struct {
+	from        net.IP
+	at          time.Time
+	reached     bool   // The destination answered
+	note        string // traceroute-style annotation such as !H (host unreachable)
+	unreachable bool   // A destination-unreachable error: probes with a higher TTL cannot get further
+	ttl         int    // TTL (hop limit) of a TCP answer as it arrived, when it was captured
+}
+
+// tracer sends traceroute probes to one destination and matches the ICMP messages that come
+// back to the probe that caused them.
+type tracer struct {
+	opts     TracerouteOptions
+	dst      net.IP
+	ipv6     bool
+	listener *icmp.PacketConn // Raw ICMP(v6) socket receiving time-exceeded and unreachable messages
+	segments net.PacketConn   // Raw TCP socket capturing the destination's answers to SYN probes; may be nil
+	id       int              // ICMP echo identifier of our probes
+	seq      atomic.Uint32    // Probe sequence, used for echo sequence numbers and UDP ports
+
+	mu      sync.Mutex
+	pending map[string]chan probeReply // Keyed by probeKey
+
+	ttlMu sync.Mutex // Serializes SetTTL and WriteTo on the shared ICMP socket
+}
+
+// runNativeTraceroute traces the path to dst by sending probes with increasing TTLs and
+// listening for the ICMP time-exceeded messages routers return. It needs a raw ICMP socket.
+func runNativeTraceroute(dst net.IP, opts TracerouteOptions) ([]TracerouteHop, error) {
+	t, err := newTracer(dst, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	deadline := time.Now().Add(opts.Timeout)
+	var hops []TracerouteHop
+	for first := opts.FirstHop; first <= opts.MaxHops; first += opts.Parallel {
+		last := min(first+opts.Parallel-1, opts.MaxHops)
+
+		// Probe a window of consecutive TTLs at the same time
+		window := make([]TracerouteHop, last-first+1)
+		var wg sync.WaitGroup
+		for ttl := first; ttl <= last; ttl++ {
+			wg.Add(1)
+			go func(ttl int) {
+				defer wg.Done()
+				window[ttl-first] = t.probeHop(ttl)
+			}(ttl)
+		}
+		wg.Wait()
+
+		for _, hop := range window {
+			hops = append(hops, hop)
+			// Notes such as "open" or "(middlebox?)" only annotate a hop; the trace goes on
+			// until the destination answers or a router reports it unreachable
+			if hop.Reached || hop.Unreachable {
+				return hops, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return hops, fmt.Errorf("traceroute timed out after %s", opts.Timeout)
+		}
+	}
+	return hops, nil
+}
+
+// newTracer opens the raw ICMP socket used to receive replies and starts reading from it. For
+// TCP probes it also tries to open a raw TCP socket to see how the destination answered; without
+// one the trace still works, but open and closed ports cannot be told apart from a middlebox.
+func newTracer(dst net.IP, opts TracerouteOptions) (*tracer, error) {
+	ipv6 := dst.To4() == nil
+	network, address := "ip4:icmp", "0.0.0.0"
+	if ipv6 {
+		network, address = "ip6:ipv6-icmp", "::"
+	}
+	listener, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("opening raw ICMP socket: %w", err)
+	}
+	t := &tracer{
+		opts:     opts,
+		dst:      dst,
+		ipv6:     ipv6,
+		listener: listener,
+		id:       os.Getpid() & 0xffff,
+		pending:  make(map[string]chan probeReply),
+	}
+	go t.readReplies()
+
+	if opts.Protocol == TracerouteTCP {
+		network = "ip4:tcp"
+		if ipv6 {
+			network = "ip6:tcp"
+		}
+		if segments, err := net.ListenPacket(network, address); err == nil {
+			t.segments = segments
+			go t.readSegments()
+		}
+	}
+	return t, nil
+}
+
+// close releases the tracer's sockets, which also stops its readers.
+func (t *tracer) close() {
+	t.listener.Close()
+	if t.segments != nil {
+		t.segments.Close()
+	}
+}
+
+// probeHop sends opts.ProbesPerHop probes with the given TTL, one after the other.
+func (t *tracer) probeHop(ttl int) TracerouteHop {
+	hop := TracerouteHop{HopNumber: ttl}
+	for i := 0; i < t.opts.ProbesPerHop; i++ {
+		sent, reply, err := t.probe(context.Background(), ttl)
+		if err != nil || reply == nil {
+			hop.RTTs = append(hop.RTTs, TracerouteNoReply)
+			continue
+		}
+		hop.RTTs = append(hop.RTTs, reply.at.Sub(sent))
+		if hop.IP == "" {
+			hop.IP = reply.from.String()
+		}
+		hop.Reached = hop.Reached || reply.reached
+		hop.Unreachable = hop.Unreachable || reply.unreachable
+		if reply.note != "" {
+			hop.Note = reply.note
+		}
+	}
+	return hop
+}
+
+// probe sends a single probe with the given TTL and waits up to opts.Wait for its answer, or
+// until ctx is canceled. It returns the time the probe was sent and the reply, or nil when none
+// arrived.
+func (t *tracer) probe(ctx context.Context, ttl int) (time.Time, *probeReply, error) {
+	switch t.opts.Protocol {
+	case TracerouteICMP:
+		return t.probeICMP(ctx, ttl)
+	case TracerouteTCP:
+		return t.probeTCP(ctx, ttl)
+	}
+	return t.probeUDP(ctx, ttl)
+}
+
+// probeICMP sends an ICMP echo request from the shared raw socket.
+func (t *tracer) probeICMP(ctx context.Context, ttl int) (time.Time, *probeReply, error) {
+	seq := int(t.seq.Add(1) & 0xffff)
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{ID: t.id, Seq: seq, Data: []byte("ghost-traceroute")},
+	}
+	if t.ipv6 {
+		// The kernel fills in the ICMPv6 checksum, which covers the IPv6 pseudo-header
+		msg.Type = ipv6.ICMPTypeEchoRequest
+	}
+	packet, err := msg.Marshal(nil)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	ch := t.register(probeKey(TracerouteICMP, t.id, seq))
+	defer t.unregister(probeKey(TracerouteICMP, t.id, seq))
+
+	t.ttlMu.Lock()
+	if t.ipv6 {
+		t.listener.IPv6PacketConn().SetHopLimit(ttl)
+	} else {
+		t.listener.IPv4PacketConn().SetTTL(ttl)
+	}
+	sent := time.Now()
+	_, err = t.listener.WriteTo(packet, &net.IPAddr{IP: t.dst})
+	t.ttlMu.Unlock()
+	if err != nil {
+		return sent, nil, err
+	}
+	return sent, t.wait(ctx, ch), nil
+}
+
+// probeUDP sends a UDP datagram to an unlikely port; the destination answers with port unreachable.
+// Like classic traceroute the port is increased for every probe.
+func (t *tracer) probeUDP(ctx context.Context, ttl int) (time.Time, *probeReply, error) {
+	network := "udp4"
+	if t.ipv6 {
+		network = "udp6"
+	}
+	conn, err := net.ListenPacket(network, ":0")
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	defer conn.Close()
+	if t.ipv6 {
+		err = ipv6.NewPacketConn(conn).SetHopLimit(ttl)
+	} else {
+		err = ipv4.NewPacketConn(conn).SetTTL(ttl)
+	}
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	localPort := conn.LocalAddr().(*net.UDPAddr).Port
+	port := t.opts.Port + int(t.seq.Add(1)%1024)
+	ch := t.register(probeKey(TracerouteUDP, localPort, port))
+	defer t.unregister(probeKey(TracerouteUDP, localPort, port))
+
+	sent := time.Now()
+	if _, err := conn.WriteTo(make([]byte, 32), &net.UDPAddr{IP: t.dst, Port: port}); err != nil {
+		return sent, nil, err
+	}
+	return sent, t.wait(ctx, ch), nil
+}
+
+// probeTCP starts a TCP connection (a SYN) to opts.Port. A SYN-ACK or RST from the destination
+// completes the trace; routers on the way answer with time-exceeded messages quoting the SYN.
+func (t *tracer) probeTCP(ctx context.Context, ttl int) (time.Time, *probeReply, error) {
+	ch := make(chan probeReply, 1)
+	segment := make(chan probeReply, 1)
+	var key, segmentKey string
+	var sent time.Time
+	dialer := net.Dialer{
+		// Set the TTL and learn the source port before the SYN goes out, so the ICMP reply can be matched
+		Control: func(network, address string, c syscall.RawConn) error {
+			var probeErr error
+			err := c.Control(func(fd uintptr) {
+				var localPort int
+				localPort, probeErr = prepareProbeSocket(fd, ttl, t.ipv6)
+				if probeErr == nil {
+					key = probeKey(TracerouteTCP, localPort, t.opts.Port)
+					segmentKey = probeKey(tracerouteSegment, t.opts.Port, localPort)
+					t.registerChan(key, ch)
+					t.registerChan(segmentKey, segment)
+					sent = time.Now()
+				}
+			})
+			if err != nil {
+				return err
+			}
+			return probeErr
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.opts.Wait)
+	defer cancel()
+	type dialResult struct {
+		conn net.Conn
+		err  error
+		at   time.Time
+	}
+	network := "tcp4"
+	if t.ipv6 {
+		network = "tcp6"
+	}
+	done := make(chan dialResult, 1)
+	go func() {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(t.dst.String(), strconv.Itoa(t.opts.Port)))
+		done <- dialResult{conn, err, time.Now()}
+	}()
+
+	// A router reporting the expired TTL cuts the connection attempt short
+	var reply *probeReply
+	var result dialResult
+	select {
+	case result = <-done:
+	case r := <-ch:
+		reply = &r
+		cancel()
+		result = <-done
+	}
+	if key != "" {
+		defer t.unregister(key)
+		defer t.unregister(segmentKey)
+	}
+	if reply == nil {
+		select {
+		case r := <-ch:
+			reply = &r
+		default:
+		}
+	}
+
+	switch {
+	case result.conn != nil:
+		result.conn.Close()
+		return sent, t.tcpAnswer(ttl, segment, result.at, "open"), nil
+	case isConnectionRefused(result.err):
+		return sent, t.tcpAnswer(ttl, segment, result.at, "closed"), nil
+	case reply != nil:
+		return sent, reply, nil
+	case key == "":
+		return sent, nil, result.err
+	}
+	return sent, nil, nil
+}
+
+// tcpAnswer builds the reply for a TCP probe the destination address answered. When the answer
+// was captured from the raw TCP socket, its TTL tells roughly how many hops it travelled; an answer
+// from much closer than the hop it was received at was most likely forged by a firewall or other
+// middlebox on the path rather than sent by the destination.
+func (t *tracer) tcpAnswer(ttl int, segment chan probeReply, at time.Time, state string) *probeReply {
+	reply := &probeReply{from: t.dst, at: at, reached: true, note: state}
+	if t.segments == nil {
+		return reply
+	}
+	timer := time.NewTimer(100 * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case captured := <-segment:
+		reply.ttl = captured.ttl
+		if captured.note != "" {
+			reply.note = captured.note
+		}
+		if middleboxSuspected(ttl, captured.ttl) {
+			reply.note += " (middlebox?)"
+		}
+	case <-timer.C:
+	}
+	return reply
+}
+
+// middleboxSuspected reports whether a TCP answer that arrived with TTL replyTTL is unlikely to
+// come from a destination reached with probe TTL hop. The answer's initial TTL is assumed to be
+// the next common default (64, 128 or 255) above replyTTL. A destination at hop n sits behind
+// n-1 routers, so its answers lose about n-1 from their initial TTL; answers that lost far less
+// were sent by something closer.
+func middleboxSuspected(hop, replyTTL int) bool {
+	if replyTTL <= 0 {
+		return false
+	}
+	initial := 255
+	for _, common := range []int{64, 128} {
+		if replyTTL <= common {
+			initial = common
+			break
+		}
+	}
+	returnHops := initial - replyTTL
+	return hop-1-returnHops > 3
+}
+
+// prepareProbeSocket sets the TTL (hop limit) of a TCP probe socket before it connects and binds
+// it to an ephemeral port, returning that port so ICMP errors quoting the SYN can be matched to it.
+func prepareProbeSocket(fd uintptr, ttl int, ipv6 bool) (int, error) {
+	s := probeSocketHandle(fd)
+	level, opt := syscall.IPPROTO_IP, syscall.IP_TTL
+	var addr syscall.Sockaddr = &syscall.SockaddrInet4{}
+	if ipv6 {
+		level, opt = syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS
+		addr = &syscall.SockaddrInet6{}
+	}
+	if err := syscall.SetsockoptInt(s, level, opt, ttl); err != nil {
+		return 0, fmt.Errorf("setting TTL: %w", err)
+	}
+	if err := syscall.Bind(s, addr); err != nil {
+		return 0, fmt.Errorf("binding probe socket: %w", err)
+	}
+	local, err := syscall.Getsockname(s)
+	if err != nil {
+		return 0, err
+	}
+	switch local := local.(type) {
+	case *syscall.SockaddrInet4:
+		return local.Port, nil
+	case *syscall.SockaddrInet6:
+		return local.Port, nil
+	}
+	return 0, fmt.Errorf("unexpected probe socket address %T", local)
+}
+
+// register creates the channel the reader delivers the reply for key on.
+func (t *tracer) register(key string) chan probeReply {
+	ch := make(chan probeReply, 1)
+	t.registerChan(key, ch)
+	return ch
+}
+
+// registerChan delivers the reply for key on ch.
+func (t *tracer) registerChan(key string, ch chan probeReply) {
+	t.mu.Lock()
+	t.pending[key] = ch
+	t.mu.Unlock()
+}
+
+// unregister stops waiting for replies to key.
+func (t *tracer) unregister(key string) {
+	t.mu.Lock()
+	delete(t.pending, key)
+	t.mu.Unlock()
+}
+
+// wait returns the reply delivered on ch, or nil if none arrives within opts.Wait or before ctx
+// is canceled.
+func (t *tracer) wait(ctx context.Context, ch chan probeReply) *probeReply {
+	timer := time.NewTimer(t.opts.Wait)
+	defer timer.Stop()
+	select {
+	case reply := <-ch:
+		return &reply
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// readReplies reads ICMP(v6) messages until the listener is closed and hands each one to the
+// probe it answers.
+func (t *tracer) readReplies() {
+	protocol := 1 // ICMP
+	if t.ipv6 {
+		protocol = 58 // ICMPv6
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := t.listener.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		at := time.Now()
+		from := peer.(*net.IPAddr).IP
+		msg, err := icmp.ParseMessage(protocol, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		reply := probeReply{from: from, at: at}
+		var key string
+		switch body := msg.Body.(type) {
+		case *icmp.Echo:
+			if (msg.Type != ipv4.ICMPTypeEchoReply && msg.Type != ipv6.ICMPTypeEchoReply) || !from.Equal(t.dst) {
+				continue
+			}
+			key = probeKey(TracerouteICMP, body.ID, body.Seq)
+			reply.reached = true
+		case *icmp.TimeExceeded:
+			key = quotedProbeKey(body.Data)
+		case *icmp.DstUnreach:
+			key = quotedProbeKey(body.Data)
+			if t.ipv6 {
+				reply.reached, reply.note = unreachableNote6(msg.Code, from.Equal(t.dst))
+			} else {
+				reply.reached, reply.note = unreachableNote(msg.Code, from.Equal(t.dst))
+			}
+			reply.unreachable = !reply.reached
+		default:
+			continue
+		}
+		t.deliver(key, reply)
+	}
+}
+
+// readSegments reads the TCP segments the destination sends until the raw TCP socket is closed,
+// and hands SYN-ACKs and RSTs to the TCP probe they answer together with their arrival TTL.
+func (t *tracer) readSegments() {
+	var read func([]byte) (int, int, net.Addr, error)
+	if t.ipv6 {
+		conn := ipv6.NewPacketConn(t.segments)
+		conn.SetControlMessage(ipv6.FlagHopLimit, true)
+		read = func(b []byte) (int, int, net.Addr, error) {
+			n, cm, peer, err := conn.ReadFrom(b)
+			if cm == nil {
+				return n, 0, peer, err
+			}
+			return n, cm.HopLimit, peer, err
+		}
+	} else {
+		conn := ipv4.NewPacketConn(t.segments)
+		conn.SetControlMessage(ipv4.FlagTTL, true)
+		read = func(b []byte) (int, int, net.Addr, error) {
+			n, cm, peer, err := conn.ReadFrom(b)
+			if cm == nil {
+				return n, 0, peer, err
+			}
+			return n, cm.TTL, peer, err
+		}
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, ttl, peer, err := read(buf)
+		if err != nil {
+			return
+		}
+		ipAddr, ok := peer.(*net.IPAddr)
+		if !ok || !ipAddr.IP.Equal(t.dst) || n < 20 {
+			continue
+		}
+		const syn, rst, ack = 0x02, 0x04, 0x10
+		flags := buf[13]
+		reply := probeReply{from: ipAddr.IP, at: time.Now(), reached: true, ttl: ttl}
+		switch {
+		case flags&rst != 0:
+			reply.note = "closed"
+		case flags&(syn|ack) == syn|ack:
+			reply.note = "open"
+		default:
+			continue
+		}
+		key := probeKey(tracerouteSegment, int(binary.BigEndian.Uint16(buf[0:2])), int(binary.BigEndian.Uint16(buf[2:4])))
+		t.deliver(key, reply)
+	}
+}
+
+// deliver hands reply to the probe waiting for key, if there is one.
+func (t *tracer) deliver(key string, reply probeReply) {
+	if key == "" {
+		return
+	}
+	t.mu.Lock()
+	if ch, ok := t.pending[key]; ok {
+		select {
+		case ch <- reply:
+		default:
+		}
+	}
+	t.mu.Unlock()
+}
+
+// unreachableNote interprets an ICMP destination-unreachable code the way traceroute annotates
+// it. A port (or protocol) unreachable from the destination means the probe arrived.
+func unreachableNote(code int, fromDestination bool) (bool, string) {
+	switch code {
+	case 2, 3: // Protocol, port unreachable
+		if fromDestination {
+			return true, ""
+		}
+		return false, "!P"
+	case 0:
+		return false, "!N"
+	case 1:
+		return false, "!H"
+	case 9, 10, 13: // Administratively prohibited
+		return false, "!X"
+	}
+	return false, "!" + strconv.Itoa(code)
+}
+
+// unreachableNote6 interprets an ICMPv6 destination-unreachable code like unreachableNote.
+func unreachableNote6(code int, fromDestination bool) (bool, string) {
+	switch code {
+	case 4: // Port unreachable
+		if fromDestination {
+			return true, ""
+		}
+		return false, "!P"
+	case 0: // No route to destination
+		return false, "!N"
+	case 3: // Address unreachable
+		return false, "!H"
+	case 1, 5, 6: // Administratively prohibited, source address failed policy, reject route
+		return false, "!X"
+	}
+	return false, "!" + strconv.Itoa(code)
+}
+
+// quotedProbeKey identifies the probe quoted in the body of an ICMP error: the original IPv4 or
+// IPv6 header followed by at least the first 8 bytes of the transport header. IPv6 extension
+// headers are not followed, since our probes never carry any.
+func quotedProbeKey(data []byte) string {
+	if len(data) < 20 {
+		return ""
+	}
+	var protocol byte
+	var l4 []byte
+	switch data[0] >> 4 {
+	case 4:
+		headerLen := int(data[0]&0x0f) * 4
+		if len(data) < headerLen+8 {
+			return ""
+		}
+		protocol, l4 = data[9], data[headerLen:]
+	case 6:
+		if len(data) < 40+8 {
+			return ""
+		}
+		protocol, l4 = data[6], data[40:]
+	default:
+		return ""
+	}
+	switch protocol {
+	case 1, 58: // ICMP, ICMPv6
+		return probeKey(TracerouteICMP, int(binary.BigEndian.Uint16(l4[4:6])), int(binary.BigEndian.Uint16(l4[6:8])))
+	case 17: // UDP
+		return probeKey(TracerouteUDP, int(binary.BigEndian.Uint16(l4[0:2])), int(binary.BigEndian.Uint16(l4[2:4])))
+	case 6: // TCP
+		return probeKey(TracerouteTCP, int(binary.BigEndian.Uint16(l4[0:2])), int(binary.BigEndian.Uint16(l4[2:4])))
+	}
+	return ""
+}
+
+// probeKey builds the key probes are matched by: the echo ID and sequence number for ICMP,
+// the source and destination ports for UDP and TCP.
+func probeKey(protocol string, a, b int) string {
+	return fmt.Sprintf("%s:%d:%d", protocol, a, b)
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestUnreachableNote(t *testing.T) {
+	tests := []struct {
+		code           int
+		ipv6, fromDest bool
+		reached        bool
+		note           string
+	}{
+		{code: 3, fromDest: true, reached: true},
+		{code: 3, note: "!P"},
+		{code: 2, fromDest: true, reached: true},
+		{code: 0, note: "!N"},
+		{code: 1, fromDest: true, note: "!H"},
+		{code: 13, note: "!X"},
+		{code: 4, note: "!4"},
+		{code: 4, ipv6: true, fromDest: true, reached: true},
+		{code: 4, ipv6: true, note: "!P"},
+		{code: 0, ipv6: true, note: "!N"},
+		{code: 3, ipv6: true, note: "!H"},
+		{code: 1, ipv6: true, note: "!X"},
+		{code: 2, ipv6: true, note: "!2"},
+	}
+	for _, tt := range tests {
+		note := unreachableNote
+		if tt.ipv6 {
+			note = unreachableNote6
+		}
+		reached, got := note(tt.code, tt.fromDest)
+		if reached != tt.reached || got != tt.note {
+			t.Errorf("code %d ipv6=%v fromDest=%v: got %v %q, want %v %q", tt.code, tt.ipv6, tt.fromDest, reached, got, tt.reached, tt.note)
+		}
+	}
+}
+
+func TestQuotedProbeKey(t *testing.T) {
+	ipv4 := func(protocol byte, l4 ...byte) []byte {
+		header := make([]byte, 20)
+		header[0] = 0x45
+		header[9] = protocol
+		return append(header, l4...)
+	}
+	ipv6 := func(next byte, l4 ...byte) []byte {
+		header := make([]byte, 40)
+		header[0] = 0x60
+		header[6] = next
+		return append(header, l4...)
+	}
+	ports := func(src, dst uint16) []byte {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint16(b[0:], src)
+		binary.BigEndian.PutUint16(b[2:], dst)
+		return b
+	}
+	echo := []byte{8, 0, 0, 0, 0x12, 0x34, 0x00, 0x07}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"UDP", ipv4(17, ports(40000, 33434)...), probeKey(TracerouteUDP, 40000, 33434)},
+		{"TCP", ipv4(6, ports(51000, 443)...), probeKey(TracerouteTCP, 51000, 443)},
+		{"ICMP echo", ipv4(1, echo...), probeKey(TracerouteICMP, 0x1234, 7)},
+		{"ICMPv6 echo", ipv6(58, echo...), probeKey(TracerouteICMP, 0x1234, 7)},
+		{"UDP over IPv6", ipv6(17, ports(40000, 33435)...), probeKey(TracerouteUDP, 40000, 33435)},
+		{"truncated transport header", ipv4(17, 0x9c, 0x40), ""},
+		{"unknown protocol", ipv4(47, ports(1, 2)...), ""},
+		{"not an IP header", append([]byte{0x20}, make([]byte, 40)...), ""},
+		{"too short", []byte{0x45}, ""},
+	}
+	for _, tt := range tests {
+		if got := quotedProbeKey(tt.data); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMiddleboxSuspected(t *testing.T) {
+	tests := []struct {
+		hop, replyTTL int
+		want          bool
+	}{
+		{hop: 12, replyTTL: 53, want: false}, // 11 hops back from a Linux host
+		{hop: 12, replyTTL: 63, want: true},  // 1 hop back: answered close to us
+		{hop: 5, replyTTL: 124, want: false}, // Windows host
+		{hop: 20, replyTTL: 250, want: true},
+		{hop: 3, replyTTL: 64, want: false},
+		{hop: 12, replyTTL: 0, want: false}, // Not captured
+	}
+	for _, tt := range tests {
+		if got := middleboxSuspected(tt.hop, tt.replyTTL); got != tt.want {
+			t.Errorf("middleboxSuspected(%d, %d) = %v, want %v", tt.hop, tt.replyTTL, got, tt.want)
+		}
+	}
+}
+
+func TestNativeTracerouteLoopback(t *testing.T) {
+	opts := TracerouteOptions{MaxHops: 4, ProbesPerHop: 1, Parallel: 2, Wait: time.Second, NoDNS: true}.withDefaults()
+	hops, err := runNativeTraceroute(net.ParseIP("127.0.0.1"), opts)
+	if errors.Is(err, os.ErrPermission) {
+		t.Skip("raw sockets are not permitted:", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hops) != 1 || !hops[0].Reached || hops[0].Unreachable || hops[0].IP != "127.0.0.1" {
+		t.Errorf("hops = %+v, want the destination reached at the first hop", hops)
+	}
+}
+
+func TestNativeTracerouteTCPLoopback(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	openPort := ln.Addr().(*net.TCPAddr).Port
+	closed, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+	defer ln.Close()
+
+	for _, tt := range []struct {
+		port int
+		note string
+	}{{openPort, "open"}, {closedPort, "closed"}} {
+		opts := TracerouteOptions{Protocol: TracerouteTCP, Port: tt.port, MaxHops: 4, ProbesPerHop: 1, Wait: time.Second, NoDNS: true}.withDefaults()
+		hops, err := runNativeTraceroute(net.ParseIP("127.0.0.1"), opts)
+		if errors.Is(err, os.ErrPermission) {
+			t.Skip("raw sockets are not permitted:", err)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Loopback answers arrive with their initial TTL, so they must not look forged
+		if len(hops) != 1 || !hops[0].Reached || hops[0].Note != tt.note {
+			t.Errorf("port %d: hops = %+v, want the destination reached at the first hop and noted %q", tt.port, hops, tt.note)
+		}
+	}
+}
+
+func TestNativeTracerouteIPv6Loopback(t *testing.T) {
+	if ln, err := net.Listen("tcp6", "[::1]:0"); err != nil {
+		t.Skip("IPv6 loopback is not available:", err)
+	} else {
+		ln.Close()
+	}
+	opts := TracerouteOptions{Protocol: TracerouteICMP, MaxHops: 4, ProbesPerHop: 1, Wait: time.Second, NoDNS: true, Family: FamilyIPv6}.withDefaults()
+	hops, err := runNativeTraceroute(net.ParseIP("::1"), opts)
+	if errors.Is(err, os.ErrPermission) {
+		t.Skip("raw sockets are not permitted:", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hops) != 1 || !hops[0].Reached || hops[0].IP != "::1" {
+		t.Errorf("hops = %+v, want ::1 reached at the first hop", hops)
+	}
+}
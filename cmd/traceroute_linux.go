@@ -0,0 +1,10 @@
+//go:build linux
+// +build linux
+
+package cmd
+
+// probeSocketHandle converts the descriptor of a probe socket to the type the syscall package
+// takes for it on this platform.
+func probeSocketHandle(fd uintptr) int {
+	return int(fd)
+}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+import "syscall"
+
+// probeSocketHandle converts the descriptor of a probe socket to the type the syscall package
+// takes for it on this platform.
+func probeSocketHandle(fd uintptr) syscall.Handle {
+	return syscall.Handle(fd)
+}
@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"net"
 	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
@@ -20,18 +22,48 @@ import (
 var TracerouteCmd = &cobra.Command{
 	Use:   "traceroute",
 	Short: "Performs a traceroute to a specified IP address.",
-	Long:  `Executes a traceroute from the current location to a specified IP address and displays detailed hop information.`,
+	Long: `Executes a traceroute from the current location to a specified IP address and displays detailed hop information.
+
+Probes are sent by a built-in implementation using UDP (default), ICMP echo or TCP SYN packets with
+increasing TTLs, which needs raw-socket privileges (root or CAP_NET_RAW, or Administrator on
+Windows). Without them the system's traceroute, tracepath or tracert command is used instead.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Retrieve flags
 		destination := viper.GetString("destination")
-		maxHops := viper.GetInt("maxHops")
-		timeoutSec := viper.GetInt("timeout")
+		opts := TracerouteOptions{
+			MaxHops: viper.GetInt("maxHops"),
+			Timeout: time.Duration(viper.GetInt("timeout")) * time.Second,
+		}
+		opts.Protocol, _ = cmd.Flags().GetString("proto")
+		opts.Port, _ = cmd.Flags().GetInt("port")
+		opts.FirstHop, _ = cmd.Flags().GetInt("first-hop")
+		opts.ProbesPerHop, _ = cmd.Flags().GetInt("probes")
+		opts.Parallel, _ = cmd.Flags().GetInt("parallel")
+		opts.Wait, _ = cmd.Flags().GetDuration("wait")
+		opts.NoDNS, _ = cmd.Flags().GetBool("no-dns")
+		family, err := addressFamilyFlag(cmd)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		opts.Family = family
+
+		continuous, _ := cmd.Flags().GetBool("continuous")
+		cycles, _ := cmd.Flags().GetInt("cycles")
+		if continuous || cycles > 0 {
+			interval, _ := cmd.Flags().GetDuration("interval")
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			runTracerouteMonitor(destination, TracerouteMonitorOptions{TracerouteOptions: opts, Cycles: cycles, Interval: interval}, jsonOutput)
+			return
+		}
 
 		// Execute traceroute with timeout
-		hops, err := RunTraceroute(destination, maxHops, timeoutSec)
+		hops, err := RunTraceroute(destination, opts)
 		if err != nil {
 			fmt.Println("Error:", err)
-			return
+			if len(hops) == 0 {
+				return
+			}
 		}
 
 		// Display traceroute results
@@ -41,31 +73,146 @@ var TracerouteCmd = &cobra.Command{
 
 // TracerouteHop holds details about a single hop in the traceroute.
 type TracerouteHop struct {
-	HopNumber int
-	Hostname  string
-	IP        string
-	RTTs      [3]string
+	HopNumber   int
+	Hostname    string
+	IP          string
+	RTTs        []time.Duration // One per probe; TracerouteNoReply for probes that got no answer
+	Reached     bool            // The destination itself answered at this hop
+	Unreachable bool            // A router reported the destination unreachable; Note says why
+	Note        string          // Annotation such as !H (host unreachable) or !X (prohibited)
+}
+
+// TracerouteOptions controls how the path to a destination is probed.
+type TracerouteOptions struct {
+	Protocol     string        // TracerouteUDP (default), TracerouteICMP or TracerouteTCP
+	Port         int           // Destination port: the first port for UDP, the target port for TCP
+	FirstHop     int           // TTL of the first hop probed
+	MaxHops      int           // Largest TTL probed
+	ProbesPerHop int           // Number of probes sent to each hop
+	Parallel     int           // Number of hops probed at the same time
+	Wait         time.Duration // How long to wait for the answer to each probe
+	Timeout      time.Duration // Limit for the whole traceroute
+	NoDNS        bool          // Do not resolve hop addresses to host names
+	Family       string        // FamilyIPv4 or FamilyIPv6; FamilyAny traces names over IPv4
+}
+
+// Default destination ports: the traditional traceroute UDP base port and HTTP for TCP probes.
+const (
+	tracerouteUDPPort = 33434
+	tracerouteTCPPort = 80
+)
+
+// withDefaults fills in zero-valued options with the command's defaults.
+func (opts TracerouteOptions) withDefaults() TracerouteOptions {
+	opts.Protocol = strings.ToLower(opts.Protocol)
+	if opts.Protocol == "" {
+		opts.Protocol = TracerouteUDP
+	}
+	if opts.Port <= 0 {
+		opts.Port = tracerouteUDPPort
+		if opts.Protocol == TracerouteTCP {
+			opts.Port = tracerouteTCPPort
+		}
+	}
+	if opts.FirstHop <= 0 {
+		opts.FirstHop = 1
+	}
+	if opts.MaxHops <= 0 {
+		opts.MaxHops = 30
+	}
+	if opts.ProbesPerHop <= 0 {
+		opts.ProbesPerHop = 3
+	}
+	if opts.Parallel <= 0 {
+		opts.Parallel = 16
+	}
+	if opts.Wait <= 0 {
+		opts.Wait = 3 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	return opts
+}
+
+// RunTraceroute traces the route to destination with the built-in prober. When raw sockets are
+// not available it falls back to the system's traceroute tools.
+func RunTraceroute(destination string, opts TracerouteOptions) ([]TracerouteHop, error) {
+	opts = opts.withDefaults()
+	switch opts.Protocol {
+	case TracerouteUDP, TracerouteICMP, TracerouteTCP:
+	default:
+		return nil, fmt.Errorf("unknown probe protocol %q (use udp, icmp or tcp)", opts.Protocol)
+	}
+	if opts.FirstHop > opts.MaxHops {
+		return nil, fmt.Errorf("first hop %d is beyond the maximum of %d hops", opts.FirstHop, opts.MaxHops)
+	}
+
+	dst, err := tracerouteAddress(destination, opts.Family)
+	if err != nil {
+		return nil, err
+	}
+
+	hops, err := runNativeTraceroute(dst, opts)
+	if err != nil && len(hops) == 0 {
+		utils.TerminalColor(fmt.Sprintf("Warning: built-in traceroute unavailable (%v); using the system traceroute command.", err), utils.Warn)
+		// Trace the resolved address so the system tool uses the same address family
+		return GetTraceroute(dst.String(), opts.MaxHops, int(opts.Timeout/time.Second))
+	}
+	if !opts.NoDNS {
+		resolveHopNames(hops)
+	}
+	return hops, err
 }
 
-// RunTraceroute executes the traceroute command with a timeout and retrieves the hop information.
-func RunTraceroute(destination string, maxHops int, timeoutSec int) ([]TracerouteHop, error) {
-	return GetTraceroute(destination, maxHops, timeoutSec)
+// tracerouteAddress resolves the destination to trace. Like the classic traceroute, host names
+// are traced over IPv4 unless IPv6 is requested; literal addresses are traced in their own family.
+func tracerouteAddress(destination string, family string) (net.IP, error) {
+	if family == FamilyAny {
+		family = FamilyIPv4
+		if ip := net.ParseIP(strings.Trim(destination, "[]")); ip != nil && ip.To4() == nil {
+			family = FamilyIPv6
+		}
+	}
+	targets, err := ParseScanTargets(destination, family)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(targets[0].Address, "%") {
+		return nil, fmt.Errorf("link-local destination %s cannot be traced", targets[0].Address)
+	}
+	return net.ParseIP(targets[0].Address), nil
 }
 
 // PrintTraceroute displays the traceroute hops in a formatted table.
 func PrintTraceroute(hops []TracerouteHop) {
+	probes := 0
+	for _, hop := range hops {
+		probes = max(probes, len(hop.RTTs))
+	}
+
 	t := utils.Table("DarkSimple", "tracerouteCmd")
-	t.AppendHeader(table.Row{"Hop", "Hostname", "IP Address", "RTT1 (ms)", "RTT2 (ms)", "RTT3 (ms)"})
+	header := table.Row{"Hop", "Hostname", "IP Address"}
+	for i := 1; i <= probes; i++ {
+		header = append(header, fmt.Sprintf("RTT%d (ms)", i))
+	}
+	header = append(header, "Note")
+	t.AppendHeader(header)
 
 	for _, hop := range hops {
-		t.AppendRow(table.Row{
-			hop.HopNumber,
-			hop.Hostname,
-			hop.IP,
-			hop.RTTs[0],
-			hop.RTTs[1],
-			hop.RTTs[2],
-		})
+		row := table.Row{hop.HopNumber, valueOrDash(hop.Hostname), valueOrDash(hop.IP)}
+		for i := 0; i < probes; i++ {
+			switch {
+			case i >= len(hop.RTTs):
+				row = append(row, "-")
+			case hop.RTTs[i] == TracerouteNoReply:
+				row = append(row, "*")
+			default:
+				row = append(row, fmt.Sprintf("%.3f", float64(hop.RTTs[i])/float64(time.Millisecond)))
+			}
+		}
+		row = append(row, hop.Note)
+		t.AppendRow(row)
 	}
 
 	fmt.Println()
@@ -80,6 +227,19 @@ func init() {
 	TracerouteCmd.PersistentFlags().StringP("destination", "d", "google.com", "Destination IP address or hostname for traceroute")
 	TracerouteCmd.PersistentFlags().IntP("maxHops", "m", 30, "Maximum number of hops to trace")
 	TracerouteCmd.PersistentFlags().IntP("timeout", "t", 30, "Timeout in seconds for the traceroute command")
+	TracerouteCmd.Flags().StringP("proto", "P", TracerouteUDP, "Probe protocol: udp, icmp or tcp")
+	TracerouteCmd.Flags().IntP("port", "p", 0, "Destination port (default: 33434 for udp, incremented per probe; 80 for tcp)")
+	TracerouteCmd.Flags().IntP("first-hop", "f", 1, "TTL of the first hop to probe")
+	TracerouteCmd.Flags().IntP("probes", "q", 3, "Number of probes per hop")
+	TracerouteCmd.Flags().Int("parallel", 16, "Number of hops to probe at the same time")
+	TracerouteCmd.Flags().DurationP("wait", "w", 3*time.Second, "Time to wait for the answer to each probe")
+	TracerouteCmd.Flags().BoolP("no-dns", "n", false, "Do not resolve hop addresses to host names")
+	TracerouteCmd.Flags().BoolP("ipv4", "4", false, "Trace over IPv4 (the default for host names)")
+	TracerouteCmd.Flags().BoolP("ipv6", "6", false, "Trace over IPv6")
+	TracerouteCmd.Flags().Bool("continuous", false, "Keep probing every hop and show live loss and latency statistics, like mtr")
+	TracerouteCmd.Flags().Int("cycles", 0, "Number of cycles to run in continuous mode before printing a report (default: until interrupted)")
+	TracerouteCmd.Flags().Duration("interval", time.Second, "Time between cycles in continuous mode")
+	TracerouteCmd.Flags().Bool("json", false, "Print the final continuous-mode report as JSON")
 
 	// Bind flags to viper
 	viper.BindPFlag("destination", TracerouteCmd.PersistentFlags().Lookup("destination"))
@@ -87,7 +247,39 @@ func init() {
 	viper.BindPFlag("timeout", TracerouteCmd.PersistentFlags().Lookup("timeout"))
 }
 
-// GetTraceroute retrieves traceroute information based on the operating system and enforces the timeout.
+// resolveHopNames looks up the host name of every hop concurrently, leaving hops whose address
+// has no name (or does not answer in time) without one.
+func resolveHopNames(hops []TracerouteHop) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := range hops {
+		if hops[i].IP == "" || hops[i].Hostname != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(hop *TracerouteHop) {
+			defer wg.Done()
+			names, err := net.DefaultResolver.LookupAddr(ctx, hop.IP)
+			if err == nil && len(names) > 0 {
+				hop.Hostname = strings.TrimSuffix(names[0], ".")
+			}
+		}(&hops[i])
+	}
+	wg.Wait()
+}
+
+// valueOrDash returns value, or "-" when it is empty.
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// GetTraceroute retrieves traceroute information from the system's traceroute tools based on the
+// operating system and enforces the timeout.
 func GetTraceroute(destination string, maxHops int, timeoutSec int) ([]TracerouteHop, error) {
 	if runtime.GOOS == "windows" {
 		return getTracerouteWindows(destination, maxHops, timeoutSec)
@@ -97,8 +289,6 @@ func GetTraceroute(destination string, maxHops int, timeoutSec int) ([]Tracerout
 
 // getTracerouteUnix retrieves traceroute information on Unix-based systems (Linux, macOS).
 func getTracerouteUnix(destination string, maxHops int, timeoutSec int) ([]TracerouteHop, error) {
-	var hops []TracerouteHop
-
 	// Determine the traceroute command based on availability
 	cmdName := "traceroute"
 	if _, err := exec.LookPath(cmdName); err != nil {
@@ -109,13 +299,8 @@ func getTracerouteUnix(destination string, maxHops int, timeoutSec int) ([]Trace
 		}
 	}
 
-	// Prepare the command arguments
-	var args []string
-	if cmdName == "traceroute" {
-		args = []string{"-m", strconv.Itoa(maxHops), destination}
-	} else { // tracepath
-		args = []string{destination, "-n"} // '-n' to skip DNS resolution for faster results
-	}
+	// Prepare the command arguments; '-n' skips DNS resolution for faster results
+	args := []string{"-n", "-m", strconv.Itoa(maxHops), destination}
 
 	// Create a context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
@@ -127,28 +312,33 @@ func getTracerouteUnix(destination string, maxHops int, timeoutSec int) ([]Trace
 
 	// Check if the context was canceled (timeout)
 	if ctx.Err() == context.DeadlineExceeded {
-		return hops, fmt.Errorf("traceroute command timed out after %d seconds", timeoutSec)
+		return nil, fmt.Errorf("%s command timed out after %d seconds", cmdName, timeoutSec)
 	}
 
 	if err != nil {
-		return hops, fmt.Errorf("failed to execute '%s' command: %v", cmdName, err)
+		return nil, fmt.Errorf("failed to execute '%s' command: %v", cmdName, err)
 	}
 
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	lineNumber := 0
+	return parseTracerouteUnix(string(output))
+}
+
+// parseTracerouteUnix parses the output of traceroute or tracepath, for example:
+//
+//	1  192.168.1.1  0.512 ms  0.456 ms  0.431 ms
+//	2  * * *
+//	1:  192.168.1.1   0.512ms
+//	2:  no reply
+func parseTracerouteUnix(output string) ([]TracerouteHop, error) {
+	var hops []TracerouteHop
+	scanner := bufio.NewScanner(strings.NewReader(output))
 	currentHop := TracerouteHop{}
 
 	for scanner.Scan() {
 		line := scanner.Text()
-		lineNumber++
-
-		// Skip the first line which typically contains the destination info
-		if strings.HasPrefix(line, "traceroute") || strings.HasPrefix(line, "tracepath") {
-			continue
-		}
 
-		// Handle lines like "1?: [LOCALHOST] pmtu 1500"
-		if strings.Contains(line, "pmtu") {
+		// Skip the first line which typically contains the destination info, and lines
+		// like "1?: [LOCALHOST] pmtu 1500"
+		if strings.HasPrefix(line, "traceroute") || strings.HasPrefix(line, "tracepath") || strings.Contains(line, "pmtu") {
 			continue
 		}
 
@@ -158,53 +348,43 @@ func getTracerouteUnix(destination string, maxHops int, timeoutSec int) ([]Trace
 		}
 
 		// Parse hop number
-		hopNumStr := fields[0]
-		hopNumStr = strings.TrimSuffix(hopNumStr, "?")
-		hopNumStr = strings.TrimSuffix(hopNumStr, ":")
+		hopNumStr := strings.TrimSuffix(strings.TrimSuffix(fields[0], ":"), "?")
 		hopNum, err := strconv.Atoi(hopNumStr)
 		if err != nil {
 			continue // Skip lines that don't start with a hop number
 		}
 
-		// Initialize or reset TracerouteHop
+		// tracepath prints one line per probe, so lines for the same hop are merged
 		if currentHop.HopNumber != hopNum {
-			// If we are starting a new hop, append the previous one if it exists
 			if currentHop.HopNumber != 0 {
 				hops = append(hops, currentHop)
 			}
-			currentHop = TracerouteHop{
-				HopNumber: hopNum,
-				Hostname:  "-",
-				IP:        "-",
-				RTTs:      [3]string{"-", "-", "-"},
-			}
+			currentHop = TracerouteHop{HopNumber: hopNum}
 		}
 
-		// Determine if the line contains 'no reply' indicating a timeout
 		if strings.Contains(line, "no reply") {
-			currentHop.RTTs[0] = "*"
-			currentHop.RTTs[1] = "*"
-			currentHop.RTTs[2] = "*"
+			currentHop.RTTs = append(currentHop.RTTs, TracerouteNoReply)
 			continue
 		}
 
-		// Extract hostname and IP
-		// Check if IP is in parentheses
-		if strings.Contains(line, "(") && strings.Contains(line, ")") {
-			parts := strings.SplitN(line, "(", 2)
-			currentHop.Hostname = strings.TrimSpace(parts[0])
-			ipPart := strings.SplitN(parts[1], ")", 2)[0]
-			currentHop.IP = ipPart
-			// Extract RTTs
-			rtts := extractRTTs(parts[1])
-			currentHop.RTTs = rtts
-		} else {
-			// No hostname, only IP
-			currentHop.Hostname = "-"
-			currentHop.IP = fields[1]
-			// Extract RTTs
-			rtts := extractRTTs(line)
-			currentHop.RTTs = rtts
+		for i := 1; i < len(fields); i++ {
+			field := fields[i]
+			switch {
+			case field == "*":
+				currentHop.RTTs = append(currentHop.RTTs, TracerouteNoReply)
+			case strings.HasPrefix(field, "(") && strings.HasSuffix(field, ")"):
+				// "hostname (ip)" form used when names are resolved
+				currentHop.Hostname = fields[i-1]
+				currentHop.IP = strings.Trim(field, "()")
+			case strings.HasPrefix(field, "!"):
+				currentHop.Note = field
+			case net.ParseIP(field) != nil && currentHop.IP == "":
+				currentHop.IP = field
+			default:
+				if rtt, ok := parseRTTField(field, fields, i); ok {
+					currentHop.RTTs = append(currentHop.RTTs, rtt)
+				}
+			}
 		}
 	}
 
@@ -222,93 +402,88 @@ func getTracerouteUnix(destination string, maxHops int, timeoutSec int) ([]Trace
 
 // getTracerouteWindows retrieves traceroute information on Windows systems.
 func getTracerouteWindows(destination string, maxHops int, timeoutSec int) ([]TracerouteHop, error) {
-	var hops []TracerouteHop
-
-	// Windows uses 'tracert' command
-	// '/h' specifies the maximum number of hops
-	// '/w' specifies the timeout in milliseconds
-	cmd := exec.Command("tracert", "-h", strconv.Itoa(maxHops), destination)
-
 	// Create a context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
 	defer cancel()
 
-	// Execute the command with context
-	cmd = exec.CommandContext(ctx, "tracert", "-h", strconv.Itoa(maxHops), destination)
+	// Windows uses 'tracert' command; '-h' specifies the maximum number of hops
+	cmd := exec.CommandContext(ctx, "tracert", "-h", strconv.Itoa(maxHops), destination)
 
 	// Capture combined output (stdout and stderr) for better debugging
 	output, err := cmd.CombinedOutput()
 
 	// Check if the context was canceled (timeout)
 	if ctx.Err() == context.DeadlineExceeded {
-		return hops, fmt.Errorf("tracert command timed out after %d seconds", timeoutSec)
+		return nil, fmt.Errorf("tracert command timed out after %d seconds", timeoutSec)
 	}
 
 	if err != nil {
-		return hops, fmt.Errorf("failed to execute 'tracert' command: %v", err)
+		return nil, fmt.Errorf("failed to execute 'tracert' command: %v", err)
 	}
 
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-
-	for scanner.Scan() {
-		line := scanner.Text()
+	return parseTracert(string(output))
+}
 
-		// Skip header lines
-		if strings.HasPrefix(line, "Tracing route to") || strings.HasPrefix(line, "over a maximum of") {
-			continue
-		}
+// parseTracert parses the output of tracert. Each hop line holds three round-trip times, each
+// either "<n> ms", "<1 ms" or "*", followed by the host, for example:
+//
+//	2     2 ms     *        3 ms  10.0.0.1
+//	3    12 ms    11 ms    11 ms  edge router.example.net [203.0.113.7]
+//	4     *        *        *     Request timed out.
+func parseTracert(output string) ([]TracerouteHop, error) {
+	var hops []TracerouteHop
+	scanner := bufio.NewScanner(strings.NewReader(output))
 
-		fields := strings.Fields(line)
-		if len(fields) < 5 {
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
 			continue
 		}
 
 		// Parse hop number
-		hopNumStr := fields[0]
-		hopNum, err := strconv.Atoi(hopNumStr)
+		hopNum, err := strconv.Atoi(fields[0])
 		if err != nil {
-			continue // Skip lines that don't start with a hop number
+			continue // Skip header lines and lines that don't start with a hop number
 		}
-
-		hop := TracerouteHop{
-			HopNumber: hopNum,
-			Hostname:  "-",
-			IP:        "-",
-			RTTs:      [3]string{"-", "-", "-"},
-		}
-
-		// Check for timeout
-		if strings.Contains(line, "Request timed out.") {
-			hop.RTTs[0] = "*"
-			hop.RTTs[1] = "*"
-			hop.RTTs[2] = "*"
-			hops = append(hops, hop)
-			continue
+		hop := TracerouteHop{HopNumber: hopNum}
+
+		// Read the round-trip times: "*" or a number followed by "ms"
+		i := 1
+		for i < len(fields) && len(hop.RTTs) < 3 {
+			if fields[i] == "*" {
+				hop.RTTs = append(hop.RTTs, TracerouteNoReply)
+				i++
+				continue
+			}
+			rtt, ok := parseRTTField(fields[i], fields, i)
+			if !ok {
+				break
+			}
+			hop.RTTs = append(hop.RTTs, rtt)
+			i++
+			if i < len(fields) && fields[i] == "ms" {
+				i++
+			}
 		}
 
-		// Extract RTTs and IP/Hostname
-		// Example line: "  2     2 ms     2 ms     2 ms  10.0.0.1"
-		rtt1 := fields[1]
-		rtt2 := fields[2]
-		rtt3 := fields[3]
-		hostIP := fields[4]
-
-		// Attempt to separate hostname and IP if available
-		hostname := "-"
-		ip := hostIP
-		if strings.Contains(hostIP, "(") && strings.Contains(hostIP, ")") {
-			// Hostname and IP are present
-			parts := strings.SplitN(hostIP, "(", 2)
-			hostname = strings.TrimSpace(parts[0])
-			ip = strings.TrimSuffix(strings.TrimSpace(parts[1]), ")")
+		// The rest of the line is the host: "Request timed out.", "ip", or "name [ip]" where the
+		// name may itself contain spaces
+		host := strings.Join(fields[i:], " ")
+		switch {
+		case host == "" || strings.HasPrefix(host, "Request timed out"):
+		case strings.HasSuffix(host, "]") && strings.Contains(host, "["):
+			open := strings.LastIndex(host, "[")
+			hop.Hostname = strings.TrimSpace(host[:open])
+			hop.IP = strings.TrimSuffix(host[open+1:], "]")
+		default:
+			if ip, note, ok := strings.Cut(host, " reports: "); ok {
+				hop.IP = ip
+				hop.Note = note
+			} else {
+				hop.IP = host
+			}
 		}
 
-		hop.Hostname = hostname
-		hop.IP = ip
-		hop.RTTs[0] = strings.TrimSuffix(rtt1, "ms")
-		hop.RTTs[1] = strings.TrimSuffix(rtt2, "ms")
-		hop.RTTs[2] = strings.TrimSuffix(rtt3, "ms")
-
 		hops = append(hops, hop)
 	}
 
@@ -319,25 +494,18 @@ func getTracerouteWindows(destination string, maxHops int, timeoutSec int) ([]Tr
 	return hops, nil
 }
 
-// extractRTTs parses RTT values from a traceroute line.
-// It returns an array of three RTTs as strings.
-func extractRTTs(line string) [3]string {
-	var rtts [3]string
-	// Split the line by "ms" to extract RTT values
-	parts := strings.Split(line, "ms")
-	count := 0
-	for i := 0; i < len(parts)-1 && count < 3; i++ {
-		rtt := strings.TrimSpace(parts[i])
-		if rtt == "*" || rtt == "?" || rtt == "no" {
-			rtts[count] = "*"
-		} else {
-			rtts[count] = rtt
-		}
-		count++
+// parseRTTField parses a round-trip time such as "0.512" followed by a separate "ms" field,
+// "0.512ms" or "<1" (tracert's below-a-millisecond value, reported as 1 ms).
+func parseRTTField(field string, fields []string, i int) (time.Duration, bool) {
+	value := strings.TrimPrefix(field, "<")
+	if strings.HasSuffix(value, "ms") {
+		value = strings.TrimSuffix(value, "ms")
+	} else if i+1 >= len(fields) || fields[i+1] != "ms" {
+		return 0, false
 	}
-	// Fill remaining RTTs with "-"
-	for ; count < 3; count++ {
-		rtts[count] = "-"
+	ms, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
 	}
-	return rtts
+	return time.Duration(ms * float64(time.Millisecond)), true
 }
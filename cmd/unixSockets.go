@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mwiater/ghost/utils"
+)
+
+// UnixSocket is a Unix domain socket and the process that owns it.
+type UnixSocket struct {
+	Path     string `json:"path,omitempty"`    // File system path, or @name for abstract sockets; empty when unnamed
+	Abstract bool   `json:"abstract"`          // The name lives in the abstract namespace, not the file system
+	Type     string `json:"type"`              // stream, dgram or seqpacket
+	State    string `json:"state"`             // LISTENING, CONNECTED, UNCONNECTED, CONNECTING or DISCONNECTING
+	Inode    uint64 `json:"inode"`             // Socket inode, as in /proc/<pid>/fd
+	PID      int32  `json:"pid,omitempty"`     // 0 when the owner is unknown
+	Process  string `json:"process,omitempty"` // Name of the owning process
+	User     string `json:"user,omitempty"`    // User the owning process runs as
+}
+
+// unixSocketTypes maps socket types of /proc/net/unix to their names.
+var unixSocketTypes = map[uint64]string{1: "stream", 2: "dgram", 5: "seqpacket"}
+
+// unixSocketStates maps the socket states (SS_*) of /proc/net/unix to names.
+var unixSocketStates = map[uint64]string{1: "UNCONNECTED", 2: "CONNECTING", 3: "CONNECTED", 4: "DISCONNECTING"}
+
+// unixAcceptCon is the __SO_ACCEPTCON flag of listening sockets.
+const unixAcceptCon = 0x10000
+
+// GetUnixSockets lists the Unix domain sockets of the system with their owning processes.
+// Unix sockets are only listed on Linux.
+func GetUnixSockets() ([]UnixSocket, error) {
+	sockets, err := readUnixSockets()
+	if err != nil {
+		return nil, err
+	}
+	owners := newProcessOwners()
+	for i := range sockets {
+		if sockets[i].PID > 0 {
+			sockets[i].Process = owners.name(sockets[i].PID)
+			sockets[i].User = owners.user(sockets[i].PID)
+		}
+	}
+	return sockets, nil
+}
+
+// parseProcNetUnix parses /proc/net/unix:
+//
+//	Num       RefCount Protocol Flags    Type St Inode Path
+//	0000000000000000: 00000002 00000000 00010000 0001 01 23456 /run/docker.sock
+//
+// Abstract names are shown with a leading @; unnamed sockets have no path.
+func parseProcNetUnix(r io.Reader) ([]UnixSocket, error) {
+	var sockets []UnixSocket
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) < 7 || fields[0] == "Num" {
+			continue
+		}
+		flags, err1 := strconv.ParseUint(fields[3], 16, 32)
+		socketType, err2 := strconv.ParseUint(fields[4], 16, 16)
+		state, err3 := strconv.ParseUint(fields[5], 16, 8)
+		inode, err4 := strconv.ParseUint(fields[6], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			return nil, fmt.Errorf("invalid unix socket line %q", line)
+		}
+		socket := UnixSocket{
+			Type:  unixSocketTypes[socketType],
+			State: unixSocketStates[state],
+			Inode: inode,
+		}
+		if socket.Type == "" {
+			socket.Type = strconv.FormatUint(socketType, 10)
+		}
+		if flags&unixAcceptCon != 0 {
+			socket.State = "LISTENING"
+		}
+		if len(fields) > 7 {
+			// The path is the rest of the line and may contain spaces
+			socket.Path = skipFields(line, 7)
+			socket.Abstract = strings.HasPrefix(socket.Path, "@")
+		}
+		sockets = append(sockets, socket)
+	}
+	return sockets, scanner.Err()
+}
+
+// skipFields returns what follows the first n space-separated fields of line.
+func skipFields(line string, n int) string {
+	rest := line
+	for i := 0; i < n; i++ {
+		rest = strings.TrimLeft(rest, " ")
+		j := strings.IndexByte(rest, ' ')
+		if j < 0 {
+			return ""
+		}
+		rest = rest[j:]
+	}
+	return strings.TrimPrefix(rest, " ")
+}
+
+// parseSocketInode returns the inode of a "socket:[12345]" file descriptor link.
+func parseSocketInode(link string) (uint64, bool) {
+	if !strings.HasPrefix(link, "socket:[") || !strings.HasSuffix(link, "]") {
+		return 0, false
+	}
+	inode, err := strconv.ParseUint(link[len("socket:["):len(link)-1], 10, 64)
+	return inode, err == nil
+}
+
+// connection describes the socket as a Connection, so that netstat filters and sort keys
+// apply to it.
+func (s UnixSocket) connection() Connection {
+	return Connection{Protocol: "UNIX", LocalAddress: s.Path, State: s.State, PID: s.PID, Process: s.Process, User: s.User}
+}
+
+// FilterUnixSockets returns the sockets that pass the filter; port and remote filters match no
+// Unix socket.
+func FilterUnixSockets(sockets []UnixSocket, filter NetstatFilter) []UnixSocket {
+	var filtered []UnixSocket
+	for _, socket := range sockets {
+		if filter.Match(socket.connection()) {
+			filtered = append(filtered, socket)
+		}
+	}
+	return filtered
+}
+
+// SortUnixSockets sorts sockets in place by a netstat sort key; "local" sorts by path.
+func SortUnixSockets(sockets []UnixSocket, key string) error {
+	compare, ok := netstatSortKeys[strings.ToLower(key)]
+	if !ok {
+		return fmt.Errorf("invalid sort key %q (use proto, local, remote, port, state, pid, process or user)", key)
+	}
+	sort.SliceStable(sockets, func(i, j int) bool {
+		a, b := sockets[i], sockets[j]
+		if c := compare(a.connection(), b.connection()); c != 0 {
+			return c < 0
+		}
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c < 0
+		}
+		return a.Inode < b.Inode
+	})
+	return nil
+}
+
+// PrintUnixSockets displays the Unix domain sockets in a formatted table.
+func PrintUnixSockets(sockets []UnixSocket) {
+	t := utils.Table("DarkSimple", "Unix Domain Sockets")
+	t.AppendHeader(table.Row{"Path", "Type", "State", "Inode", "PID", "Process", "User"})
+	for _, socket := range sockets {
+		pid := "-"
+		if socket.PID > 0 {
+			pid = strconv.Itoa(int(socket.PID))
+		}
+		t.AppendRow(table.Row{
+			valueOrDash(socket.Path),
+			socket.Type,
+			socket.State,
+			socket.Inode,
+			pid,
+			valueOrDash(socket.Process),
+			valueOrDash(socket.User),
+		})
+	}
+	fmt.Println()
+	t.Render()
+	fmt.Printf("Netstat complete: %d Unix socket(s).\n", len(sockets))
+}
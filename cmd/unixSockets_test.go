@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProcNetUnix(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "netstat", "unix"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sockets, err := parseProcNetUnix(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []UnixSocket{
+		{Path: "/run/docker.sock", Type: "stream", State: "LISTENING", Inode: 185387},
+		{Type: "stream", State: "CONNECTED", Inode: 194223},
+		{Path: "/run/systemd/journal/stdout", Type: "stream", State: "CONNECTED", Inode: 912},
+		{Path: "@/tmp/.X11-unix/X0", Abstract: true, Type: "seqpacket", State: "LISTENING", Inode: 20001},
+		{Path: "/var/lib/app data/my socket", Type: "dgram", State: "UNCONNECTED", Inode: 20002},
+		{Type: "3", State: "CONNECTING", Inode: 20003}, // SOCK_RAW has no name here
+	}
+	if !reflect.DeepEqual(sockets, want) {
+		t.Errorf("sockets =\n%+v\nwant\n%+v", sockets, want)
+	}
+
+	if _, err := parseProcNetUnix(strings.NewReader("0000: 00000002 00000000 zz 0001 01 1 /x\n")); err == nil {
+		t.Error("a line with invalid flags was accepted")
+	}
+}
+
+func TestSkipFields(t *testing.T) {
+	tests := []struct {
+		line string
+		n    int
+		want string
+	}{
+		{"a b c d", 2, "c d"},
+		// Only the separating space is dropped, so a path may start with spaces
+		{"a  b   c  d", 2, "  c  d"},
+		{"   a b c", 1, "b c"},
+		{"a b", 2, ""},
+		{"a b ", 2, ""},
+		{"a b  c", 0, "a b  c"},
+		{"0000: 00000002 00000000 00010000 0001 01   912 /path with spaces", 7, "/path with spaces"},
+	}
+	for _, tt := range tests {
+		if got := skipFields(tt.line, tt.n); got != tt.want {
+			t.Errorf("skipFields(%q, %d) = %q, want %q", tt.line, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestParseSocketInode(t *testing.T) {
+	if inode, ok := parseSocketInode("socket:[185387]"); !ok || inode != 185387 {
+		t.Errorf("parseSocketInode = %d, %v", inode, ok)
+	}
+	for _, link := range []string{"pipe:[185387]", "socket:[]", "/dev/null", "socket:[12"} {
+		if _, ok := parseSocketInode(link); ok {
+			t.Errorf("parseSocketInode(%q) took it for a socket", link)
+		}
+	}
+}
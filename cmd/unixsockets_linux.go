@@ -0,0 +1,56 @@
+//go:build linux
+// +build linux
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// readUnixSockets reads /proc/net/unix and finds the process holding each socket.
+func readUnixSockets() ([]UnixSocket, error) {
+	f, err := os.Open("/proc/net/unix")
+	if err != nil {
+		return nil, fmt.Errorf("reading unix sockets: %w", err)
+	}
+	defer f.Close()
+	sockets, err := parseProcNetUnix(f)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := socketInodeOwners()
+	for i := range sockets {
+		sockets[i].PID = owners[sockets[i].Inode]
+	}
+	return sockets, nil
+}
+
+// socketInodeOwners maps socket inodes to the process that holds them open, scanning the file
+// descriptors in /proc/<pid>/fd. A socket shared by several processes (e.g. after fork) is
+// attributed to the one with the lowest PID. Processes that cannot be inspected are skipped.
+func socketInodeOwners() map[uint64]int32 {
+	owners := make(map[uint64]int32)
+	links, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, link := range links {
+		target, err := os.Readlink(link)
+		if err != nil {
+			continue
+		}
+		inode, ok := parseSocketInode(target)
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(filepath.Dir(link))))
+		if err != nil {
+			continue
+		}
+		if owner, seen := owners[inode]; !seen || int32(pid) < owner {
+			owners[inode] = int32(pid)
+		}
+	}
+	return owners
+}
@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package cmd
+
+import "fmt"
+
+// readUnixSockets is not available on Windows, which offers no list of its AF_UNIX sockets.
+func readUnixSockets() ([]UnixSocket, error) {
+	return nil, fmt.Errorf("listing Unix domain sockets is only available on Linux")
+}
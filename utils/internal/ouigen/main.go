@@ -0,0 +1,128 @@
+// Command ouigen regenerates the embedded MAC vendor database (utils/oui.txt) from the
+// IEEE registration authority listings. Run it through go generate:
+//
+//	go generate ./utils
+//
+// Without arguments the listings are downloaded. Previously downloaded CSV files can be given
+// as arguments instead, e.g. on a machine without internet access:
+//
+//	go run ./utils/internal/ouigen -o utils/oui.txt oui.csv mam.csv oui36.csv
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// registries lists the IEEE assignment files: MA-L (24-bit), MA-M (28-bit) and MA-S (36-bit) prefixes.
+var registries = []string{
+	"https://standards-oui.ieee.org/oui/oui.csv",
+	"https://standards-oui.ieee.org/oui28/mam.csv",
+	"https://standards-oui.ieee.org/oui36/oui36.csv",
+}
+
+func main() {
+	output := flag.String("o", "oui.txt", "File to write the vendor database to")
+	flag.Parse()
+
+	entries := make(map[string]string)
+	var sources []string
+	if flag.NArg() > 0 {
+		for _, path := range flag.Args() {
+			if err := readRegistryFile(path, entries); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			sources = append(sources, filepath.Base(path))
+		}
+	} else {
+		client := &http.Client{Timeout: 2 * time.Minute}
+		for _, url := range registries {
+			if err := fetchRegistry(client, url, entries); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			sources = append(sources, url[strings.LastIndex(url, "/")+1:])
+		}
+	}
+
+	prefixes := make([]string, 0, len(entries))
+	for prefix := range entries {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# MAC address vendor prefixes from the IEEE registration authority (%s).\n", strings.Join(sources, ", "))
+	fmt.Fprintf(&b, "# Generated by utils/internal/ouigen on %s; do not edit by hand.\n", time.Now().UTC().Format("2006-01-02"))
+	fmt.Fprintf(&b, "# Format: <hex prefix (6, 7 or 9 digits)><TAB><organization>\n")
+	for _, prefix := range prefixes {
+		fmt.Fprintf(&b, "%s\t%s\n", prefix, entries[prefix])
+	}
+	if err := os.WriteFile(*output, []byte(b.String()), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %d prefixes to %s\n", len(prefixes), *output)
+}
+
+// fetchRegistry downloads one IEEE CSV file and adds its assignments to entries.
+func fetchRegistry(client *http.Client, url string, entries map[string]string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	return readRegistry(resp.Body, url, entries)
+}
+
+// readRegistryFile adds the assignments of a downloaded IEEE CSV file to entries.
+func readRegistryFile(path string, entries map[string]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return readRegistry(f, path, entries)
+}
+
+// readRegistry parses one IEEE CSV listing and adds its assignments to entries.
+// Columns are: Registry, Assignment, Organization Name, Organization Address.
+func readRegistry(r io.Reader, source string, entries map[string]string) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", source, err)
+		}
+		if header {
+			header = false
+			continue
+		}
+		if len(record) < 3 {
+			continue
+		}
+		prefix := strings.ToUpper(strings.TrimSpace(record[1]))
+		name := strings.Join(strings.Fields(record[2]), " ")
+		if prefix == "" || name == "" {
+			continue
+		}
+		entries[prefix] = name
+	}
+}
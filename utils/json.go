@@ -0,0 +1,59 @@
+// Package utils provides utilities for interacting with the terminal and formatting output.
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FindingsExitCode is the exit status of commands that ran successfully but found something a
+// script should react to, e.g. changed ports, new devices or unreachable ports, so cron jobs
+// can alert on it.
+const FindingsExitCode = 2
+
+// PrintJSON writes v to stdout as indented JSON so results can be piped into other tools.
+func PrintJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, "Error encoding JSON:", err)
+	}
+}
+
+// ReadJSONFile decodes the JSON file at path into v. A missing file leaves v unchanged, so
+// stores start out empty on the first run.
+func ReadJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid JSON in %s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteJSONFile writes v to path as indented JSON, creating the directory if needed. The data
+// is written to a temporary file first and renamed, so readers never see a partial file.
+func WriteJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:generate go run ./internal/ouigen -o oui.txt
+
+// embeddedOUI is the vendor database compiled into the binary. Each line holds a hex
+// prefix of 6, 7 or 9 digits (IEEE MA-L, MA-M and MA-S assignments), a tab and the organization.
+// The copy in the repository holds only the MA-L prefixes of 2020-07-21; see its header.
+//
+//go:embed oui.txt
+var embeddedOUI string
+
+// OUIFileEnv names the environment variable that points at a vendor database to use instead
+// of the embedded one.
+const OUIFileEnv = "GHOST_OUI_FILE"
+
+var (
+	ouiOnce    sync.Once
+	ouiVendors map[string]string
+	ouiFile    string
+	ouiErr     error
+)
+
+// SetOUIFile makes vendor lookups use the database at path instead of the embedded one. The file
+// may use the embedded format or be one of the IEEE CSV listings (oui.csv, mam.csv, oui36.csv).
+// It must be called before the first lookup; an empty path keeps the default.
+func SetOUIFile(path string) {
+	if path != "" {
+		ouiFile = path
+	}
+}
+
+// OUIError returns the error from loading the override vendor database, if any. Lookups fall
+// back to the embedded database when the override cannot be read.
+func OUIError() error {
+	loadOUI()
+	return ouiErr
+}
+
+// LookupVendor returns the organization the IEEE assigned mac's prefix to, or "" when the
+// prefix is unknown. mac may use any of the usual separators (colons, dashes or dots).
+func LookupVendor(mac string) string {
+	digits := macHexDigits(mac)
+	if len(digits) < 6 {
+		return ""
+	}
+	loadOUI()
+	// Longer assignments are carved out of MA-L blocks, so the most specific prefix wins.
+	for _, length := range []int{9, 7, 6} {
+		if len(digits) >= length {
+			if vendor, ok := ouiVendors[digits[:length]]; ok {
+				return vendor
+			}
+		}
+	}
+	return ""
+}
+
+// DescribeVendor returns a vendor label for mac suitable for display. Besides the registered
+// organization it recognizes multicast addresses and locally administered addresses, which
+// include the randomized addresses phones and laptops use for privacy.
+func DescribeVendor(mac string) string {
+	first, ok := macFirstOctet(mac)
+	if !ok {
+		return ""
+	}
+	switch {
+	case first&0x01 != 0:
+		return "Multicast"
+	case first&0x02 != 0:
+		return "Locally administered (randomized)"
+	}
+	if vendor := LookupVendor(mac); vendor != "" {
+		return vendor
+	}
+	return "Unknown"
+}
+
+// IsLocallyAdministeredMAC reports whether mac has the locally administered bit set, as
+// randomized (private) addresses do.
+func IsLocallyAdministeredMAC(mac string) bool {
+	first, ok := macFirstOctet(mac)
+	return ok && first&0x02 != 0
+}
+
+// macFirstOctet returns the first octet of mac, which carries the multicast and locally
+// administered bits.
+func macFirstOctet(mac string) (byte, bool) {
+	digits := macHexDigits(mac)
+	if len(digits) < 2 {
+		return 0, false
+	}
+	first, err := strconv.ParseUint(digits[:2], 16, 8)
+	return byte(first), err == nil
+}
+
+// macHexDigits strips separators from mac and returns its upper-case hex digits.
+func macHexDigits(mac string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(mac) {
+		if (r >= '0' && r <= '9') || (r >= 'A' && r <= 'F') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// loadOUI parses the vendor database on first use.
+func loadOUI() {
+	ouiOnce.Do(func() {
+		if ouiFile == "" {
+			ouiFile = os.Getenv(OUIFileEnv)
+		}
+		if ouiFile != "" {
+			ouiVendors, ouiErr = readOUIFile(ouiFile)
+			if ouiErr == nil {
+				return
+			}
+		}
+		ouiVendors, _ = parseOUI(strings.NewReader(embeddedOUI))
+	})
+}
+
+// readOUIFile loads a vendor database from disk, detecting IEEE CSV files by their header.
+func readOUIFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening OUI file: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	header, _ := reader.Peek(9)
+	var vendors map[string]string
+	if strings.HasPrefix(string(header), "Registry,") {
+		vendors, err = parseOUICSV(reader)
+	} else {
+		vendors, err = parseOUI(reader)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading OUI file %s: %w", path, err)
+	}
+	if len(vendors) == 0 {
+		return nil, fmt.Errorf("OUI file %s contains no prefixes", path)
+	}
+	return vendors, nil
+}
+
+// parseOUI reads the tab-separated format used by the embedded database. Blank lines and
+// lines starting with # are ignored.
+func parseOUI(r io.Reader) (map[string]string, error) {
+	vendors := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prefix, vendor, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		prefix = macHexDigits(prefix)
+		if len(prefix) >= 6 {
+			vendors[prefix] = strings.TrimSpace(vendor)
+		}
+	}
+	return vendors, scanner.Err()
+}
+
+// parseOUICSV reads an IEEE registration authority CSV listing.
+// Columns are: Registry, Assignment, Organization Name, Organization Address.
+func parseOUICSV(r io.Reader) (map[string]string, error) {
+	vendors := make(map[string]string)
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return vendors, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header {
+			header = false
+			continue
+		}
+		if len(record) < 3 {
+			continue
+		}
+		prefix := macHexDigits(record[1])
+		if len(prefix) >= 6 {
+			vendors[prefix] = strings.Join(strings.Fields(record[2]), " ")
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedOUI(t *testing.T) {
+	vendors, err := parseOUI(strings.NewReader(embeddedOUI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The embedded database is the whole IEEE MA-L registry (as of 2020-07-21), not a hand-picked subset
+	if len(vendors) < 20000 {
+		t.Errorf("embedded database has %d prefixes, want the full registry", len(vendors))
+	}
+	for prefix := range vendors {
+		if n := len(prefix); n != 6 && n != 7 && n != 9 {
+			t.Fatalf("prefix %q has %d digits", prefix, n)
+		}
+	}
+}
+
+func TestDescribeVendor(t *testing.T) {
+	tests := []struct {
+		mac  string
+		want string
+	}{
+		{"b8:27:eb:12:34:56", "Raspberry Pi Foundation"},
+		{"00-0C-29-AB-CD-EF", "VMware, Inc."},
+		{"000c.29ab.cdef", "VMware, Inc."},
+		{"01:00:5e:00:00:fb", "Multicast"},
+		{"da:a1:19:00:00:01", "Locally administered (randomized)"},
+		{"00:00:5e", "ICANN, IANA Department"},
+		{"zz", ""},
+	}
+	for _, tt := range tests {
+		if got := DescribeVendor(tt.mac); got != tt.want {
+			t.Errorf("DescribeVendor(%s) = %q, want %q", tt.mac, got, tt.want)
+		}
+	}
+}
+
+func TestParseOUICSV(t *testing.T) {
+	csv := `Registry,Assignment,Organization Name,Organization Address
+MA-L,70B3D5,IEEE Registration Authority,445 Hoes Lane Piscataway NJ US 08554
+MA-S,70B3D5F2E,"Example  Devices, Inc.",Somewhere
+MA-M,1C82596,Example Sensors,
+bad
+`
+	vendors, err := parseOUICSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"70B3D5": "IEEE Registration Authority", "70B3D5F2E": "Example Devices, Inc.", "1C82596": "Example Sensors"}
+	if len(vendors) != len(want) {
+		t.Fatalf("got %v, want %v", vendors, want)
+	}
+	for prefix, name := range want {
+		if vendors[prefix] != name {
+			t.Errorf("vendors[%s] = %q, want %q", prefix, vendors[prefix], name)
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+
+	"golang.org/x/term"
 )
 
 type Terminal struct {
@@ -44,7 +46,11 @@ var colorMap = map[ErrorLevel]string{
 }
 
 // ClearTerminal clears the terminal screen based on the operating system.
+// Nothing is written when stdout is redirected, so piped output (e.g. JSON) stays clean.
 func ClearTerminal() error {
+	if !IsTerminal() {
+		return nil
+	}
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
 	case "windows":
@@ -56,6 +62,11 @@ func ClearTerminal() error {
 	return cmd.Run()
 }
 
+// IsTerminal reports whether standard output is a terminal rather than a file or pipe.
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
 // TerminalColor prints the given string to the terminal in the color corresponding to the error level
 func TerminalColor(message string, level ErrorLevel) {
 	colorCode, ok := colorMap[level]